@@ -0,0 +1,192 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+)
+
+// PadPolicy tells EncryptAny and DecryptAny what to do with lengths out of
+// XXTEA limits.
+type PadPolicy uint8
+
+const (
+	// PadNone takes lengths within XXTEA limits only, other lengths panic.
+	PadNone PadPolicy = iota
+	// PadISO appends 0x80 and zero bytes up to a multiple of four, and at
+	// least 12 bytes.  Input can be 0..207 bytes long.
+	PadISO
+	// PadClearTail encrypts the leading multiple of four bytes (12..208)
+	// and passes the 0..3 slack bytes in clear, followed by an 8B tag over
+	// the length, ciphertext and slack.  Slack bytes are authenticated, not
+	// hidden.  Every output is tagged, so none can be cut to a shorter one.
+	PadClearTail
+	// PadPKCS7 appends 1..12 bytes of the pad length up to a multiple of
+	// four, and at least 12 bytes, as PKCS#7 does for 4B blocks.  Input can
+	// be 0..207 bytes long.  Padding is checked in constant time.
+	PadPKCS7
+	// PadZero appends 0..11 zero bytes up to a multiple of four, and at
+	// least 12 bytes, as many C libraries do.  DecryptAny strips all zeros
+	// at the end, so input ending with zero bytes does not round trip.
+	// Input can be 0..208 bytes long.
+	PadZero
+)
+
+// TailTagSize is the size of the tag of PadClearTail output.
+const TailTagSize = 8
+
+var (
+	ErrPadding = errors.New("xxtea: bad padding")
+	ErrTag     = errors.New("xxtea: clear tail does not authenticate")
+)
+
+var lblTail = []byte("tail-mac")
+
+// tailTag returns the tag of PadClearTail ciphertext and tail, bound to
+// their length.
+func (k TeaKey) tailTag(ct []byte) []byte {
+	m := hmac.New(sha256.New, k.Derive(lblTail).Bytes())
+	m.Write([]byte{byte(len(ct))})
+	m.Write(ct)
+	return m.Sum(nil)[:TailTagSize]
+}
+
+// isoLen returns PadISO output length for n bytes of input.
+func isoLen(n int) int {
+	n = (n + 4) &^ 3
+	if n < 12 {
+		n = 12
+	}
+	return n
+}
+
+// zeroLen returns PadZero output length for n bytes of input.
+func zeroLen(n int) int {
+	n = (n + 3) &^ 3
+	if n < 12 {
+		n = 12
+	}
+	return n
+}
+
+// EncryptAny returns 'in' encrypted into a new slice, handling lengths not
+// divisible by four as the policy says.  Lengths the policy can not handle
+// panic, as for Encrypt.
+func (k TeaKey) EncryptAny(in []byte, policy PadPolicy) []byte {
+	n := len(in)
+	switch policy {
+	case PadNone:
+		return k.Encrypt(in, make([]byte, n))
+	case PadISO:
+		if n > 207 {
+			panic(ErrMsgLong)
+		}
+		out := make([]byte, isoLen(n))
+		copy(out, in)
+		out[n] = 0x80
+		return k.Encrypt(out, out)
+	case PadPKCS7:
+		if n > 207 {
+			panic(ErrMsgLong)
+		}
+		out := make([]byte, isoLen(n))
+		copy(out, in)
+		for i := n; i < len(out); i++ {
+			out[i] = byte(len(out) - n)
+		}
+		return k.Encrypt(out, out)
+	case PadZero:
+		if n > 208 {
+			panic(ErrMsgLong)
+		}
+		out := make([]byte, zeroLen(n))
+		copy(out, in)
+		return k.Encrypt(out, out)
+	case PadClearTail:
+		w := n &^ 3
+		out := make([]byte, n, n+TailTagSize)
+		k.Encrypt(in[:w], out[:w])
+		copy(out[w:], in[w:])
+		return append(out, k.tailTag(out)...)
+	}
+	panic(ErrMisuse)
+}
+
+// DecryptAny undoes EncryptAny with the same policy.  It returns
+// ErrPadding or ErrTag for inputs EncryptAny could not have made.
+func (k TeaKey) DecryptAny(in []byte, policy PadPolicy) ([]byte, error) {
+	n := len(in)
+	switch policy {
+	case PadNone:
+		return k.Decrypt(in, make([]byte, n)), nil
+	case PadISO:
+		if n < 12 || n > 208 || n&3 != 0 {
+			return nil, ErrPadding
+		}
+		out := k.Decrypt(in, make([]byte, n))
+		i := n - 1
+		for i >= 0 && out[i] == 0 {
+			i--
+		}
+		if i < 0 || out[i] != 0x80 || isoLen(i) != n {
+			return nil, ErrPadding
+		}
+		return out[:i], nil
+	case PadPKCS7:
+		if n < 12 || n > 208 || n&3 != 0 {
+			return nil, ErrPadding
+		}
+		out := k.Decrypt(in, make([]byte, n))
+		return unpadPKCS7(out)
+	case PadZero:
+		if n < 12 || n > 208 || n&3 != 0 {
+			return nil, ErrPadding
+		}
+		out := k.Decrypt(in, make([]byte, n))
+		z, m := 0, 1 // m: 1 while in trailing zeros
+		for i := n - 1; i >= 0; i-- {
+			m &= subtle.ConstantTimeByteEq(out[i], 0)
+			z += m
+		}
+		return out[:n-z], nil
+	case PadClearTail:
+		n -= TailTagSize
+		if n < 12 || n&^3 > 208 {
+			return nil, ErrPadding
+		}
+		if !hmac.Equal(k.tailTag(in[:n]), in[n:]) {
+			return nil, ErrTag
+		}
+		w := n &^ 3
+		out := make([]byte, n)
+		k.Decrypt(in[:w], out[:w])
+		copy(out[w:], in[w:n])
+		return out, nil
+	}
+	panic(ErrMisuse)
+}
+
+// unpadPKCS7 returns b without its PadPKCS7 padding, or ErrPadding.  Pad
+// bytes are checked without branching on their values.
+func unpadPKCS7(b []byte) ([]byte, error) {
+	n := len(b)
+	max := 4 // longer pads make 12 bytes only
+	if n == 12 {
+		max = 12
+	}
+	p := int(b[n-1])
+	good := subtle.ConstantTimeLessOrEq(1, p) & subtle.ConstantTimeLessOrEq(p, max)
+	for i := 1; i <= max; i++ {
+		pad := subtle.ConstantTimeLessOrEq(i, p)
+		good &= subtle.ConstantTimeSelect(pad, subtle.ConstantTimeByteEq(b[n-i], byte(p)), 1)
+	}
+	if good != 1 {
+		return nil, ErrPadding
+	}
+	return b[:n-p], nil
+}
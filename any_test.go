@@ -0,0 +1,98 @@
+package xxtea
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_EncryptAny(t *testing.T) {
+	k := NewKey([]byte(keyBEBE))
+	for _, tc := range []struct {
+		policy PadPolicy
+		sizes  []int
+	}{
+		{PadNone, []int{12, 16, 208}},
+		{PadISO, []int{0, 1, 11, 12, 13, 15, 16, 100, 207}},
+		{PadClearTail, []int{12, 13, 14, 15, 16, 208, 209, 211}},
+		{PadPKCS7, []int{0, 1, 11, 12, 13, 15, 16, 100, 207}},
+		{PadZero, []int{0, 1, 11, 12, 13, 15, 16, 100, 207, 208}},
+	} {
+		for _, n := range tc.sizes {
+			pt := []byte(msgMax + msgMax)[:n]
+			ct := k.EncryptAny(pt, tc.policy)
+			got, err := k.DecryptAny(ct, tc.policy)
+			if err != nil || !bytes.Equal(got, pt) {
+				t.Error("EncryptAny round trip failed", tc.policy, n, err)
+			}
+		}
+	}
+	ct := k.EncryptAny([]byte("13 bytes long"), PadClearTail)
+	if len(ct) != 13+TailTagSize || ct[12] != 'g' {
+		t.Error("Clear tail not passed")
+	}
+	ct[12] ^= 1
+	if _, err := k.DecryptAny(ct, PadClearTail); err != ErrTag {
+		t.Error("Tampered tail accepted", err)
+	}
+	if _, err := k.DecryptAny(ct[:10], PadClearTail); err != ErrPadding {
+		t.Error("Short input accepted", err)
+	}
+	ct = k.EncryptAny([]byte(msgMin+"x"), PadClearTail)
+	if _, err := k.DecryptAny(ct[:12], PadClearTail); err == nil {
+		t.Error("Truncated input accepted")
+	}
+	ct = k.EncryptAny([]byte(msgMin), PadClearTail)
+	if len(ct) != 12+TailTagSize {
+		t.Error("Aligned input not tagged")
+	}
+	if _, err := k.DecryptAny(k.Encrypt([]byte(msgMin+msgMin), make([]byte, 24)), PadClearTail); err != ErrTag {
+		t.Error("Untagged input accepted", err)
+	}
+	bad := k.Encrypt([]byte(msgMin), make([]byte, 12)) // no 0x80 marker
+	if _, err := k.DecryptAny(bad, PadISO); err != ErrPadding {
+		t.Error("Bad ISO padding accepted", err)
+	}
+	bad = k.Encrypt([]byte("ab\x80\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"), make([]byte, 16))
+	if _, err := k.DecryptAny(bad, PadISO); err != ErrPadding {
+		t.Error("Overlong ISO padding accepted", err)
+	}
+	if _, err := k.DecryptAny(make([]byte, 14), PadISO); err != ErrPadding {
+		t.Error("Unaligned ISO input accepted", err)
+	}
+	for _, p := range []string{
+		"0123456789a\x00",              // zero pad length
+		"012345678\x03\x02\x03",        // pad bytes differ
+		"012345678901\x05\x05\x05\x05", // longer than a 16B message needs
+		"0123456789ab\x00\x00\x00\x00", // no padding
+	} {
+		bad = k.Encrypt([]byte(p), make([]byte, len(p)))
+		if _, err := k.DecryptAny(bad, PadPKCS7); err != ErrPadding {
+			t.Error("Bad PKCS7 padding accepted", p, err)
+		}
+	}
+	ct = k.EncryptAny([]byte("data\x00"), PadZero)
+	if got, _ := k.DecryptAny(ct, PadZero); string(got) != "data" {
+		t.Error("Zero padding not stripped", got)
+	}
+}
+
+func Test_EncryptAny_Panics(t *testing.T) {
+	k := NewKey([]byte(keyBEBE))
+	for _, f := range []func(){
+		func() { k.EncryptAny(make([]byte, 13), PadNone) },
+		func() { k.EncryptAny(make([]byte, 208), PadISO) },
+		func() { k.EncryptAny(make([]byte, 11), PadClearTail) },
+		func() { k.EncryptAny(make([]byte, 208), PadPKCS7) },
+		func() { k.EncryptAny(make([]byte, 209), PadZero) },
+		func() { k.EncryptAny(make([]byte, 12), PadZero+1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("EncryptAny misuse should panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
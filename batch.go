@@ -0,0 +1,141 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import "context"
+
+const (
+	batchLanes  = 8  // messages of a lanes pass
+	batchMin    = 2  // shortest run of equal lengths worth a lanes pass
+	batchWindow = 64 // messages between context checks
+)
+
+// useAVX2 selects the AVX2 lanes of batch_amd64.s; tests clear it to run
+// the scalar loops.
+var useAVX2 = hasAVX2()
+
+// BatchKernel names the kernel EncryptBatch and DecryptBatch run equal
+// lengths on: "avx2" for vector lanes, "go" for the scalar loops.
+func BatchKernel() string {
+	if useAVX2 {
+		return "avx2"
+	}
+	return "go"
+}
+
+// TeaKey.EncryptBatch encrypts each of msgs in place, as Encrypt(m, m)
+// does.  A single message is a serial chain of steps, but independent
+// messages are not: on CPUs with AVX2, messages of equal length are
+// encrypted together, up to eight at a time, in vector lanes.  Others, and
+// lengths with no equal, are encrypted one by one.  Gateways handling many
+// frames of one length gain the most.
+//
+// All lengths are checked before any message is touched; a bad one panics
+// as for Encrypt.
+func (k TeaKey) EncryptBatch(msgs [][]byte) {
+	if _, err := k.batch(context.Background(), msgs, false); err != nil {
+		panic(err)
+	}
+}
+
+// TeaKey.DecryptBatch is the inverse of EncryptBatch.
+func (k TeaKey) DecryptBatch(msgs [][]byte) {
+	if _, err := k.batch(context.Background(), msgs, true); err != nil {
+		panic(err)
+	}
+}
+
+// TeaKey.EncryptBatchContext is EncryptBatch bound by ctx, checked every
+// 64 messages.  It returns the number of leading messages encrypted: all
+// of them, or fewer and the error of ctx.  Bad lengths give their misuse
+// code and no message is touched.
+func (k TeaKey) EncryptBatchContext(ctx context.Context, msgs [][]byte) (int, error) {
+	return k.batch(ctx, msgs, false)
+}
+
+// TeaKey.DecryptBatchContext is DecryptBatch bound by ctx, as
+// EncryptBatchContext is.
+func (k TeaKey) DecryptBatchContext(ctx context.Context, msgs [][]byte) (int, error) {
+	return k.batch(ctx, msgs, true)
+}
+
+// batch does windows of msgs, grouping equal lengths of a window into
+// lanes passes.
+func (k TeaKey) batch(ctx context.Context, msgs [][]byte, dec bool) (int, error) {
+	for _, m := range msgs {
+		if err := chkMsg(len(m), len(m)); err != nil {
+			return 0, err
+		}
+	}
+	var ix [batchWindow]int
+	var v [52 * batchLanes]uint32
+	for done := 0; done < len(msgs); {
+		if err := ctx.Err(); err != nil {
+			return done, err
+		}
+		w := msgs[done:]
+		if len(w) > batchWindow {
+			w = w[:batchWindow]
+		}
+		s := ix[:len(w)]
+		for i := range s { // sorted by length, insertion
+			j := i
+			for ; j > 0 && len(w[s[j-1]]) > len(w[i]); j-- {
+				s[j] = s[j-1]
+			}
+			s[j] = i
+		}
+		for len(s) > 0 {
+			r := 1
+			for r < len(s) && r < batchLanes && len(w[s[r]]) == len(w[s[0]]) {
+				r++
+			}
+			if r < batchMin || !useAVX2 {
+				for _, x := range s[:r] {
+					if dec {
+						k.decryptBytes(w[x], w[x])
+					} else {
+						k.encryptBytes(w[x], w[x])
+					}
+				}
+			} else {
+				k.lanes(w, s[:r], v[:], dec)
+			}
+			s = s[r:]
+		}
+		done += len(w)
+	}
+	return len(msgs), nil
+}
+
+// lanes does the equal length messages w[s[i]], up to batchLanes of them,
+// together in AVX2 lanes.  Word p of lane i is v[p*batchLanes+i]; unused
+// lanes run over zeros.
+func (k TeaKey) lanes(w [][]byte, s []int, v []uint32, dec bool) {
+	n := len(w[s[0]]) / 4
+	v = v[:n*batchLanes]
+	for i := range v {
+		v[i] = 0
+	}
+	for i, x := range s {
+		m := w[x]
+		for p := 0; p < n; p++ {
+			v[p*batchLanes+i] = uint32(m[4*p+3]) | uint32(m[4*p+2])<<8 | // from bytes
+				uint32(m[4*p+1])<<16 | uint32(m[4*p])<<24
+		}
+	}
+	if dec {
+		decrypt8(&k, v)
+	} else {
+		encrypt8(&k, v)
+	}
+	for i, x := range s {
+		m := w[x]
+		for p := 0; p < n; p++ {
+			c := v[p*batchLanes+i] // to bytes
+			m[4*p+3], m[4*p+2], m[4*p+1], m[4*p] = byte(c), byte(c>>8), byte(c>>16), byte(c>>24)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build amd64 && !purego
+
+package xxtea
+
+// hasAVX2 reports whether the CPU, and the OS saving YMM registers, let
+// encrypt8 and decrypt8 run.
+func hasAVX2() bool
+
+// encrypt8 does xxtea block rounds over all eight lanes of v, n words
+// each, as TeaKey.encrypt does over one message.
+//
+//go:noescape
+func encrypt8(k *TeaKey, v []uint32)
+
+// decrypt8 undoes encrypt8.
+//
+//go:noescape
+func decrypt8(k *TeaKey, v []uint32)
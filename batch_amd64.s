@@ -0,0 +1,135 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+#define DELTA $0x9e3779b9
+
+// Eight messages of n words run in the lanes of YMM registers; word p of
+// all of them is the 32 bytes at v+32*p.  Registers:
+//	DI key, SI v, CX n-1, R13 &v[n-1], R12 &v[p], R10 p,
+//	R8 rounds, R9 sum, R11 e,
+//	Y0 z, Y4 y, Y5 key word, Y6 sum, Y1 Y2 Y3 scratch.
+
+// MX leaves ((z>>5 ^ y<<2) + (y>>3 ^ z<<4)) ^ ((sum ^ y) + (k ^ z)) in Y1.
+#define MX \
+	VPSLLD $4, Y0, Y1; VPSRLD $3, Y4, Y2; VPXOR Y2, Y1, Y1; \
+	VPSRLD $5, Y0, Y2; VPSLLD $2, Y4, Y3; VPXOR Y3, Y2, Y2; VPADDD Y2, Y1, Y1; \
+	VPXOR Y4, Y6, Y2; VPXOR Y0, Y5, Y3; VPADDD Y3, Y2, Y2; VPXOR Y2, Y1, Y1
+
+// KEY broadcasts k[p&3^e] to Y5.
+#define KEY \
+	MOVL R10, DX; XORL R11, DX; ANDL $3, DX; VPBROADCASTD (DI)(DX*4), Y5
+
+// SETUP loads arguments, sets R8 to 6 + 52/n rounds, CX to n-1 and R13
+// to &v[n-1].
+#define SETUP \
+	MOVQ k+0(FP), DI; MOVQ v_base+8(FP), SI; MOVQ v_len+16(FP), CX; \
+	SHRQ $3, CX; MOVL $52, AX; XORL DX, DX; DIVL CX; LEAL 6(AX), R8; \
+	DECQ CX; MOVQ CX, R13; SHLQ $5, R13; ADDQ SI, R13
+
+// ROUND sets e and broadcasts sum to Y6.
+#define ROUND \
+	MOVL R9, R11; SHRL $2, R11; VMOVD R9, X6; VPBROADCASTD X6, Y6
+
+// func hasAVX2() bool
+TEXT ·hasAVX2(SB), NOSPLIT, $0-1
+	MOVB $0, ret+0(FP)
+	XORL AX, AX
+	CPUID
+	CMPL AX, $7
+	JB   no
+	MOVL $1, AX
+	CPUID
+	ANDL $0x18000000, CX   // OSXSAVE, AVX
+	CMPL CX, $0x18000000
+	JNE  no
+	XORL CX, CX
+	XGETBV
+	ANDL $6, AX            // XMM and YMM state saved
+	CMPL AX, $6
+	JNE  no
+	MOVL $7, AX
+	XORL CX, CX
+	CPUID
+	SHRL $5, BX
+	ANDL $1, BX
+	MOVB BX, ret+0(FP)
+no:
+	RET
+
+// func encrypt8(k *TeaKey, v []uint32)
+TEXT ·encrypt8(SB), NOSPLIT, $0-32
+	SETUP
+	XORL R9, R9            // sum = 0
+	VMOVDQU (R13), Y0      // z = v[n-1]
+
+enc_round:
+	ADDL DELTA, R9         // sum += DELTA
+	ROUND
+	MOVQ SI, R12
+	XORQ R10, R10          // p = 0
+
+enc_step:
+	CMPQ R12, R13
+	JAE  enc_last
+	VMOVDQU 32(R12), Y4    // y = v[p+1]
+	KEY
+	MX
+	VPADDD (R12), Y1, Y0   // z = v[p] += MX
+	VMOVDQU Y0, (R12)
+	ADDQ $32, R12
+	INCQ R10
+	JMP  enc_step
+
+enc_last:
+	VMOVDQU (SI), Y4       // y = v[0]
+	KEY
+	MX
+	VPADDD (R13), Y1, Y0   // z = v[n-1] += MX
+	VMOVDQU Y0, (R13)
+	DECL R8
+	JNZ  enc_round
+	VZEROUPPER
+	RET
+
+// func decrypt8(k *TeaKey, v []uint32)
+TEXT ·decrypt8(SB), NOSPLIT, $0-32
+	SETUP
+	MOVL DELTA, R9
+	IMULL R8, R9           // sum = rounds*DELTA
+	VMOVDQU (SI), Y4       // y = v[0]
+
+dec_round:
+	ROUND
+	MOVQ R13, R12
+	MOVQ CX, R10           // p = n-1
+
+dec_step:
+	CMPQ R12, SI
+	JBE  dec_last
+	VMOVDQU -32(R12), Y0   // z = v[p-1]
+	KEY
+	MX
+	VMOVDQU (R12), Y4      // y = v[p] -= MX
+	VPSUBD Y1, Y4, Y4
+	VMOVDQU Y4, (R12)
+	SUBQ $32, R12
+	DECQ R10
+	JMP  dec_step
+
+dec_last:
+	VMOVDQU (R13), Y0      // z = v[n-1]
+	KEY
+	MX
+	VMOVDQU (SI), Y4       // y = v[0] -= MX
+	VPSUBD Y1, Y4, Y4
+	VMOVDQU Y4, (SI)
+	SUBL DELTA, R9         // sum -= DELTA
+	DECL R8
+	JNZ  dec_round
+	VZEROUPPER
+	RET
@@ -0,0 +1,13 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !amd64 || purego
+
+package xxtea
+
+func hasAVX2() bool { return false }
+
+// encrypt8 and decrypt8 are never called, as useAVX2 is false.
+func encrypt8(k *TeaKey, v []uint32) { panic(ErrMisuse) }
+func decrypt8(k *TeaKey, v []uint32) { panic(ErrMisuse) }
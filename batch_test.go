@@ -0,0 +1,112 @@
+package xxtea
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"testing"
+)
+
+func batchMsgs(r *rand.Rand, count int) (msgs, want [][]byte) {
+	for i := 0; i < count; i++ {
+		n := 12 + 4*r.Intn(50)
+		if r.Intn(2) == 0 {
+			n = 32 // runs of equal lengths
+		}
+		m := make(bs, n)
+		r.Read(m)
+		msgs = append(msgs, m)
+		want = append(want, append(bs(nil), m...))
+	}
+	return
+}
+
+func Test_EncryptBatch(t *testing.T) {
+	k := NewKey([]byte(keyBEBE))
+	r := rand.New(rand.NewSource(1))
+	defer func(a bool) { useAVX2 = a }(useAVX2)
+	for _, simd := range []bool{false, useAVX2} {
+		useAVX2 = simd
+		for _, count := range []int{0, 1, 2, 7, 8, 9, 64, 200} {
+			msgs, pt := batchMsgs(r, count)
+			k.EncryptBatch(msgs)
+			for i, m := range msgs {
+				if !bytes.Equal(m, k.Encrypt(pt[i], make(bs, len(m)))) {
+					t.Error("EncryptBatch differs from Encrypt", simd, count, i)
+				}
+			}
+			k.DecryptBatch(msgs)
+			for i, m := range msgs {
+				if !bytes.Equal(m, pt[i]) {
+					t.Error("DecryptBatch failed", simd, count, i)
+				}
+			}
+		}
+	}
+}
+
+func Test_EncryptBatchContext(t *testing.T) {
+	k := NewKey([]byte(keyBEBE))
+	msgs, pt := batchMsgs(rand.New(rand.NewSource(2)), 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if n, err := k.EncryptBatchContext(ctx, msgs); n != 0 || err != context.Canceled {
+		t.Error("Canceled batch not stopped", n, err)
+	}
+	if n, err := k.EncryptBatchContext(context.Background(), msgs); n != 100 || err != nil {
+		t.Error("EncryptBatchContext failed", n, err)
+	}
+	if n, err := k.DecryptBatchContext(context.Background(), msgs); n != 100 || err != nil || !bytes.Equal(msgs[99], pt[99]) {
+		t.Error("DecryptBatchContext failed", n, err)
+	}
+	msgs = append(msgs, make(bs, 14))
+	if n, err := k.EncryptBatchContext(context.Background(), msgs); n != 0 || err != ErrMsgAlign || !bytes.Equal(msgs[0], pt[0]) {
+		t.Error("Bad length accepted", n, err)
+	}
+	defer func() {
+		if recover() != ErrMsgAlign {
+			t.Error("EncryptBatch misuse should panic")
+		}
+	}()
+	k.EncryptBatch(msgs)
+}
+
+func BenchmarkBatch(b *testing.B) {
+	key := NewKey(bs(keyBEBE))
+	msgs := make([][]byte, 64)
+	for i := range msgs {
+		msgs[i] = make(bs, 32)
+	}
+	b.Run("Encrypt_32x64", func(b *testing.B) {
+		b.SetBytes(32 * 64)
+		for n := 0; n < b.N; n++ {
+			for _, m := range msgs {
+				key.Encrypt(m, m)
+			}
+		}
+	})
+	b.Run("EncryptBatch_32x64", func(b *testing.B) {
+		b.SetBytes(32 * 64)
+		for n := 0; n < b.N; n++ {
+			key.EncryptBatch(msgs)
+		}
+	})
+	b.Run("DecryptBatch_32x64", func(b *testing.B) {
+		b.SetBytes(32 * 64)
+		for n := 0; n < b.N; n++ {
+			key.DecryptBatch(msgs)
+		}
+	})
+}
+
+func Test_BatchKernel(t *testing.T) {
+	defer func(a bool) { useAVX2 = a }(useAVX2)
+	useAVX2 = false
+	if BatchKernel() != "go" {
+		t.Error("BatchKernel failed")
+	}
+	useAVX2 = true
+	if BatchKernel() != "avx2" {
+		t.Error("BatchKernel failed")
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package boltstore implements envelope.ReplayState and envelope.CounterStore
+// on top of a bbolt database.
+//
+// Every Store and Reserve returns only after its transaction is committed
+// and synced, so after a crash the database never holds a state older than
+// one already handed to the caller.  Concurrent Store calls are coalesced
+// into shared transactions (bbolt Batch), and senders reserve counters in
+// blocks (see envelope.Counter), which keeps the number of syncs low.
+//
+// Store lives in a separate module, so users of the xxtea packages do not
+// pull in bbolt.
+package boltstore
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/ohir/xxtea/envelope"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bktReplay  = []byte("xxtea-replay")
+	bktCounter = []byte("xxtea-counter")
+)
+
+var ErrCorrupt = errors.New("boltstore: corrupt record")
+
+// Store is an envelope.ReplayState and envelope.CounterStore kept in bbolt.
+type Store struct {
+	db  *bolt.DB
+	own bool
+}
+
+// Open opens (or creates) the database file at path.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	s, err := New(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.own = true
+	return s, nil
+}
+
+// New returns a Store using buckets of an already open database.
+func New(db *bolt.DB) (*Store, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{bktReplay, bktCounter} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database if it was opened by Open.
+func (s *Store) Close() error {
+	if s.own {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// Load implements envelope.ReplayState.
+func (s *Store) Load(kid uint16) (st envelope.State, ok bool, err error) {
+	var k [2]byte
+	binary.BigEndian.PutUint16(k[:], kid)
+	err = s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bktReplay).Get(k[:])
+		if v == nil {
+			return nil
+		}
+		if len(v) != 13 {
+			return ErrCorrupt
+		}
+		st.Epoch = binary.BigEndian.Uint32(v)
+		st.Counter = binary.BigEndian.Uint32(v[4:])
+		st.Time = binary.BigEndian.Uint32(v[8:])
+		st.Version = v[12]
+		ok = true
+		return nil
+	})
+	return
+}
+
+// Store implements envelope.ReplayState.
+func (s *Store) Store(kid uint16, st envelope.State) error {
+	k := make([]byte, 2)
+	binary.BigEndian.PutUint16(k, kid)
+	v := make([]byte, 13)
+	binary.BigEndian.PutUint32(v, st.Epoch)
+	binary.BigEndian.PutUint32(v[4:], st.Counter)
+	binary.BigEndian.PutUint32(v[8:], st.Time)
+	v[12] = st.Version
+	return s.db.Batch(func(tx *bolt.Tx) error {
+		return tx.Bucket(bktReplay).Put(k, v)
+	})
+}
+
+// Reserve implements envelope.CounterStore.
+func (s *Store) Reserve(kid uint16, epoch uint32, n uint32) (first uint32, err error) {
+	k := make([]byte, 6)
+	binary.BigEndian.PutUint16(k, kid)
+	binary.BigEndian.PutUint32(k[2:], epoch)
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bktCounter)
+		var hi uint64
+		if v := b.Get(k); v != nil {
+			if len(v) != 8 {
+				return ErrCorrupt
+			}
+			hi = binary.BigEndian.Uint64(v)
+		}
+		if hi+uint64(n) > 1<<32 {
+			return envelope.ErrCounter
+		}
+		first = uint32(hi)
+		v := make([]byte, 8)
+		binary.BigEndian.PutUint64(v, hi+uint64(n))
+		return b.Put(k, v)
+	})
+	return
+}
@@ -0,0 +1,59 @@
+package boltstore
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ohir/xxtea/envelope"
+)
+
+func Test_ReplayState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := s.Load(1); ok || err != nil {
+		t.Error("Empty store returned a state", err)
+	}
+	want := envelope.State{Epoch: 2, Counter: 300, Time: 1700000000, Version: envelope.Latest}
+	if err = s.Store(1, want); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	s, err = Open(path) // restart
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got, ok, err := s.Load(1); !ok || err != nil || got != want {
+		t.Error("State not restored", got, err)
+	}
+}
+
+func Test_Reserve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &envelope.Counter{Store: s, KeyID: 1, Block: 8}
+	for i := uint32(0); i < 10; i++ {
+		if n, err := c.Next(); err != nil || n != i {
+			t.Fatal("Bad counter", n, err)
+		}
+	}
+	s.Close()
+	s, err = Open(path) // restart
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c = &envelope.Counter{Store: s, KeyID: 1, Block: 8}
+	if n, _ := c.Next(); n != 16 {
+		t.Error("Counter reused after restart", n)
+	}
+	if _, err = s.Reserve(1, 0, 1<<32-1); err != envelope.ErrCounter {
+		t.Error("Counter overflow not reported", err)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bundle packs small file trees, like device configuration sets,
+// into a single encrypted and authenticated archive.
+//
+// Archive layout:
+//
+//	"XBDL" | nonce (16B) | manifest | file 0 | file 1 ... | mac (32B)
+//
+// Manifest and every file are sections: a 4B BE length followed by
+// a stream of sealed records (see package stream) under a key derived from
+// the key, the nonce and the section index.  Manifest lists name, mode,
+// size and SHA-256 of every file, in file section order.  HMAC-SHA256 under
+// a key derived from the key covers everything before it and is checked
+// before anything gets decrypted.
+package bundle
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/stream"
+)
+
+// Sizes of archive parts.
+const (
+	NonceSize = 16
+	MACSize   = sha256.Size
+)
+
+var (
+	ErrFormat = errors.New("bundle: malformed archive")
+	ErrMAC    = errors.New("bundle: archive authentication failed")
+	ErrName   = errors.New("bundle: bad file name")
+)
+
+var magic = []byte("XBDL")
+
+// manifest section index
+const manifestIdx = ^uint32(0)
+
+// File is a regular file of an archive.  Name is slash separated, relative
+// and clean, as io/fs.ValidPath checks.
+type File struct {
+	Name string
+	Mode fs.FileMode
+	Data []byte
+}
+
+// sectionKey returns the key of a section.
+func sectionKey(k xxtea.TeaKey, nonce []byte, idx uint32) xxtea.TeaKey {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], idx)
+	return k.DeriveLabel("bundle", nonce, b[:])
+}
+
+var lblNonce = []byte("bundle-nonce")
+
+func macKey(k xxtea.TeaKey) []byte {
+	return k.Derive([]byte("bundle-mac")).Bytes()
+}
+
+// section appends data sealed as a section to out.
+func section(out []byte, k xxtea.TeaKey, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := stream.NewWriterID(&buf, k, 0) // section keys are single use
+	_, err := w.Write(data)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(buf.Len()))
+	out = append(out, n[:]...)
+	return append(out, buf.Bytes()...), nil
+}
+
+// Pack returns files sealed under the key into an archive, with a random
+// nonce.
+func Pack(k xxtea.TeaKey, files []File) ([]byte, error) {
+	return pack(k, files, false)
+}
+
+// PackDeterministic returns files sealed under the key into an archive
+// with the nonce derived from the key and all the archive contents, for
+// reproducible builds: equal files give equal archives, any change gives
+// an unrelated one.  It reveals only whether two archives hold the same.
+func PackDeterministic(k xxtea.TeaKey, files []File) ([]byte, error) {
+	return pack(k, files, true)
+}
+
+func pack(k xxtea.TeaKey, files []File, det bool) ([]byte, error) {
+	var w [8]byte // big-endian fields of the manifest
+	binary.BigEndian.PutUint32(w[:], uint32(len(files)))
+	man := append([]byte(nil), w[:4]...)
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		if !fs.ValidPath(f.Name) || f.Name == "." || len(f.Name) > 0xffff || seen[f.Name] {
+			return nil, ErrName
+		}
+		seen[f.Name] = true
+		binary.BigEndian.PutUint16(w[:], uint16(len(f.Name)))
+		man = append(append(man, w[:2]...), f.Name...)
+		binary.BigEndian.PutUint32(w[:], uint32(f.Mode.Perm()))
+		man = append(man, w[:4]...)
+		binary.BigEndian.PutUint64(w[:], uint64(len(f.Data)))
+		man = append(man, w[:]...)
+		sum := sha256.Sum256(f.Data)
+		man = append(man, sum[:]...)
+	}
+	nonce := make([]byte, NonceSize)
+	if det {
+		// manifest holds names, modes, sizes and hashes of all files
+		m := hmac.New(sha256.New, k.Derive(lblNonce).Bytes())
+		m.Write(man)
+		copy(nonce, m.Sum(nil))
+	} else if err := xxtea.ReadEntropy(nonce); err != nil {
+		return nil, err
+	}
+	out := append(append([]byte(nil), magic...), nonce...)
+	out, err := section(out, sectionKey(k, nonce, manifestIdx), man)
+	for i := 0; err == nil && i < len(files); i++ {
+		out, err = section(out, sectionKey(k, nonce, uint32(i)), files[i].Data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := hmac.New(sha256.New, macKey(k))
+	m.Write(out)
+	return m.Sum(out), nil
+}
+
+// Entry describes a file of an archive.
+type Entry struct {
+	Name string
+	Mode fs.FileMode
+	Size int64
+
+	sum [sha256.Size]byte
+	sec []byte // sealed section
+	idx uint32
+}
+
+// Reader gives access to files of an authenticated archive, decrypting
+// each only when it is read.
+type Reader struct {
+	Entries []Entry // in archive order
+
+	k     xxtea.TeaKey
+	nonce []byte
+}
+
+// open returns decrypted section.
+func (r *Reader) open(sec []byte, idx uint32) ([]byte, error) {
+	data, err := io.ReadAll(stream.NewReader(bytes.NewReader(sec), sectionKey(r.k, r.nonce, idx)))
+	if err != nil {
+		return nil, ErrFormat
+	}
+	return data, nil
+}
+
+// NewReader authenticates the archive and reads its manifest.  Archive
+// must not be modified while the Reader is in use.
+func NewReader(k xxtea.TeaKey, archive []byte) (*Reader, error) {
+	if len(archive) < len(magic)+NonceSize+MACSize || !bytes.Equal(archive[:len(magic)], magic) {
+		return nil, ErrFormat
+	}
+	body := archive[:len(archive)-MACSize]
+	m := hmac.New(sha256.New, macKey(k))
+	m.Write(body)
+	if !hmac.Equal(m.Sum(nil), archive[len(body):]) {
+		return nil, ErrMAC
+	}
+	r := &Reader{k: k, nonce: body[len(magic) : len(magic)+NonceSize]}
+	rest := body[len(magic)+NonceSize:]
+	next := func() ([]byte, error) {
+		if len(rest) < 4 || uint64(len(rest)-4) < uint64(binary.BigEndian.Uint32(rest)) {
+			return nil, ErrFormat
+		}
+		n := 4 + int(binary.BigEndian.Uint32(rest))
+		sec := rest[4:n]
+		rest = rest[n:]
+		return sec, nil
+	}
+	sec, err := next()
+	if err != nil {
+		return nil, err
+	}
+	man, err := r.open(sec, manifestIdx)
+	if err != nil || len(man) < 4 {
+		return nil, ErrFormat
+	}
+	cnt := binary.BigEndian.Uint32(man)
+	man = man[4:]
+	seen := make(map[string]bool)
+	for i := uint32(0); i < cnt; i++ {
+		if len(man) < 2 || len(man) < 2+int(binary.BigEndian.Uint16(man))+4+8+32 {
+			return nil, ErrFormat
+		}
+		nl := int(binary.BigEndian.Uint16(man))
+		e := Entry{Name: string(man[2 : 2+nl]), Mode: fs.FileMode(binary.BigEndian.Uint32(man[2+nl:])).Perm(), idx: i}
+		size := binary.BigEndian.Uint64(man[6+nl:])
+		copy(e.sum[:], man[14+nl:46+nl])
+		man = man[46+nl:]
+		if !fs.ValidPath(e.Name) || e.Name == "." || seen[e.Name] {
+			return nil, ErrName
+		}
+		seen[e.Name] = true
+		if size > 1<<62 {
+			return nil, ErrFormat
+		}
+		e.Size = int64(size)
+		if e.sec, err = next(); err != nil {
+			return nil, err
+		}
+		r.Entries = append(r.Entries, e)
+	}
+	if len(man) != 0 || len(rest) != 0 {
+		return nil, ErrFormat
+	}
+	return r, nil
+}
+
+// ReadFile returns decrypted contents of the i-th entry.
+func (r *Reader) ReadFile(i int) ([]byte, error) {
+	e := &r.Entries[i]
+	data, err := r.open(e.sec, e.idx)
+	if err != nil {
+		return nil, err
+	}
+	if s := sha256.Sum256(data); int64(len(data)) != e.Size || s != e.sum {
+		return nil, ErrFormat
+	}
+	return data, nil
+}
+
+// Unpack checks the archive and returns its files.
+func Unpack(k xxtea.TeaKey, archive []byte) ([]File, error) {
+	r, err := NewReader(k, archive)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]File, len(r.Entries))
+	for i, e := range r.Entries {
+		files[i] = File{Name: e.Name, Mode: e.Mode}
+		if files[i].Data, err = r.ReadFile(i); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// ReadFS returns all regular files of fsys, sorted by name.  Other
+// non-directory entries are an error.
+func ReadFS(fsys fs.FS) ([]File, error) {
+	var files []File
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return &fs.PathError{Op: "bundle", Path: name, Err: errors.New("not a regular file")}
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		files = append(files, File{name, info.Mode().Perm(), data})
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, err
+}
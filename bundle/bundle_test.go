@@ -0,0 +1,126 @@
+package bundle
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ohir/xxtea"
+)
+
+var key = xxtea.NewKey([]byte("0123456789ABCDEF"))
+
+func testFiles() []File {
+	return []File{
+		{"wifi.conf", 0600, []byte("ssid=lab\npsk=secret\n")},
+		{"certs/ca.pem", 0644, bytes.Repeat([]byte("PEM"), 300)},
+		{"empty", 0644, nil},
+	}
+}
+
+func Test_PackUnpack(t *testing.T) {
+	a, err := Pack(key, testFiles())
+	if err != nil {
+		t.Fatal("Pack failed:", err)
+	}
+	if bytes.Contains(a, []byte("secret")) {
+		t.Error("Plaintext leaked")
+	}
+	files, err := Unpack(key, a)
+	if err != nil || len(files) != 3 {
+		t.Fatal("Unpack failed:", err)
+	}
+	for i, f := range testFiles() {
+		g := files[i]
+		if g.Name != f.Name || g.Mode != f.Mode || !bytes.Equal(g.Data, f.Data) {
+			t.Error("File differs:", f.Name)
+		}
+	}
+	if b, _ := Pack(key, testFiles()); bytes.Equal(a, b) {
+		t.Error("Archives of same files are equal")
+	}
+}
+
+func Test_Unpack_Tampered(t *testing.T) {
+	a, _ := Pack(key, testFiles())
+	for _, i := range []int{0, 5, 30, len(a) / 2, len(a) - 1} {
+		b := append([]byte(nil), a...)
+		b[i] ^= 1
+		if _, err := Unpack(key, b); err == nil {
+			t.Error("Tampered byte accepted at", i)
+		}
+	}
+	if _, err := Unpack(key, a[:len(a)-1]); err == nil {
+		t.Error("Truncated archive accepted")
+	}
+	if _, err := Unpack(xxtea.NewKey([]byte("FEDCBA9876543210")), a); err != ErrMAC {
+		t.Error("Wrong key accepted:", err)
+	}
+	if _, err := Unpack(key, a[:10]); err != ErrFormat {
+		t.Error("Short archive accepted:", err)
+	}
+}
+
+func Test_Pack_BadNames(t *testing.T) {
+	for _, n := range []string{"", ".", "../x", "/etc/passwd", "a//b", "a/./b"} {
+		if _, err := Pack(key, []File{{Name: n}}); err != ErrName {
+			t.Error("Bad name accepted:", n)
+		}
+	}
+	if _, err := Pack(key, []File{{Name: "a"}, {Name: "a"}}); err != ErrName {
+		t.Error("Duplicate name accepted")
+	}
+}
+
+func Test_ReadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"b.conf":     {Data: []byte("b"), Mode: 0600},
+		"a/x.conf":   {Data: []byte("x"), Mode: 0644},
+		"a/sub/y.db": {Data: []byte("y"), Mode: 0640},
+	}
+	files, err := ReadFS(fsys)
+	if err != nil || len(files) != 3 || files[0].Name != "a/sub/y.db" || files[2].Mode != 0600 {
+		t.Error("ReadFS failed:", files, err)
+	}
+	fsys["link"] = &fstest.MapFile{Mode: fs.ModeSymlink}
+	if _, err = ReadFS(fsys); err == nil {
+		t.Error("Symlink accepted")
+	}
+}
+
+func Test_Reader(t *testing.T) {
+	a, _ := Pack(key, testFiles())
+	r, err := NewReader(key, a)
+	if err != nil || len(r.Entries) != 3 {
+		t.Fatal("NewReader failed:", err)
+	}
+	if e := r.Entries[1]; e.Name != "certs/ca.pem" || e.Size != 900 || e.Mode != 0644 {
+		t.Error("Bad entry", e.Name, e.Size, e.Mode)
+	}
+	if d, err := r.ReadFile(0); err != nil || string(d) != "ssid=lab\npsk=secret\n" {
+		t.Error("ReadFile failed:", err)
+	}
+	r.Entries[0].Size++
+	if _, err := r.ReadFile(0); err != ErrFormat {
+		t.Error("Size mismatch not detected:", err)
+	}
+}
+
+func Test_PackDeterministic(t *testing.T) {
+	a, err := PackDeterministic(key, testFiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := PackDeterministic(key, testFiles()); !bytes.Equal(a, b) {
+		t.Error("Deterministic archives differ")
+	}
+	fs := testFiles()
+	fs[1].Data[0] = 'X'
+	if b, _ := PackDeterministic(key, fs); bytes.Equal(a[4:4+NonceSize], b[4:4+NonceSize]) {
+		t.Error("Nonce does not depend on contents")
+	}
+	if files, err := Unpack(key, a); err != nil || len(files) != 3 {
+		t.Error("Deterministic archive not unpacked", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package capture records frames received in the field, with what became
+// of them, and replays them later against a new version of the receiving
+// code.  Regression suites built of real traffic then tell any change in
+// what is accepted or rejected.
+//
+// Captures are JSON Lines, a Record per line:
+//
+//	{"time":"2024-05-01T10:00:00Z","src":"gw-1","frame":"AgAAAQ...","err":"envelope: frame counter replayed"}
+//
+// Malformed frames are captured as well; they are bytes, not parsed.
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/ohir/xxtea"
+)
+
+// Record is a captured frame.
+type Record struct {
+	Time  time.Time `json:"time"`
+	Src   string    `json:"src,omitempty"` // where the frame came from
+	Frame []byte    `json:"frame"`
+	Err   string    `json:"err,omitempty"` // error of the receiver, empty if accepted
+}
+
+// Writer appends Records to a capture.
+type Writer struct {
+	Src string // Src of written Records
+
+	enc *json.Encoder
+}
+
+// NewWriter returns a Writer of a capture to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{enc: json.NewEncoder(w)}
+}
+
+// Write records the frame and the error the receiver returned for it.
+func (w *Writer) Write(frame []byte, err error) error {
+	r := Record{Time: xxtea.Now().UTC(), Src: w.Src, Frame: frame}
+	if err != nil {
+		r.Err = err.Error()
+	}
+	return w.enc.Encode(&r)
+}
+
+// Reader reads Records of a capture.
+type Reader struct {
+	dec *json.Decoder
+}
+
+// NewReader returns a Reader of the capture.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: json.NewDecoder(bufio.NewReader(r))}
+}
+
+// Next returns the next Record, or io.EOF at the end of the capture.
+func (r *Reader) Next() (rec Record, err error) {
+	err = r.dec.Decode(&rec)
+	return rec, err
+}
+
+// Mismatch is a Record the replayed receiver treated otherwise.
+type Mismatch struct {
+	Index  int // of the Record in the capture, from 0
+	Record Record
+	Err    string // error of the replay, empty if accepted
+}
+
+// Replay feeds frames of the capture, in order, to the open function,
+// typically wrapping a fresh receiver:
+//
+//	r := envelope.NewReceiver()
+//	r.SetKey(1, 0, key)
+//	ms, err := capture.Replay(f, func(b []byte) error {
+//		_, _, err := r.Open(b)
+//		return err
+//	})
+//
+// It returns the Records whose outcome differs from the recorded one.
+func Replay(src io.Reader, open func(frame []byte) error) ([]Mismatch, error) {
+	var ms []Mismatch
+	cr := NewReader(src)
+	for i := 0; ; i++ {
+		rec, err := cr.Next()
+		if err == io.EOF {
+			return ms, nil
+		}
+		if err != nil {
+			return ms, err
+		}
+		var got string
+		if err := open(rec.Frame); err != nil {
+			got = err.Error()
+		}
+		if got != rec.Err {
+			ms = append(ms, Mismatch{i, rec, got})
+		}
+	}
+}
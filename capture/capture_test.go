@@ -0,0 +1,53 @@
+package capture
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+var key = xxtea.NewKey([]byte("0123456789ABCDEF"))
+
+func Test_CaptureReplay(t *testing.T) {
+	var frames [][]byte
+	for c := uint32(1); c <= 3; c++ {
+		f, _ := envelope.Seal(key, envelope.Header{KeyID: 1, Counter: c}, []byte("Sixteen bytes!!!"))
+		frames = append(frames, f)
+	}
+	frames = append(frames, frames[1], []byte("junk"))
+	recv := func() func([]byte) error {
+		r := envelope.NewReceiver()
+		r.SetKey(1, 0, key)
+		return func(b []byte) error {
+			_, _, err := r.Open(b)
+			return err
+		}
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Src = "gw-1"
+	open := recv()
+	for _, f := range frames {
+		if err := w.Write(f, open(f)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := strings.Count(buf.String(), "\n"); n != len(frames) || !strings.Contains(buf.String(), `"src":"gw-1"`) {
+		t.Error("Capture malformed", buf.String())
+	}
+	ms, err := Replay(bytes.NewReader(buf.Bytes()), recv())
+	if err != nil || len(ms) != 0 {
+		t.Error("Replay of same code mismatched", ms, err)
+	}
+	// a receiver accepting everything differs on the replay and the junk
+	ms, err = Replay(bytes.NewReader(buf.Bytes()), func([]byte) error { return nil })
+	if err != nil || len(ms) != 2 || ms[0].Index != 3 || ms[1].Index != 4 || ms[0].Record.Err != envelope.ErrReplay.Error() {
+		t.Error("Replay mismatches wrong", ms, err)
+	}
+	if _, err = Replay(strings.NewReader("{bad json"), recv()); err == nil {
+		t.Error("Bad capture replayed")
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package claims implements a small typed key/value claims container,
+// with issued-at time and audience, sealed as an envelope frame under a
+// subkey of a TeaKey.
+//
+// Whole container must fit in the 208 bytes of a single XXTEA block:
+//
+//	issued-at (4B) | audience (1B len + bytes) | claims... | 0x00 | zero pad
+//
+// Each claim is encoded as:
+//
+//	key (1B len + bytes) | type (1B) | value
+//
+// Uint values are uvarints, String and Bytes values are length prefixed
+// (1B) and Bool values take a single byte.  Builder accounts every byte as
+// claims are added and reports the first claim that did not fit.
+package claims
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+// Budget is the maximum encoded size of a container.
+const Budget = envelope.MaxPayload
+
+// Claim value types.
+const (
+	TypeUint byte = 1 + iota
+	TypeString
+	TypeBytes
+	TypeBool
+)
+
+var (
+	ErrFormat   = errors.New("claims: malformed container")
+	ErrAudience = errors.New("claims: audience mismatch")
+	ErrKey      = errors.New("claims: key must be 1 to 255 bytes long")
+	ErrValue    = errors.New("claims: value longer than 255 bytes")
+)
+
+// SizeError reports a claim that does not fit in the Budget.
+type SizeError struct {
+	Key  string // claim key, empty for the audience
+	Need int    // bytes the claim needs
+	Left int    // bytes left in the budget
+}
+
+func (e *SizeError) Error() string {
+	what := "audience"
+	if e.Key != "" {
+		what = "claim " + strconv.Quote(e.Key)
+	}
+	return "claims: " + what + " needs " + strconv.Itoa(e.Need) + " bytes, " +
+		strconv.Itoa(e.Left) + " left of " + strconv.Itoa(Budget)
+}
+
+var lblClaims = []byte("claims")
+
+// Builder collects claims to be sealed.  Errors are sticky: the first one
+// is kept and returned by Seal, further claims are ignored.
+type Builder struct {
+	b   []byte
+	err error
+}
+
+// New returns a Builder for the audience with issued-at time iat.
+func New(audience string, iat time.Time) *Builder {
+	b := &Builder{b: make([]byte, 4, Budget)}
+	binary.BigEndian.PutUint32(b.b, uint32(iat.Unix()))
+	if len(audience) > 255 {
+		b.err = ErrValue
+		return b
+	}
+	b.put("", append([]byte{byte(len(audience))}, audience...))
+	return b
+}
+
+// Size returns the encoded size of the container so far, end marker
+// included.
+func (b *Builder) Size() int {
+	return len(b.b) + 1
+}
+
+// Err returns the first error met while adding claims.
+func (b *Builder) Err() error {
+	return b.err
+}
+
+// put appends the encoded claim, if it fits.
+func (b *Builder) put(key string, enc []byte) {
+	if b.err != nil {
+		return
+	}
+	if left := Budget - b.Size(); len(enc) > left {
+		b.err = &SizeError{key, len(enc), left}
+		return
+	}
+	b.b = append(b.b, enc...)
+}
+
+func (b *Builder) add(key string, typ byte, val []byte) *Builder {
+	if b.err == nil && (len(key) == 0 || len(key) > 255) {
+		b.err = ErrKey
+	}
+	enc := append([]byte{byte(len(key))}, key...)
+	b.put(key, append(append(enc, typ), val...))
+	return b
+}
+
+// Uint adds an unsigned integer claim.
+func (b *Builder) Uint(key string, v uint64) *Builder {
+	var u [binary.MaxVarintLen64]byte
+	return b.add(key, TypeUint, u[:binary.PutUvarint(u[:], v)])
+}
+
+// String adds a string claim.
+func (b *Builder) String(key, v string) *Builder {
+	return b.addLen(key, TypeString, []byte(v))
+}
+
+// Bytes adds a bytes claim.
+func (b *Builder) Bytes(key string, v []byte) *Builder {
+	return b.addLen(key, TypeBytes, v)
+}
+
+// addLen adds a length prefixed claim.
+func (b *Builder) addLen(key string, typ byte, v []byte) *Builder {
+	if len(v) > 255 && b.err == nil {
+		b.err = ErrValue
+	}
+	return b.add(key, typ, append([]byte{byte(len(v))}, v...))
+}
+
+// Bool adds a boolean claim.
+func (b *Builder) Bool(key string, v bool) *Builder {
+	var x byte
+	if v {
+		x = 1
+	}
+	return b.add(key, TypeBool, []byte{x})
+}
+
+// Seal returns the container sealed under a subkey of k, or the first error
+// met while adding claims.
+func (b *Builder) Seal(k xxtea.TeaKey) ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	p := make([]byte, padded(b.Size()))
+	copy(p, b.b)
+	return envelope.Seal(k.Derive(lblClaims), envelope.Header{}, p)
+}
+
+// Set is an opened claims container.
+type Set struct {
+	Audience string
+	IssuedAt time.Time
+	claims   map[string]claim
+}
+
+type claim struct {
+	typ byte
+	val []byte
+}
+
+// Open authenticates the sealed container under a subkey of k, checks it
+// is meant for the audience, and returns its claims.  In xxtea.Strict mode
+// Uint values must be minimal uvarints and padding no longer than Seal
+// makes it.
+func Open(k xxtea.TeaKey, sealed []byte, audience string) (*Set, error) {
+	_, p, err := envelope.Open(k.Derive(lblClaims), sealed)
+	if err != nil {
+		return nil, err
+	}
+	s := &Set{
+		IssuedAt: time.Unix(int64(binary.BigEndian.Uint32(p)), 0),
+		claims:   make(map[string]claim),
+	}
+	r := p[4:]
+	aud, ok := take(&r)
+	if !ok {
+		return nil, ErrFormat
+	}
+	if s.Audience = string(aud); s.Audience != audience {
+		return nil, ErrAudience
+	}
+	for len(r) > 0 && r[0] != 0 {
+		key, ok := take(&r)
+		if !ok || len(r) == 0 {
+			return nil, ErrFormat
+		}
+		c := claim{typ: r[0]}
+		r = r[1:]
+		switch c.typ {
+		case TypeUint:
+			v, n := binary.Uvarint(r)
+			if n <= 0 || xxtea.Strict() && n != uvarintLen(v) {
+				return nil, ErrFormat
+			}
+			c.val, r = r[:n], r[n:]
+		case TypeString, TypeBytes:
+			if c.val, ok = take(&r); !ok {
+				return nil, ErrFormat
+			}
+		case TypeBool:
+			if len(r) == 0 || r[0] > 1 {
+				return nil, ErrFormat
+			}
+			c.val, r = r[:1], r[1:]
+		default:
+			return nil, ErrFormat
+		}
+		if _, dup := s.claims[string(key)]; dup {
+			return nil, ErrFormat
+		}
+		s.claims[string(key)] = c
+	}
+	for _, x := range r {
+		if x != 0 {
+			return nil, ErrFormat
+		}
+	}
+	if xxtea.Strict() && (len(r) == 0 || len(p) != padded(len(p)-len(r)+1)) {
+		return nil, ErrFormat
+	}
+	return s, nil
+}
+
+// uvarintLen returns the size of the minimal uvarint encoding of v.
+func uvarintLen(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
+
+// padded returns the sealed size of a container of n bytes.
+func padded(n int) int {
+	n = (n + 3) &^ 3
+	if n < envelope.MinPayload {
+		n = envelope.MinPayload
+	}
+	return n
+}
+
+// take cuts a length prefixed value off r.
+func take(r *[]byte) ([]byte, bool) {
+	b := *r
+	if len(b) == 0 || len(b) < 1+int(b[0]) {
+		return nil, false
+	}
+	*r = b[1+int(b[0]):]
+	return b[1 : 1+int(b[0])], true
+}
+
+// Expired reports whether the set was issued more than maxAge ago, by the
+// xxtea Clock.
+func (s *Set) Expired(maxAge time.Duration) bool {
+	return xxtea.Now().Sub(s.IssuedAt) > maxAge
+}
+
+// Len returns the number of claims in the set.
+func (s *Set) Len() int {
+	return len(s.claims)
+}
+
+// Uint returns the unsigned integer claim, ok is false if there is no such
+// claim of this type.
+func (s *Set) Uint(key string) (v uint64, ok bool) {
+	c, ok := s.claims[key]
+	if !ok || c.typ != TypeUint {
+		return 0, false
+	}
+	v, _ = binary.Uvarint(c.val)
+	return v, true
+}
+
+// String returns the string claim, ok is false if there is no such claim
+// of this type.
+func (s *Set) String(key string) (string, bool) {
+	c, ok := s.claims[key]
+	if !ok || c.typ != TypeString {
+		return "", false
+	}
+	return string(c.val), true
+}
+
+// Bytes returns the bytes claim, ok is false if there is no such claim of
+// this type.
+func (s *Set) Bytes(key string) ([]byte, bool) {
+	c, ok := s.claims[key]
+	if !ok || c.typ != TypeBytes {
+		return nil, false
+	}
+	return c.val, true
+}
+
+// Bool returns the boolean claim, ok is false if there is no such claim of
+// this type.
+func (s *Set) Bool(key string) (v, ok bool) {
+	c, ok := s.claims[key]
+	if !ok || c.typ != TypeBool {
+		return false, false
+	}
+	return c.val[0] == 1, true
+}
@@ -0,0 +1,148 @@
+package claims
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+const (
+	keyBEBE = "0123456789ABCDEF"
+	keyLELE = "FEDCBA9876543210"
+)
+
+func Test_SealOpen(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	iat := time.Unix(1700000000, 0)
+	sealed, err := New("gw-1", iat).
+		Uint("fw", 300).String("site", "B7").Bytes("mac", []byte{1, 2, 3}).Bool("ota", true).
+		Seal(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(key, sealed, "gw-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Audience != "gw-1" || !s.IssuedAt.Equal(iat) || s.Len() != 4 {
+		t.Error("Bad container", s)
+	}
+	xxtea.SetClock(xxtea.ClockFunc(func() time.Time { return iat.Add(time.Hour) }))
+	defer xxtea.SetClock(nil)
+	if s.Expired(time.Hour) || !s.Expired(time.Hour-1) {
+		t.Error("Expired does not follow the Clock")
+	}
+	if v, ok := s.Uint("fw"); !ok || v != 300 {
+		t.Error("Uint claim lost")
+	}
+	if v, ok := s.String("site"); !ok || v != "B7" {
+		t.Error("String claim lost")
+	}
+	if v, ok := s.Bytes("mac"); !ok || !bytes.Equal(v, []byte{1, 2, 3}) {
+		t.Error("Bytes claim lost")
+	}
+	if v, ok := s.Bool("ota"); !ok || !v {
+		t.Error("Bool claim lost")
+	}
+	if _, ok := s.Uint("site"); ok {
+		t.Error("Claim read as other type")
+	}
+	if _, ok := s.String("fw"); ok {
+		t.Error("Claim read as other type")
+	}
+	if _, ok := s.Bytes("none"); ok {
+		t.Error("Missing claim found")
+	}
+	if _, ok := s.Bool("fw"); ok {
+		t.Error("Claim read as other type")
+	}
+	if _, err = Open(key, sealed, "gw-2"); err != ErrAudience {
+		t.Error("Other audience accepted")
+	}
+	if _, err = Open(xxtea.NewKey([]byte(keyLELE)), sealed, "gw-1"); err != envelope.ErrOpenFailed {
+		t.Error("Container opened with other key")
+	}
+}
+
+func Test_Budget(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	b := New("a", time.Now()) // 4 + 2 + end
+	if b.Size() != 7 {
+		t.Error("Bad size accounting", b.Size())
+	}
+	b.Bytes("blob", make([]byte, 193)) // 1+4+1+1+193 = 200
+	if b.Err() != nil || b.Size() != Budget-1 {
+		t.Fatal("Claim not accounted", b.Size(), b.Err())
+	}
+	b.Bool("x", true).Uint("y", 1)
+	var se *SizeError
+	if !errors.As(b.Err(), &se) || se.Key != "x" || se.Need != 4 || se.Left != 1 {
+		t.Error("Bad size error", b.Err())
+	}
+	if !strings.Contains(b.Err().Error(), `"x" needs 4 bytes, 1 left of 208`) {
+		t.Error("Unhelpful error", b.Err())
+	}
+	if _, err := b.Seal(key); err != se {
+		t.Error("Oversized container sealed")
+	}
+	sealed, err := New("a", time.Now()).Bytes("blob", make([]byte, 190)).Bool("z", false).Seal(key)
+	if err != nil {
+		t.Error("Full budget not sealed", err)
+	}
+	if _, err = Open(key, sealed, "a"); err != nil {
+		t.Error("Full budget not opened", err)
+	}
+	if err = New(strings.Repeat("a", 300), time.Now()).Err(); err != ErrValue {
+		t.Error("Long audience accepted")
+	}
+	if err = New("a", time.Now()).String("", "v").Err(); err != ErrKey {
+		t.Error("Empty key accepted")
+	}
+	if err = New("a", time.Now()).Bytes("k", make([]byte, 256)).Err(); err != ErrValue {
+		t.Error("Long value accepted")
+	}
+}
+
+func Test_Malformed(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE)).Derive(lblClaims)
+	for _, p := range [][]byte{
+		{0, 0, 0, 0, 9, 0, 0, 0, 0, 0, 0, 0},                               // audience overflow
+		{0, 0, 0, 0, 0, 1, 'k', 9, 0, 0, 0, 0},                             // unknown type
+		{0, 0, 0, 0, 0, 1, 'k', TypeBool, 2, 0, 0, 0},                      // bad bool
+		{0, 0, 0, 0, 0, 1, 'k', TypeBool, 1, 0, 0, 7},                      // dirty pad
+		{0, 0, 0, 0, 0, 1, 'k', TypeBool, 1, 1, 'k', TypeBool, 0, 0, 0, 0}, // duplicate
+	} {
+		f, _ := envelope.Seal(key, envelope.Header{}, p)
+		if _, err := Open(xxtea.NewKey([]byte(keyBEBE)), f, ""); err != ErrFormat {
+			t.Error("Malformed container accepted", p, err)
+		}
+	}
+}
+
+func Test_Strict(t *testing.T) {
+	defer xxtea.SetStrict(false)
+	key := xxtea.NewKey([]byte(keyBEBE)).Derive(lblClaims)
+	for _, p := range [][]byte{
+		{0, 0, 0, 0, 0, 1, 'k', TypeUint, 0x81, 0x00, 0, 0}, // overlong uvarint
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},    // overlong pad
+	} {
+		f, _ := envelope.Seal(key, envelope.Header{}, p)
+		xxtea.SetStrict(false)
+		if _, err := Open(xxtea.NewKey([]byte(keyBEBE)), f, ""); err != nil {
+			t.Error("Lax Open failed", p, err)
+		}
+		xxtea.SetStrict(true)
+		if _, err := Open(xxtea.NewKey([]byte(keyBEBE)), f, ""); err != ErrFormat {
+			t.Error("Non-canonical container accepted", p, err)
+		}
+	}
+	f, _ := New("a", time.Now()).Uint("n", 300).Seal(xxtea.NewKey([]byte(keyBEBE)))
+	if _, err := Open(xxtea.NewKey([]byte(keyBEBE)), f, "a"); err != nil {
+		t.Error("Strict Open failed", err)
+	}
+}
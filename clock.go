@@ -0,0 +1,47 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a source of current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface.
+type ClockFunc func() time.Time
+
+// Now returns f().
+func (f ClockFunc) Now() time.Time { return f() }
+
+var (
+	clkMu sync.RWMutex
+	clk   Clock = ClockFunc(time.Now)
+)
+
+// SetClock makes c the source of time of this package and its subpackages:
+// timestamps of frames, time windows of receivers and age of tokens.  It is
+// for devices that keep their own, drift corrected time, and for tests.
+// Nil restores the system clock.
+func SetClock(c Clock) {
+	if c == nil {
+		c = ClockFunc(time.Now)
+	}
+	clkMu.Lock()
+	clk = c
+	clkMu.Unlock()
+}
+
+// Now returns current time of the Clock set.
+func Now() time.Time {
+	clkMu.RLock()
+	c := clk
+	clkMu.RUnlock()
+	return c.Now()
+}
@@ -0,0 +1,19 @@
+package xxtea
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Clock(t *testing.T) {
+	defer SetClock(nil)
+	at := time.Unix(1700000000, 0)
+	SetClock(ClockFunc(func() time.Time { return at }))
+	if !Now().Equal(at) {
+		t.Error("Time not read from the set clock")
+	}
+	SetClock(nil)
+	if d := time.Since(Now()); d < 0 || d > time.Minute {
+		t.Error("Nil did not restore the system clock")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command bundlegen encrypts a directory into a bundle archive at build
+// time, to be embedded with embed.FS and served with package encfs.
+//
+// Usage:
+//
+//	//go:generate go run github.com/ohir/xxtea/cmd/bundlegen -key @asset.key -o ui.xbdl ./ui
+//	//go:embed ui.xbdl
+//	var ui []byte
+//
+// Output is deterministic (see bundle.PackDeterministic), so reproducible
+// builds stay reproducible.  Key is given as 32 hex digits, as @file
+// holding them, or as $NAME of an environment variable holding them.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/bundle"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+func run(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("bundlegen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	key := fs.String("key", "", "key as 32 hex digits, @file, or $ENV")
+	out := fs.String("o", "", "output `file`")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: bundlegen -key K -o out dir")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 1 || *out == "" {
+		fs.Usage()
+		return 2
+	}
+	if err := generate(*key, fs.Arg(0), *out); err != nil {
+		fmt.Fprintln(stderr, "bundlegen:", err)
+		return 1
+	}
+	return 0
+}
+
+func generate(key, dir, out string) error {
+	k, err := parseKey(key)
+	if err != nil {
+		return err
+	}
+	files, err := bundle.ReadFS(os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+	a, err := bundle.PackDeterministic(k, files)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, a, 0644)
+}
+
+// parseKey returns the key given as 32 hex digits, @file or $ENV.
+func parseKey(s string) (k xxtea.TeaKey, err error) {
+	switch {
+	case s == "":
+		return k, errors.New("no key given")
+	case strings.HasPrefix(s, "@"):
+		b, err := os.ReadFile(s[1:])
+		if err != nil {
+			return k, err
+		}
+		s = string(b)
+	case strings.HasPrefix(s, "$"):
+		s = os.Getenv(s[1:])
+	}
+	k, err = xxtea.ParseKeyHex(strings.TrimSpace(s))
+	switch err {
+	case xxtea.ErrKeyText:
+		err = errors.New("key must be 32 hex digits")
+	case xxtea.ErrZeroKey:
+		err = errors.New("all-zeros key")
+	}
+	return k, err
+}
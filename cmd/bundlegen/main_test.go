@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/encfs"
+)
+
+const keyHex = "30313233343536373839414243444546"
+
+func Test_Generate(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "ui")
+	os.MkdirAll(filepath.Join(src, "js"), 0755)
+	os.WriteFile(filepath.Join(src, "index.html"), []byte("<h1>ui</h1>"), 0644)
+	os.WriteFile(filepath.Join(src, "js", "app.js"), []byte("init()"), 0644)
+	a, b := filepath.Join(dir, "a.xbdl"), filepath.Join(dir, "b.xbdl")
+	t.Setenv("UI_KEY", keyHex)
+	if c := run([]string{"-key", keyHex, "-o", a, src}, io.Discard); c != 0 {
+		t.Fatal("bundlegen failed")
+	}
+	if c := run([]string{"-key", "$UI_KEY", "-o", b, src}, io.Discard); c != 0 {
+		t.Fatal("bundlegen with env key failed")
+	}
+	da, _ := os.ReadFile(a)
+	db, _ := os.ReadFile(b)
+	if !bytes.Equal(da, db) {
+		t.Error("Output not deterministic")
+	}
+	k, _ := parseKey(keyHex)
+	fsys, err := encfs.Open(da, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, err := fs.ReadFile(fsys, "js/app.js"); err != nil || string(d) != "init()" {
+		t.Error("Generated bundle not readable", err)
+	}
+	if k != xxtea.NewKey([]byte("0123456789ABCDEF")) {
+		t.Error("Key misparsed")
+	}
+	for _, args := range [][]string{
+		{"-key", keyHex, src},
+		{"-key", keyHex, "-o", a},
+	} {
+		if c := run(args, io.Discard); c != 2 {
+			t.Error("Bad usage accepted", args)
+		}
+	}
+	for _, key := range []string{"", "abc", "$NO_SUCH_KEY_VAR", "@" + a + "none", "00000000000000000000000000000000"} {
+		if c := run([]string{"-key", key, "-o", a, src}, io.Discard); c != 1 {
+			t.Error("Bad key accepted", key)
+		}
+	}
+}
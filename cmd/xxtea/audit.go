@@ -0,0 +1,177 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ohir/xxtea"
+)
+
+// derivations turn a candidate string into key bytes, as weak provisioning
+// schemes seen in the field do.  Nil result skips the candidate.
+var derivations = map[string]func(s string) []byte{
+	// 32 hex digits taken as the key.
+	"hex": func(s string) []byte {
+		if b, err := hex.DecodeString(s); err == nil && len(b) == 16 {
+			return b
+		}
+		return nil
+	},
+	// string bytes, zero padded or truncated to 16.
+	"ascii": func(s string) []byte {
+		b := make([]byte, 16)
+		copy(b, s)
+		return b
+	},
+	// as ascii, but stored as little-endian words by the device.
+	"ascii-le": func(s string) []byte {
+		b := make([]byte, 16)
+		copy(b, s)
+		return xxtea.AsLEBE(b)
+	},
+}
+
+type auditFlags struct {
+	known  []byte
+	at     int
+	words  string
+	serial string
+	format string
+	derive []string
+}
+
+func runAudit(fs *flag.FlagSet, args []string, e *env) error {
+	var af auditFlags
+	known := fs.String("known", "", "known plaintext `hex`")
+	fs.IntVar(&af.at, "at", 0, "offset of known plaintext")
+	fs.StringVar(&af.words, "words", "", "wordlist `file`, one candidate per line")
+	fs.StringVar(&af.serial, "serial", "", "serial number `range` lo-hi to try")
+	fs.StringVar(&af.format, "format", "%d", "printf `format` of a serial number")
+	derive := fs.String("derive", "hex,ascii,ascii-le", "key derivations to try")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s --known HEX (--words F | --serial LO-HI) [flags] [ciphertext]\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var err error
+	if af.known, err = hex.DecodeString(*known); err != nil || len(af.known) == 0 {
+		return errors.New("--known must be hex of known plaintext")
+	}
+	if af.words == "" && af.serial == "" {
+		return errUsage
+	}
+	for _, d := range strings.Split(*derive, ",") {
+		if derivations[d] == nil {
+			return fmt.Errorf("unknown derivation %q", d)
+		}
+		af.derive = append(af.derive, d)
+	}
+	var in io.Reader = e.stdin
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	default:
+		return errUsage
+	}
+	ct, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if len(ct) < 12 || len(ct) > 208 || len(ct)&3 != 0 {
+		return fmt.Errorf("ciphertext of %d bytes is not an xxtea block", len(ct))
+	}
+	if af.at < 0 || af.at+len(af.known) > len(ct) {
+		return errors.New("known plaintext out of ciphertext bounds")
+	}
+	return audit(e.stdout, ct, &af)
+}
+
+// audit tries every candidate under every derivation, printing keys that
+// decrypt ct to the known plaintext.  Candidates deriving to the zero key
+// are skipped.
+func audit(w io.Writer, ct []byte, af *auditFlags) error {
+	pt := make([]byte, len(ct))
+	tried, hits := 0, 0
+	try := func(c string) {
+		for _, d := range af.derive {
+			kb := derivations[d](c)
+			if kb == nil {
+				continue
+			}
+			k, err := xxtea.MakeKey(kb)
+			if err != nil {
+				continue // zero key, no xxtea key at all
+			}
+			tried++
+			k.Decrypt(ct, pt)
+			if bytes.Equal(pt[af.at:af.at+len(af.known)], af.known) {
+				hits++
+				fmt.Fprintf(w, "weak key %X from %q via %s\n", kb, c, d)
+			}
+		}
+	}
+	if af.words != "" {
+		f, err := os.Open(af.words)
+		if err != nil {
+			return err
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if c := strings.TrimSpace(sc.Text()); c != "" {
+				try(c)
+			}
+		}
+		f.Close()
+		if err = sc.Err(); err != nil {
+			return err
+		}
+	}
+	if af.serial != "" {
+		lo, hi, err := serialRange(af.serial)
+		if err != nil {
+			return err
+		}
+		for n := lo; ; n++ {
+			try(fmt.Sprintf(af.format, n))
+			if n == hi {
+				break
+			}
+		}
+	}
+	fmt.Fprintf(w, "%d candidate keys tried, %d weak\n", tried, hits)
+	return nil
+}
+
+// serialRange parses "lo-hi".
+func serialRange(s string) (lo, hi uint64, err error) {
+	a, b, ok := strings.Cut(s, "-")
+	if ok {
+		if lo, err = strconv.ParseUint(a, 10, 64); err == nil {
+			hi, err = strconv.ParseUint(b, 10, 64)
+		}
+	}
+	if !ok || err != nil || lo > hi {
+		return 0, 0, errors.New("--serial must be lo-hi")
+	}
+	return lo, hi, nil
+}
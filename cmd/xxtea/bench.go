@@ -0,0 +1,80 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ohir/xxtea"
+)
+
+func runBench(fs *flag.FlagSet, args []string, e *env) error {
+	sizes := fs.String("sizes", "12,16,32,64,128,208", "comma separated frame `sizes` in bytes")
+	dur := fs.Duration("time", time.Second/2, "run time per size and direction")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *dur <= 0 {
+		return errUsage
+	}
+	var ns []int
+	for _, s := range strings.Split(*sizes, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 12 || n > 208 || n&3 != 0 {
+			return fmt.Errorf("bad size %q: must be 12..208 in multiples of 4", s)
+		}
+		ns = append(ns, n)
+	}
+	bench(e.stdout, ns, *dur)
+	return nil
+}
+
+// benchBatch is the number of messages of a timed EncryptBatch call.
+const benchBatch = 64
+
+// bench prints encrypt and decrypt speed for each frame size, one message
+// at a time and in batches of equal length messages, per message.
+func bench(w io.Writer, sizes []int, dur time.Duration) {
+	fmt.Fprintf(w, "%s/%s %s, batch kernel %s\n", runtime.GOOS, runtime.GOARCH, runtime.Version(), xxtea.BatchKernel())
+	fmt.Fprintln(w, " size        enc ns/op    MB/s     dec ns/op    MB/s   batch enc ns/op    MB/s")
+	k := xxtea.NewKey([]byte("0123456789ABCDEF"))
+	for _, n := range sizes {
+		b := make([]byte, n)
+		en := timeOp(func() { k.Encrypt(b, b) }, dur)
+		de := timeOp(func() { k.Decrypt(b, b) }, dur)
+		msgs := make([][]byte, benchBatch)
+		for i := range msgs {
+			msgs[i] = make([]byte, n)
+		}
+		be := timeOp(func() { k.EncryptBatch(msgs) }, dur) / benchBatch
+		fmt.Fprintf(w, "%5d  %14.1f  %7.2f  %12.1f  %7.2f  %16.1f  %7.2f\n",
+			n, en, mbs(n, en), de, mbs(n, de), be, mbs(n, be))
+	}
+}
+
+// timeOp returns mean ns per op, running op for about dur.
+func timeOp(op func(), dur time.Duration) float64 {
+	var ops int
+	start := time.Now()
+	for batch := 1; ; batch *= 2 {
+		for i := 0; i < batch; i++ {
+			op()
+		}
+		ops += batch
+		if el := time.Since(start); el >= dur {
+			return float64(el.Nanoseconds()) / float64(ops)
+		}
+	}
+}
+
+func mbs(n int, nsop float64) float64 {
+	return float64(n) * 1e3 / nsop
+}
@@ -0,0 +1,232 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+// vector is a conformance vector: ct is the ciphertext, or the frame in
+// envelope mode.
+type vector struct {
+	key, pt, ct []byte
+}
+
+// dialect is what the vectors exercise.
+type dialect struct {
+	order    string // be, le
+	pad      string // none, iso
+	envelope bool
+}
+
+func (d dialect) String() string {
+	if d.envelope {
+		return "envelope frames (FlagLength), BE words"
+	}
+	return "words " + d.order + ", padding " + d.pad
+}
+
+func runConform(fs *flag.FlagSet, args []string, e *env) error {
+	lang := fs.String("lang", "c", "language of the harness: c, python, js")
+	order := fs.String("order", "be", "word order of key and data: be, le")
+	pad := fs.String("pad", "none", "padding of data: none (12..208, multiple of 4), iso")
+	frames := fs.Bool("envelope", false, "vectors are envelope frames to open")
+	seed := fs.String("seed", "xxtea", "seed of the vectors; same seed, same output")
+	count := fs.Int("count", 8, "number of vectors")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [flags]\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *count < 1 {
+		return errUsage
+	}
+	d := dialect{*order, *pad, *frames}
+	switch {
+	case d.order != "be" && d.order != "le":
+		return errors.New("order must be be or le")
+	case d.pad != "none" && d.pad != "iso":
+		return errors.New("pad must be none or iso")
+	case d.envelope && (d.order != "be" || d.pad != "none"):
+		return errors.New("envelope frames have a fixed layout, drop --order and --pad")
+	}
+	vs := vectors(d, *seed, *count)
+	cmdline := "xxtea conform " + strings.Join(args, " ")
+	switch *lang {
+	case "c":
+		emitC(e.stdout, cmdline, d, vs)
+	case "python":
+		emitPython(e.stdout, cmdline, d, vs)
+	case "js":
+		emitJS(e.stdout, cmdline, d, vs)
+	default:
+		return errors.New("lang must be c, python or js")
+	}
+	return nil
+}
+
+// prng returns n bytes determined by the seed, label and index.
+func prng(seed, label string, i, n int) []byte {
+	var out []byte
+	for c := 0; len(out) < n; c++ {
+		h := sha256.New()
+		fmt.Fprintf(h, "%s|%s|%d|%d", seed, label, i, c)
+		out = h.Sum(out)
+	}
+	return out[:n]
+}
+
+// lengths returns plaintext lengths of the dialect, edge cases first.
+func lengths(d dialect, count int) []int {
+	var edge []int
+	top := 208
+	switch {
+	case d.envelope:
+		edge = []int{0, 1, 12, 13, 207, 208}
+	case d.pad == "iso":
+		edge, top = []int{0, 1, 11, 12, 206, 207}, 207
+	default:
+		edge = []int{12, 16, 52, 208}
+	}
+	ls := make([]int, count)
+	for i := range ls {
+		if i < len(edge) {
+			ls[i] = edge[i]
+			continue
+		}
+		r := int(binary.BigEndian.Uint16(prng("", "len", i, 2))) % (top + 1)
+		if d.pad == "none" && !d.envelope {
+			r = 12 + r%50*4 // 12..208, multiple of 4
+		}
+		ls[i] = r
+	}
+	return ls
+}
+
+func vectors(d dialect, seed string, count int) []vector {
+	vs := make([]vector, count)
+	for i, n := range lengths(d, count) {
+		v := vector{key: prng(seed, "key", i, 16), pt: prng(seed, "pt", i, n)}
+		// key bytes as the device holds them
+		kb := append([]byte(nil), v.key...)
+		if d.order == "le" {
+			xxtea.AsLEBE(kb)
+		}
+		k := xxtea.NewKey(kb)
+		switch {
+		case d.envelope:
+			h := envelope.Header{Flags: envelope.FlagLength, KeyID: uint16(i), Counter: uint32(i)}
+			v.ct, _ = envelope.Seal(k, h, v.pt)
+		case d.order == "le":
+			pt := append([]byte(nil), v.pt...)
+			if d.pad == "iso" {
+				pt = isoPad(pt)
+			}
+			v.ct = xxtea.AsLEBE(k.Encrypt(xxtea.AsLEBE(pt), make([]byte, len(pt))))
+		case d.pad == "iso":
+			v.ct = k.EncryptAny(v.pt, xxtea.PadISO)
+		default:
+			v.ct = k.Encrypt(v.pt, make([]byte, n))
+		}
+		vs[i] = v
+	}
+	return vs
+}
+
+// isoPad returns b padded as xxtea.PadISO does.
+func isoPad(b []byte) []byte {
+	b = append(b, 0x80)
+	for len(b)&3 != 0 || len(b) < 12 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func cBytes(b []byte) string {
+	if len(b) == 0 {
+		return "{0}"
+	}
+	s := make([]string, len(b))
+	for i, c := range b {
+		s[i] = fmt.Sprintf("0x%02x", c)
+	}
+	return "{" + strings.Join(s, ",") + "}"
+}
+
+func emitC(w io.Writer, cmdline string, d dialect, vs []vector) {
+	fmt.Fprintf(w, "/* Code generated by %s; DO NOT EDIT.\n * Dialect: %s. */\n\n", cmdline, d)
+	fmt.Fprint(w, "#include <stddef.h>\n#include <stdint.h>\n#include <string.h>\n\n")
+	if d.envelope {
+		fmt.Fprintln(w, "/* Provided by the code under test: opens the frame under the key\n * into out, returns plaintext length or -1. */")
+		fmt.Fprint(w, "int conform_open(uint8_t *out, const uint8_t *frame, size_t n, const uint8_t key[16]);\n\n")
+	} else {
+		fmt.Fprintln(w, "/* Provided by the code under test: return output length or -1. */")
+		fmt.Fprintln(w, "int conform_encrypt(uint8_t *out, const uint8_t *in, size_t n, const uint8_t key[16]);")
+		fmt.Fprint(w, "int conform_decrypt(uint8_t *out, const uint8_t *in, size_t n, const uint8_t key[16]);\n\n")
+	}
+	fmt.Fprint(w, "struct conform_vector {\n\tuint8_t key[16];\n\tconst uint8_t *pt, *ct;\n\tsize_t pt_len, ct_len;\n};\n\n")
+	for i, v := range vs {
+		fmt.Fprintf(w, "static const uint8_t conform_pt%d[] = %s;\n", i, cBytes(v.pt))
+		fmt.Fprintf(w, "static const uint8_t conform_ct%d[] = %s;\n", i, cBytes(v.ct))
+	}
+	fmt.Fprintln(w, "\nstatic const struct conform_vector conform_vectors[] = {")
+	for i, v := range vs {
+		fmt.Fprintf(w, "\t{%s, conform_pt%d, conform_ct%d, %d, %d},\n", cBytes(v.key), i, i, len(v.pt), len(v.ct))
+	}
+	fmt.Fprintln(w, "};\n\n/* conform_run returns the number of failed vectors. */\nint conform_run(void)\n{\n\tuint8_t buf[256];\n\tint failed = 0;\n\tsize_t i;")
+	fmt.Fprintln(w, "\tfor (i = 0; i < sizeof conform_vectors / sizeof conform_vectors[0]; i++) {\n\t\tconst struct conform_vector *v = &conform_vectors[i];")
+	if d.envelope {
+		fmt.Fprintln(w, "\t\tif (conform_open(buf, v->ct, v->ct_len, v->key) != (int)v->pt_len ||\n\t\t    memcmp(buf, v->pt, v->pt_len) != 0)\n\t\t\tfailed++;")
+	} else {
+		fmt.Fprintln(w, "\t\tif (conform_encrypt(buf, v->pt, v->pt_len, v->key) != (int)v->ct_len ||\n\t\t    memcmp(buf, v->ct, v->ct_len) != 0)\n\t\t\tfailed++;")
+		fmt.Fprintln(w, "\t\telse if (conform_decrypt(buf, v->ct, v->ct_len, v->key) != (int)v->pt_len ||\n\t\t    memcmp(buf, v->pt, v->pt_len) != 0)\n\t\t\tfailed++;")
+	}
+	fmt.Fprintln(w, "\t}\n\treturn failed;\n}")
+}
+
+func emitPython(w io.Writer, cmdline string, d dialect, vs []vector) {
+	fmt.Fprintf(w, "# Code generated by %s; DO NOT EDIT.\n# Dialect: %s.\n\n", cmdline, d)
+	fmt.Fprintln(w, "# (key, plaintext, ciphertext or frame)\nVECTORS = [")
+	for _, v := range vs {
+		fmt.Fprintf(w, "    (bytes.fromhex(%q), bytes.fromhex(%q), bytes.fromhex(%q)),\n", fmt.Sprintf("%x", v.key), fmt.Sprintf("%x", v.pt), fmt.Sprintf("%x", v.ct))
+	}
+	fmt.Fprint(w, "]\n\n")
+	if d.envelope {
+		fmt.Fprintln(w, "\ndef run(open_frame):\n    \"\"\"Returns indexes of failed vectors; open_frame(key, frame) -> bytes.\"\"\"")
+		fmt.Fprintln(w, "    return [i for i, (k, pt, f) in enumerate(VECTORS) if open_frame(k, f) != pt]")
+		return
+	}
+	fmt.Fprintln(w, "\ndef run(encrypt, decrypt):\n    \"\"\"Returns indexes of failed vectors; encrypt(key, data) -> bytes, decrypt likewise.\"\"\"")
+	fmt.Fprintln(w, "    return [i for i, (k, pt, ct) in enumerate(VECTORS)\n            if encrypt(k, pt) != ct or decrypt(k, ct) != pt]")
+}
+
+func emitJS(w io.Writer, cmdline string, d dialect, vs []vector) {
+	fmt.Fprintf(w, "// Code generated by %s; DO NOT EDIT.\n// Dialect: %s.\n\n", cmdline, d)
+	fmt.Fprintln(w, "const hex = (s) => Uint8Array.from(s.match(/../g) || [], (b) => parseInt(b, 16));")
+	fmt.Fprint(w, "const same = (a, b) => a.length === b.length && a.every((x, i) => x === b[i]);\n\n")
+	fmt.Fprintln(w, "// [key, plaintext, ciphertext or frame]\nexport const vectors = [")
+	for _, v := range vs {
+		fmt.Fprintf(w, "  [hex(%q), hex(%q), hex(%q)],\n", fmt.Sprintf("%x", v.key), fmt.Sprintf("%x", v.pt), fmt.Sprintf("%x", v.ct))
+	}
+	fmt.Fprint(w, "];\n\n")
+	if d.envelope {
+		fmt.Fprintln(w, "// run returns indexes of failed vectors; openFrame(key, frame) -> Uint8Array.")
+		fmt.Fprintln(w, "export function run(openFrame) {\n  return vectors.flatMap(([k, pt, f], i) => (same(openFrame(k, f), pt) ? [] : [i]));\n}")
+		return
+	}
+	fmt.Fprintln(w, "// run returns indexes of failed vectors; encrypt(key, data) -> Uint8Array, decrypt likewise.")
+	fmt.Fprintln(w, "export function run(encrypt, decrypt) {\n  return vectors.flatMap(([k, pt, ct], i) =>\n    same(encrypt(k, pt), ct) && same(decrypt(k, ct), pt) ? [] : [i]);\n}")
+}
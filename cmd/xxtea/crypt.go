@@ -0,0 +1,70 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+
+	"github.com/ohir/xxtea/stream"
+)
+
+func cryptFlags(fs *flag.FlagSet, verb string) (key *string, pipe *bool) {
+	key = fs.String("key", "", "key as 32 hex digits, or @file")
+	pipe = fs.Bool("pipe", false, verb+" stdin to stdout")
+	fs.Usage = func() {
+		fs.Output().Write([]byte("usage: " + fs.Name() + " --key K (--pipe | in out)\n"))
+		fs.PrintDefaults()
+	}
+	return
+}
+
+func runEnc(fs *flag.FlagSet, args []string, e *env) error {
+	key, pipe := cryptFlags(fs, "seal")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	k, err := parseKey(*key)
+	if err != nil {
+		return err
+	}
+	in, out, done, err := openIO(fs.Args(), *pipe, e)
+	if err != nil {
+		return err
+	}
+	defer done()
+	w, err := stream.NewWriter(out, k)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, in); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return done()
+}
+
+func runDec(fs *flag.FlagSet, args []string, e *env) error {
+	key, pipe := cryptFlags(fs, "open")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	k, err := parseKey(*key)
+	if err != nil {
+		return err
+	}
+	in, out, done, err := openIO(fs.Args(), *pipe, e)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if _, err = io.Copy(out, stream.NewReader(bufio.NewReader(in), k)); err != nil {
+		return err
+	}
+	return done()
+}
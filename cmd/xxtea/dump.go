@@ -0,0 +1,84 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func runDump(fs *flag.FlagSet, args []string, e *env) error {
+	hx := fs.Bool("hex", false, "input is hex text (whitespace ignored)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [--hex] [file]\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var in io.Reader = e.stdin
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	default:
+		return errUsage
+	}
+	b, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if *hx {
+		if b, err = hex.DecodeString(strings.Join(strings.Fields(string(b)), "")); err != nil {
+			return errors.New("bad hex input")
+		}
+	}
+	dump(e.stdout, b)
+	return nil
+}
+
+// dump prints b a word per line, with both word interpretations, flagging
+// bytes that break XXTEA length rules.
+func dump(w io.Writer, b []byte) {
+	fmt.Fprintln(w, "offset  bytes        BE word   LE word")
+	for i := 0; i < len(b); i += 4 {
+		if i+4 > len(b) {
+			fmt.Fprintf(w, "%04d    % X%*s  ! not a whole word\n", i, b[i:], 3*(4-len(b)+i)+20, "")
+			break
+		}
+		mark := ""
+		if i >= 208 {
+			mark = "  ! beyond 208 bytes"
+		}
+		fmt.Fprintf(w, "%04d    % X  %08X  %08X%s\n", i, b[i:i+4],
+			binary.BigEndian.Uint32(b[i:]), binary.LittleEndian.Uint32(b[i:]), mark)
+	}
+	var bad []string
+	if len(b) < 12 {
+		bad = append(bad, "shorter than 12")
+	}
+	if len(b) > 208 {
+		bad = append(bad, "longer than 208")
+	}
+	if len(b)&3 != 0 {
+		bad = append(bad, "not a multiple of 4")
+	}
+	if bad == nil {
+		fmt.Fprintf(w, "length %d: ok, %d words\n", len(b), len(b)/4)
+	} else {
+		fmt.Fprintf(w, "length %d: %s\n", len(b), strings.Join(bad, ", "))
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/ohir/xxtea/cmd/xxtea
+
+go 1.26.0
+
+require github.com/ohir/xxtea v0.0.0
+
+require (
+	golang.org/x/crypto v0.57.0
+	golang.org/x/sys v0.48.0 // indirect
+)
+
+replace github.com/ohir/xxtea => ../../
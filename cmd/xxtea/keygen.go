@@ -0,0 +1,107 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ohir/xxtea"
+	"golang.org/x/crypto/argon2"
+)
+
+type kdfFlags struct {
+	name    string
+	salt    string
+	time    uint
+	memory  uint
+	threads uint
+	iter    int
+}
+
+// derive returns 16 key bytes from the passphrase.
+func (f *kdfFlags) derive(pass []byte) ([]byte, error) {
+	if f.salt == "" {
+		return nil, errors.New("--salt is required with --from-passphrase")
+	}
+	var p xxtea.PassphraseParams
+	switch f.name {
+	case "argon2id":
+		if f.time == 0 || f.memory == 0 || f.threads == 0 || f.threads > 255 {
+			return nil, errors.New("bad argon2id parameters")
+		}
+		p.KDF = func(pass, salt []byte) []byte {
+			return argon2.IDKey(pass, salt, uint32(f.time), uint32(f.memory), uint8(f.threads), 16)
+		}
+	case "pbkdf2":
+		if f.iter <= 0 {
+			return nil, errors.New("bad pbkdf2 iteration count")
+		}
+		p.Iterations = f.iter
+	default:
+		return nil, fmt.Errorf("unknown kdf %q", f.name)
+	}
+	k, err := xxtea.NewKeyFromPassphrase(pass, []byte(f.salt), p)
+	if err != nil {
+		return nil, err
+	}
+	return k.Bytes(), nil
+}
+
+func runKeygen(fs *flag.FlagSet, args []string, e *env) error {
+	var kf kdfFlags
+	pass := fs.Bool("from-passphrase", false, "derive the key from a passphrase read from stdin")
+	fs.StringVar(&kf.name, "kdf", "argon2id", "passphrase `kdf`: argon2id or pbkdf2")
+	fs.StringVar(&kf.salt, "salt", "", "kdf salt, e.g. the device serial number")
+	fs.UintVar(&kf.time, "time", 3, "argon2id passes")
+	fs.UintVar(&kf.memory, "memory", 64*1024, "argon2id memory in `KiB`")
+	fs.UintVar(&kf.threads, "threads", 4, "argon2id parallelism")
+	fs.IntVar(&kf.iter, "iter", 600000, "pbkdf2 iterations")
+	format := fs.String("format", "hex", "output `format`: hex, base64, or words")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+	var kb []byte
+	var err error
+	if *pass {
+		line, err := bufio.NewReader(e.stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			return errors.New("empty passphrase")
+		}
+		if kb, err = kf.derive([]byte(line)); err != nil {
+			return err
+		}
+	} else {
+		if _, kb, err = xxtea.NewRandomKey(); err != nil {
+			return err
+		}
+	}
+	switch *format {
+	case "hex":
+		fmt.Fprintf(e.stdout, "%x\n", kb)
+	case "base64":
+		fmt.Fprintln(e.stdout, base64.StdEncoding.EncodeToString(kb))
+	case "words":
+		fmt.Fprintf(e.stdout, "{0x%08X, 0x%08X, 0x%08X, 0x%08X}\n",
+			binary.BigEndian.Uint32(kb), binary.BigEndian.Uint32(kb[4:]),
+			binary.BigEndian.Uint32(kb[8:]), binary.BigEndian.Uint32(kb[12:]))
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	return nil
+}
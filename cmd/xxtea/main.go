@@ -0,0 +1,158 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command xxtea encrypts and decrypts data with the xxtea packages.
+//
+// Usage:
+//
+//	xxtea <command> [flags] [args]
+//
+// Commands:
+//
+//	enc      seal input into a stream of records
+//	dec      open a stream of records
+//	dump     print ciphertext words, BE and LE, flagging length rule violations
+//	bench    print encrypt and decrypt speed on this host
+//	audit    try a wordlist or serial numbers as keys against known plaintext
+//	pack     seal a directory into an archive
+//	unpack   open an archive into a directory
+//	keygen   print a random or passphrase derived key
+//	selftest run known answer and reference cross-checks, exit 1 on mismatch
+//	conform  print vectors and a test harness in C, Python or JavaScript
+//
+// Keys are given as 32 hex digits, or as @file holding them.
+//
+//	cat log | xxtea enc --key @file --pipe > log.x
+//	xxtea dec --key @file log.x log
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ohir/xxtea"
+)
+
+type command struct {
+	help string
+	run  func(fs *flag.FlagSet, args []string, env *env) error
+}
+
+var commands = map[string]command{
+	"audit":    {"look for weakly provisioned keys", runAudit},
+	"enc":      {"seal input into a stream of records", runEnc},
+	"bench":    {"print encrypt and decrypt speed", runBench},
+	"dec":      {"open a stream of records", runDec},
+	"keygen":   {"print a random or passphrase derived key", runKeygen},
+	"pack":     {"seal a directory into an archive", runPack},
+	"unpack":   {"open an archive into a directory", runUnpack},
+	"selftest": {"run known answer and reference checks", runSelftest},
+	"dump":     {"print ciphertext words, BE and LE", runDump},
+	"conform":  {"print a conformance test harness for C, Python or JS", runConform},
+}
+
+// env is the command environment, replaced in tests.
+type env struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+var errUsage = errors.New("usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], &env{os.Stdin, os.Stdout, os.Stderr}))
+}
+
+func run(args []string, e *env) int {
+	if len(args) == 0 {
+		usage(e.stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(e.stderr, "xxtea: unknown command %q\n", args[0])
+		usage(e.stderr)
+		return 2
+	}
+	fs := flag.NewFlagSet("xxtea "+args[0], flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	err := cmd.run(fs, args[1:], e)
+	switch {
+	case err == flag.ErrHelp:
+		return 0
+	case err == errUsage:
+		fs.Usage()
+		return 2
+	case err != nil:
+		fmt.Fprintln(e.stderr, "xxtea:", err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: xxtea <command> [flags] [args]\n\ncommands:")
+	names := make([]string, 0, len(commands))
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Fprintf(w, "  %-9s %s\n", n, commands[n].help)
+	}
+}
+
+// parseKey returns the key given as 32 hex digits, or as @file holding them.
+func parseKey(s string) (k xxtea.TeaKey, err error) {
+	if s == "" {
+		return k, errors.New("no key given")
+	}
+	if strings.HasPrefix(s, "@") {
+		b, err := os.ReadFile(s[1:])
+		if err != nil {
+			return k, err
+		}
+		s = string(b)
+	}
+	k, err = xxtea.ParseKeyHex(strings.TrimSpace(s))
+	switch err {
+	case xxtea.ErrKeyText:
+		err = errors.New("key must be 32 hex digits")
+	case xxtea.ErrZeroKey:
+		err = errors.New("all-zeros key")
+	}
+	return k, err
+}
+
+// openIO returns input and output of a filter command: stdin and stdout in
+// pipe mode, or files named by args otherwise.
+func openIO(args []string, pipe bool, e *env) (io.Reader, io.Writer, func() error, error) {
+	if pipe {
+		if len(args) != 0 {
+			return nil, nil, nil, errUsage
+		}
+		return e.stdin, e.stdout, func() error { return nil }, nil
+	}
+	if len(args) != 2 {
+		return nil, nil, nil, errUsage
+	}
+	in, err := os.Open(args[0])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	out, err := os.Create(args[1])
+	if err != nil {
+		in.Close()
+		return nil, nil, nil, err
+	}
+	return in, out, func() error {
+		in.Close()
+		return out.Close()
+	}, nil
+}
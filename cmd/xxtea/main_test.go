@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+const keyHex = "30313233343536373839414243444546"
+
+func cli(stdin string, args ...string) (code int, stdout, stderr string) {
+	var o, e bytes.Buffer
+	code = run(args, &env{strings.NewReader(stdin), &o, &e})
+	return code, o.String(), e.String()
+}
+
+func Test_Usage(t *testing.T) {
+	if c, _, e := cli(""); c != 2 || !strings.Contains(e, "enc") {
+		t.Error("No usage without command")
+	}
+	if c, _, e := cli("", "nope"); c != 2 || !strings.Contains(e, "unknown command") {
+		t.Error("Unknown command accepted")
+	}
+	if c, _, _ := cli("", "enc", "-h"); c != 0 {
+		t.Error("Help failed")
+	}
+	if c, _, e := cli("", "enc", "--key", keyHex); c != 2 || !strings.Contains(e, "usage") {
+		t.Error("Missing args accepted")
+	}
+	if c, _, _ := cli("", "enc", "--key", keyHex, "--pipe", "x"); c != 2 {
+		t.Error("Args in pipe mode accepted")
+	}
+}
+
+func Test_Keys(t *testing.T) {
+	for _, k := range []string{"", "0123", "zz" + keyHex[2:], strings.Repeat("0", 32), "@/nonexistent"} {
+		if c, _, _ := cli("", "enc", "--key", k, "--pipe"); c != 1 {
+			t.Error("Bad key accepted:", k)
+		}
+	}
+	f := filepath.Join(t.TempDir(), "key")
+	os.WriteFile(f, []byte(keyHex+"\n"), 0600)
+	if c, _, e := cli("", "enc", "--key", "@"+f, "--pipe"); c != 0 {
+		t.Error("Key file not read", e)
+	}
+}
+
+func Test_Pipe(t *testing.T) {
+	msg := strings.Repeat("some log line\n", 100)
+	c, enc, e := cli(msg, "enc", "--key", keyHex, "--pipe")
+	if c != 0 || strings.Contains(enc, "log line") {
+		t.Fatal("enc failed", e)
+	}
+	c, dec, e := cli(enc, "dec", "--key", keyHex, "--pipe")
+	if c != 0 || dec != msg {
+		t.Error("dec failed", e)
+	}
+	if c, _, _ = cli(enc[:len(enc)-5], "dec", "--key", keyHex, "--pipe"); c != 1 {
+		t.Error("Truncated stream accepted")
+	}
+}
+
+func Test_Files(t *testing.T) {
+	d := t.TempDir()
+	in, x, out := filepath.Join(d, "in"), filepath.Join(d, "x"), filepath.Join(d, "out")
+	os.WriteFile(in, []byte("file content"), 0600)
+	if c, _, e := cli("", "enc", "--key", keyHex, in, x); c != 0 {
+		t.Fatal("enc failed", e)
+	}
+	if c, _, e := cli("", "dec", "--key", keyHex, x, out); c != 0 {
+		t.Fatal("dec failed", e)
+	}
+	if b, _ := os.ReadFile(out); string(b) != "file content" {
+		t.Error("Round trip failed")
+	}
+	if c, _, _ := cli("", "dec", "--key", keyHex, filepath.Join(d, "none"), out); c != 1 {
+		t.Error("Missing input accepted")
+	}
+	if c, _, _ := cli("", "dec", "--key", keyHex, x, filepath.Join(d, "no", "dir")); c != 1 {
+		t.Error("Bad output accepted")
+	}
+}
+
+func Test_Dump(t *testing.T) {
+	c, o, e := cli("AC CC CA 8A 36 BD 75 E3\nE2 E9 5A 1A", "dump", "--hex")
+	if c != 0 || !strings.Contains(o, "0000    AC CC CA 8A  ACCCCA8A  8ACACCAC") ||
+		!strings.Contains(o, "length 12: ok, 3 words") {
+		t.Error("Bad dump:\n", o, e)
+	}
+	c, o, _ = cli(strings.Repeat("x", 214), "dump")
+	if c != 0 || !strings.Contains(o, "0208    78 78 78 78  78787878  78787878  ! beyond 208") ||
+		!strings.Contains(o, "0212    78 78") || !strings.Contains(o, "! not a whole word") ||
+		!strings.Contains(o, "length 214: longer than 208, not a multiple of 4") {
+		t.Error("Bad dump:\n", o)
+	}
+	if _, o, _ = cli("abc", "dump"); !strings.Contains(o, "shorter than 12") {
+		t.Error("Short input not flagged:\n", o)
+	}
+	if c, _, _ = cli("zz", "dump", "--hex"); c != 1 {
+		t.Error("Bad hex accepted")
+	}
+	f := filepath.Join(t.TempDir(), "ct")
+	os.WriteFile(f, make([]byte, 16), 0600)
+	if c, o, _ = cli("", "dump", f); c != 0 || !strings.Contains(o, "length 16: ok") {
+		t.Error("File not dumped:\n", o)
+	}
+	if c, _, _ = cli("", "dump", f, f); c != 2 {
+		t.Error("Extra args accepted")
+	}
+	if c, _, _ = cli("", "dump", f+"none"); c != 1 {
+		t.Error("Missing file accepted")
+	}
+}
+
+func Test_Audit(t *testing.T) {
+	dir := t.TempDir()
+	pt := []byte(`{"temp":21.5, "id":1234}`)
+	kb := make([]byte, 16)
+	copy(kb, "SN00001234")
+	ct := xxtea.NewKey(xxtea.AsLEBE(kb)).Encrypt(pt, make([]byte, len(pt)))
+	cf := filepath.Join(dir, "ct")
+	os.WriteFile(cf, ct, 0600)
+	wf := filepath.Join(dir, "words")
+	os.WriteFile(wf, []byte("password\n\nSN00001234\n"+keyHex+"\n"), 0600)
+	known := hex.EncodeToString([]byte("\"temp\""))
+
+	c, o, e := cli("", "audit", "--known", known, "--at", "1", "--words", wf, cf)
+	if c != 0 || !strings.Contains(o, `from "SN00001234" via ascii-le`) ||
+		!strings.Contains(o, "7 candidate keys tried, 1 weak") {
+		t.Error("Wordlist audit failed:\n", o, e)
+	}
+	c, o, _ = cli(string(ct), "audit", "--known", known, "--at", "1",
+		"--serial", "1230-1239", "--format", "SN%08d", "--derive", "ascii-le")
+	if c != 0 || !strings.Contains(o, "10 candidate keys tried, 1 weak") {
+		t.Error("Serial audit failed:\n", o)
+	}
+	if _, o, _ = cli(string(ct), "audit", "--known", known, "--serial", "1-2"); !strings.Contains(o, ", 0 weak") {
+		t.Error("Bogus hit:\n", o)
+	}
+	os.WriteFile(wf, []byte(strings.Repeat("0", 32)+"\nSN00001234\n"), 0600)
+	c, o, e = cli("", "audit", "--known", known, "--at", "1", "--words", wf, cf)
+	if c != 0 || !strings.Contains(o, ", 1 weak") {
+		t.Error("Zero key candidate not skipped:\n", o, e)
+	}
+	c, o, e = cli(string(ct), "audit", "--known", known, "--serial", "0-3", "--format", "%032x", "--derive", "hex")
+	if c != 0 || !strings.Contains(o, "3 candidate keys tried, 0 weak") {
+		t.Error("Zero key serial not skipped:\n", o, e)
+	}
+	for _, a := range [][]string{
+		{"audit", "--known", "zz", "--serial", "1-2"},
+		{"audit", "--known", known, "--serial", "2-1"},
+		{"audit", "--known", known, "--serial", "1-2", "--derive", "rot13"},
+		{"audit", "--known", known, "--serial", "1-2", "--at", "30"},
+		{"audit", "--known", known, "--words", wf + "none"},
+	} {
+		if c, _, _ = cli(string(ct), a...); c != 1 {
+			t.Error("Bad audit accepted:", a)
+		}
+	}
+	if c, _, _ = cli("short", "audit", "--known", known, "--serial", "1-2"); c != 1 {
+		t.Error("Short ciphertext accepted")
+	}
+	if c, _, _ = cli("", "audit", "--known", known); c != 2 {
+		t.Error("Audit without candidates accepted")
+	}
+}
+
+func Test_Bench(t *testing.T) {
+	c, o, e := cli("", "bench", "--sizes", "12, 208", "--time", "1ms")
+	if c != 0 || !strings.Contains(o, "\n   12  ") || !strings.Contains(o, "\n  208  ") ||
+		!strings.Contains(o, "batch kernel "+xxtea.BatchKernel()) {
+		t.Error("Bench failed:\n", o, e)
+	}
+	for _, s := range []string{"8", "212", "14", "x"} {
+		if c, _, _ = cli("", "bench", "--sizes", s); c != 1 {
+			t.Error("Bad size accepted:", s)
+		}
+	}
+	if c, _, _ = cli("", "bench", "--time", "0s"); c != 2 {
+		t.Error("Zero time accepted")
+	}
+}
+
+func Test_Selftest(t *testing.T) {
+	if c, o, e := cli("", "selftest"); c != 0 || o != "selftest: ok\n" {
+		t.Error("Selftest failed:", o, e)
+	}
+	if c, _, _ := cli("", "selftest", "x"); c != 2 {
+		t.Error("Extra args accepted")
+	}
+}
+
+func Test_Keygen(t *testing.T) {
+	c, o, e := cli("", "keygen")
+	if c != 0 || len(o) != 33 {
+		t.Error("Random keygen failed:", o, e)
+	}
+	if _, o2, _ := cli("", "keygen"); o2 == o {
+		t.Error("Random keys repeat")
+	}
+	a := []string{"keygen", "--from-passphrase", "--salt", "SN0001", "--time", "1", "--memory", "64", "--threads", "1"}
+	c, o, e = cli("correct horse\n", a...)
+	if c != 0 || len(o) != 33 {
+		t.Error("Argon2id keygen failed:", o, e)
+	}
+	if _, o2, _ := cli("correct horse", a...); o2 != o {
+		t.Error("Argon2id keygen not deterministic")
+	}
+	if _, o2, _ := cli("correct horse", append(a, "--format", "words")...); !strings.HasPrefix(o2, "{0x"+strings.ToUpper(o[:8])+", 0x") {
+		t.Error("Bad words format:", o2)
+	}
+	if _, o2, _ := cli("correct horse", append(a, "--format", "base64")...); len(o2) != 25 {
+		t.Error("Bad base64 format:", o2)
+	}
+	if _, o2, _ := cli("correct horse", append(a, "--salt", "SN0002")...); o2 == o {
+		t.Error("Salt ignored")
+	}
+	if c, o, _ = cli("pw", "keygen", "--from-passphrase", "--kdf", "pbkdf2", "--iter", "10", "--salt", "s"); c != 0 || len(o) != 33 {
+		t.Error("Pbkdf2 keygen failed:", o)
+	}
+	for _, b := range [][]string{
+		{"keygen", "--from-passphrase"},
+		{"keygen", "--from-passphrase", "--salt", "s", "--kdf", "md5"},
+		{"keygen", "--from-passphrase", "--salt", "s", "--threads", "0"},
+		{"keygen", "--from-passphrase", "--salt", "s", "--kdf", "pbkdf2", "--iter", "0"},
+		{"keygen", "--format", "octal"},
+	} {
+		if c, _, _ = cli("pw", b...); c != 1 {
+			t.Error("Bad keygen accepted:", b)
+		}
+	}
+	if c, _, _ = cli("\n", "keygen", "--from-passphrase", "--salt", "s"); c != 1 {
+		t.Error("Empty passphrase accepted")
+	}
+}
+
+func Test_PackUnpack(t *testing.T) {
+	dir := t.TempDir()
+	src, dst, ar := filepath.Join(dir, "src"), filepath.Join(dir, "dst"), filepath.Join(dir, "cfg.xar")
+	os.MkdirAll(filepath.Join(src, "certs"), 0755)
+	os.WriteFile(filepath.Join(src, "wifi.conf"), []byte("psk=secret\n"), 0600)
+	os.WriteFile(filepath.Join(src, "certs", "ca.pem"), []byte("PEM"), 0644)
+	if c, _, e := cli("", "pack", "--key", keyHex, src, ar); c != 0 {
+		t.Fatal("Pack failed:", e)
+	}
+	if c, _, e := cli("", "unpack", "--key", keyHex, ar, dst); c != 0 {
+		t.Fatal("Unpack failed:", e)
+	}
+	b, _ := os.ReadFile(filepath.Join(dst, "certs", "ca.pem"))
+	st, err := os.Stat(filepath.Join(dst, "wifi.conf"))
+	if string(b) != "PEM" || err != nil || st.Mode().Perm() != 0600 {
+		t.Error("Unpacked files differ")
+	}
+	if c, _, _ := cli("", "unpack", "--key", keyHex, ar, dst); c != 1 {
+		t.Error("Existing files overwritten")
+	}
+	if c, _, _ := cli("", "unpack", "--key", "46454443424140393837363534333231", ar, dst+"2"); c != 1 {
+		t.Error("Wrong key accepted")
+	}
+	if c, _, _ := cli("", "pack", "--key", keyHex, src); c != 2 {
+		t.Error("Missing args accepted")
+	}
+	if c, _, _ := cli("", "pack", "--key", keyHex, src+"none", ar); c != 1 {
+		t.Error("Missing dir accepted")
+	}
+}
+
+func Test_Conform(t *testing.T) {
+	for _, d := range []dialect{{"be", "none", false}, {"le", "iso", false}, {"be", "none", true}} {
+		for i, v := range vectors(d, "test", 12) {
+			kb := append([]byte(nil), v.key...)
+			ct := append([]byte(nil), v.ct...)
+			var pt []byte
+			switch {
+			case d.envelope:
+				_, pt, _ = envelope.Open(xxtea.NewKey(kb), ct)
+			case d.order == "le":
+				xxtea.NewKey(xxtea.AsLEBE(kb)).Decrypt(xxtea.AsLEBE(ct), ct)
+				if pt = xxtea.AsLEBE(ct); pt[len(v.pt)] == 0x80 {
+					pt = pt[:len(v.pt)]
+				}
+			default:
+				pt = xxtea.NewKey(kb).Decrypt(ct, make([]byte, len(ct)))
+			}
+			if !bytes.Equal(pt, v.pt) {
+				t.Error("Vector does not decrypt", d, i)
+			}
+		}
+	}
+	_, a, _ := cli("", "conform", "--lang", "python", "--seed", "s")
+	_, b, _ := cli("", "conform", "--lang", "python", "--seed", "s")
+	if a != b || !strings.Contains(a, "def run(encrypt, decrypt)") {
+		t.Error("Conform output not reproducible")
+	}
+	for _, lang := range []string{"c", "js"} {
+		if c, o, e := cli("", "conform", "--lang", lang, "--envelope"); c != 0 || !strings.Contains(o, "DO NOT EDIT") {
+			t.Error("Conform failed", lang, e)
+		}
+	}
+	for _, args := range [][]string{{"--lang", "go"}, {"--order", "me"}, {"--envelope", "--order", "le"}} {
+		if c, _, _ := cli("", append([]string{"conform"}, args...)...); c != 1 {
+			t.Error("Bad conform flags accepted", args)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/ohir/xxtea/bundle"
+)
+
+func packFlags(fs *flag.FlagSet, args string) *string {
+	key := fs.String("key", "", "key as 32 hex digits, or @file")
+	fs.Usage = func() {
+		fs.Output().Write([]byte("usage: " + fs.Name() + " --key K " + args + "\n"))
+		fs.PrintDefaults()
+	}
+	return key
+}
+
+func runPack(fs *flag.FlagSet, args []string, e *env) error {
+	key := packFlags(fs, "dir archive")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+	k, err := parseKey(*key)
+	if err != nil {
+		return err
+	}
+	files, err := bundle.ReadFS(os.DirFS(fs.Arg(0)))
+	if err != nil {
+		return err
+	}
+	a, err := bundle.Pack(k, files)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fs.Arg(1), a, 0644)
+}
+
+// runUnpack writes archive files below dir.  It never overwrites
+// existing files.
+func runUnpack(fs *flag.FlagSet, args []string, e *env) error {
+	key := packFlags(fs, "archive dir")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+	k, err := parseKey(*key)
+	if err != nil {
+		return err
+	}
+	a, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	files, err := bundle.Unpack(k, a)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		name := filepath.Join(fs.Arg(1), filepath.FromSlash(f.Name))
+		if err = os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+		o, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, f.Mode)
+		if err != nil {
+			return err
+		}
+		_, err = o.Write(f.Data)
+		if cerr := o.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
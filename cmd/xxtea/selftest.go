@@ -0,0 +1,26 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/ohir/xxtea"
+)
+
+func runSelftest(fs *flag.FlagSet, args []string, e *env) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+	if err := xxtea.SelfTest(); err != nil {
+		return err
+	}
+	fmt.Fprintln(e.stdout, "selftest: ok")
+	return nil
+}
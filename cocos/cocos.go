@@ -0,0 +1,66 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cocos reads and writes Lua scripts and assets encrypted by
+// cocos2d-x games (setXXTEAKeyAndSign): a cleartext sign followed by data
+// encrypted as package compat does, little-endian words with the length
+// appended, under the key zero padded or truncated to 16 bytes.
+//
+// It is the "cocos2dx" profile of package savefile with a sign-first API,
+// for asset tools.  As there, nothing is authenticated: anyone with the
+// game binary has the key.
+package cocos
+
+import (
+	"bytes"
+
+	"github.com/ohir/xxtea/compat"
+	"github.com/ohir/xxtea/savefile"
+)
+
+// DefaultSign is the sign of the cocos2d-x project templates.
+const DefaultSign = "XXTEA"
+
+// MaxSign is the longest sign DetectSign looks for.
+const MaxSign = 64
+
+// IsEncrypted reports whether the file starts with the sign.
+func IsEncrypted(file, sign []byte) bool {
+	return len(sign) > 0 && bytes.HasPrefix(file, sign)
+}
+
+// Decrypt strips the sign off the file and returns the data decrypted
+// under the key.  It returns savefile.ErrSign for files without the sign,
+// and compat.ErrData for files not encrypted under the key.
+func Decrypt(file, key, sign []byte) ([]byte, error) {
+	c := savefile.Codec{Sign: sign, Key: key}
+	return c.Decode(file)
+}
+
+// Encrypt returns data encrypted under the key, prefixed with the sign,
+// as the game expects to load it.
+func Encrypt(data, key, sign []byte) []byte {
+	c := savefile.Codec{Sign: sign, Key: key}
+	return c.Encode(data)
+}
+
+// DetectSign finds the sign of a file encrypted under a known key, for
+// games whose sign is not known: it returns the longest prefix, up to
+// MaxSign bytes, after which the rest decrypts with a valid length word.
+// A wrong guess takes a 1 in 2^30 chance per prefix tried.
+func DetectSign(file, key []byte) ([]byte, bool) {
+	n := MaxSign
+	if n > len(file)-8 {
+		n = len(file) - 8
+	}
+	for i := n; i >= 0; i-- {
+		if (len(file)-i)&3 != 0 {
+			continue
+		}
+		if _, err := compat.Decrypt(file[i:], key); err == nil {
+			return file[:i], true
+		}
+	}
+	return nil, false
+}
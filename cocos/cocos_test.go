@@ -0,0 +1,43 @@
+package cocos
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ohir/xxtea/compat"
+	"github.com/ohir/xxtea/savefile"
+)
+
+func Test_RoundTrip(t *testing.T) {
+	key, sign := []byte("2dxLua"), []byte(DefaultSign)
+	script := []byte("print('hello from lua')")
+	f := Encrypt(script, key, sign)
+	if !IsEncrypted(f, sign) || IsEncrypted(script, sign) {
+		t.Error("IsEncrypted failed")
+	}
+	if !bytes.Equal(f[len(sign):], compat.Encrypt(script, key)) {
+		t.Error("Body is not compat ciphertext")
+	}
+	if d, err := Decrypt(f, key, sign); err != nil || !bytes.Equal(d, script) {
+		t.Error("Decrypt failed", err)
+	}
+	if _, err := Decrypt(f, key, []byte("OTHER")); err != savefile.ErrSign {
+		t.Error("Wrong sign accepted", err)
+	}
+	if _, err := Decrypt(f, []byte("wrong"), sign); err != compat.ErrData {
+		t.Error("Wrong key accepted", err)
+	}
+}
+
+func Test_DetectSign(t *testing.T) {
+	key := []byte("secret-key")
+	for _, sign := range []string{"", "XXTEA", "MyGame_v2", "s"} {
+		f := Encrypt([]byte("local t = {}\nreturn t\n"), key, []byte(sign))
+		if s, ok := DetectSign(f, key); !ok || string(s) != sign {
+			t.Error("Sign not detected", sign, string(s), ok)
+		}
+	}
+	if _, ok := DetectSign([]byte("short"), key); ok {
+		t.Error("Sign found in a short file")
+	}
+}
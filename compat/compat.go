@@ -0,0 +1,116 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package compat speaks the XXTEA scheme of the widespread xxtea-c,
+// xxtea-js, PHP and similar libraries (and of engines embedding them):
+// data of any length is read as little-endian words with its byte length
+// appended as an extra word, and encrypted whole; keys are zero padded or
+// truncated to 16 bytes and read as little-endian words.
+//
+// WARNING: there is no authentication and no nonce, and messages are not
+// limited to 208 bytes as package xxtea requires for its security
+// argument.  Use it only to read and write data of such software.
+package compat
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/internal/btea"
+)
+
+// ErrData is returned by Decrypt for data that was not made by Encrypt
+// under the key.
+var ErrData = errors.New("compat: bad length or key")
+
+// key returns the key prepared as those libraries do.
+func key(k []byte) *[4]uint32 {
+	var b [16]byte
+	copy(b[:], k)
+	var w [4]uint32
+	for i := range w {
+		w[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	return &w
+}
+
+// Encrypt returns data encrypted under the key.  Empty data gives nil, as
+// in the libraries.
+func Encrypt(data, k []byte) []byte {
+	return encrypt(data, key(k))
+}
+
+// EncryptKey is Encrypt under a TeaKey, eg. of xxtea.NewKeyCompat, for
+// gateways holding keys of such peers as TeaKeys.
+func EncryptKey(data []byte, k xxtea.TeaKey) []byte {
+	return encrypt(data, (*[4]uint32)(&k))
+}
+
+func encrypt(data []byte, k *[4]uint32) []byte {
+	if len(data) == 0 {
+		return nil
+	}
+	n := (len(data) + 3) / 4
+	v := make([]uint32, n+1)
+	var w [4]byte
+	for i := 0; i < n; i++ {
+		copy(w[:], data[4*i:]) // zero fill of the last word
+		v[i] = binary.LittleEndian.Uint32(w[:])
+		w = [4]byte{}
+	}
+	v[n] = uint32(len(data))
+	btea.Encrypt(v, k)
+	out := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(out[4*i:], x)
+	}
+	return out
+}
+
+// Decrypt returns data decrypted under the key.  It returns ErrData if the
+// length word does not fit, which mostly means a wrong key, and in
+// xxtea.Strict mode if bytes past the length are not zeros.
+func Decrypt(data, k []byte) ([]byte, error) {
+	return decrypt(data, key(k))
+}
+
+// DecryptKey is Decrypt under a TeaKey.
+func DecryptKey(data []byte, k xxtea.TeaKey) ([]byte, error) {
+	return decrypt(data, (*[4]uint32)(&k))
+}
+
+func decrypt(data []byte, k *[4]uint32) ([]byte, error) {
+	if len(data) < 8 || len(data)&3 != 0 {
+		return nil, ErrData
+	}
+	v := make([]uint32, len(data)/4)
+	for i := range v {
+		v[i] = binary.LittleEndian.Uint32(data[4*i:])
+	}
+	btea.Decrypt(v, k)
+	n := len(v) - 1
+	m := int64(v[n])
+	if m < int64(4*n-3) || m > int64(4*n) {
+		return nil, ErrData
+	}
+	out := make([]byte, 4*n)
+	for i, x := range v[:n] {
+		binary.LittleEndian.PutUint32(out[4*i:], x)
+	}
+	if xxtea.Strict() && !zeros(out[m:]) {
+		return nil, ErrData
+	}
+	return out[:m], nil
+}
+
+// zeros reports whether all bytes of b are zero.
+func zeros(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
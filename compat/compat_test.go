@@ -0,0 +1,79 @@
+package compat
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/internal/btea"
+)
+
+func Test_KnownAnswer(t *testing.T) {
+	// vector published with the xxtea-c, xxtea-js and PHP libraries
+	ct := Encrypt([]byte("Hello World! 你好，中国！"), []byte("1234567890"))
+	if base64.StdEncoding.EncodeToString(ct) != "QncB1C0rHQoZ1eRiPM4dsZtRi9pNrp7sqvX76cFXvrrIHXL6" {
+		t.Error("Known answer failed", base64.StdEncoding.EncodeToString(ct))
+	}
+}
+
+func Test_TeaKey(t *testing.T) {
+	ct := EncryptKey([]byte("Hello World! 你好，中国！"), xxtea.NewKeyCompat("1234567890"))
+	if base64.StdEncoding.EncodeToString(ct) != "QncB1C0rHQoZ1eRiPM4dsZtRi9pNrp7sqvX76cFXvrrIHXL6" {
+		t.Error("Known answer under TeaKey failed")
+	}
+	if p, err := DecryptKey(ct, xxtea.NewKeyCompat("1234567890")); err != nil || string(p) != "Hello World! 你好，中国！" {
+		t.Error("DecryptKey failed", err)
+	}
+}
+
+func Test_RoundTrip(t *testing.T) {
+	key := []byte("a key longer than sixteen bytes")
+	for n := 1; n < 40; n++ {
+		pt := bytes.Repeat([]byte{byte(n)}, n)
+		ct := Encrypt(pt, key)
+		if len(ct) != (n+3)/4*4+4 {
+			t.Error("Ciphertext length wrong", n, len(ct))
+		}
+		if p, err := Decrypt(ct, key); err != nil || !bytes.Equal(p, pt) {
+			t.Error("Round trip failed", n, err)
+		}
+	}
+	if Encrypt(nil, key) != nil {
+		t.Error("Empty data encrypted")
+	}
+}
+
+func Test_BadData(t *testing.T) {
+	ct := Encrypt([]byte("some secret save"), []byte("k1"))
+	if _, err := Decrypt(ct, []byte("k2")); err != ErrData {
+		t.Error("Decrypted under wrong key", err)
+	}
+	if _, err := Decrypt(ct[:len(ct)-1], []byte("k1")); err != ErrData {
+		t.Error("Unaligned data accepted", err)
+	}
+	if _, err := Decrypt(ct[:4], []byte("k1")); err != ErrData {
+		t.Error("Short data accepted", err)
+	}
+}
+
+func Test_Strict(t *testing.T) {
+	defer xxtea.SetStrict(false)
+	k := []byte("k1")
+	v := []uint32{binary.LittleEndian.Uint32([]byte("abcd")), 3} // 'd' past the length
+	btea.Encrypt(v, key(k))
+	ct := make([]byte, 8)
+	binary.LittleEndian.PutUint32(ct, v[0])
+	binary.LittleEndian.PutUint32(ct[4:], v[1])
+	if p, err := Decrypt(ct, k); err != nil || string(p) != "abc" {
+		t.Error("Lax Decrypt failed", err)
+	}
+	xxtea.SetStrict(true)
+	if _, err := Decrypt(ct, k); err != ErrData {
+		t.Error("Dirty tail accepted", err)
+	}
+	if p, err := Decrypt(Encrypt([]byte("abc"), k), k); err != nil || string(p) != "abc" {
+		t.Error("Strict Decrypt failed", err)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Deprecation tells of a panicking legacy function used in a process that
+// also uses its error returning replacement.
+type Deprecation struct {
+	Func    string // legacy function, eg. "TeaKey.Encrypt"
+	Instead string // its replacement, eg. "TeaKey.EncryptChecked"
+}
+
+// legacy functions, as bits of dep.seen
+const (
+	depNewKey = iota
+	depEncrypt
+	depDecrypt
+)
+
+var depNames = [...]Deprecation{
+	depNewKey:  {"NewKey", "MakeKey"},
+	depEncrypt: {"TeaKey.Encrypt", "TeaKey.EncryptChecked"},
+	depDecrypt: {"TeaKey.Decrypt", "TeaKey.DecryptChecked"},
+}
+
+var (
+	dep struct {
+		sync.Mutex
+		hook   func(Deprecation)
+		modern bool
+	}
+	depOn     int32  // hook set and replacements used
+	depModern int32  // replacements used
+	depSeen   uint32 // bits of legacy functions reported
+)
+
+// SetDeprecationHook sets the function told, once per legacy function,
+// that the function is used in a process using its replacement too, so
+// large code bases can find the calls left to migrate module by module.
+// Nil, the default, turns the warnings off.
+//
+//	xxtea.SetDeprecationHook(func(d xxtea.Deprecation) {
+//		log.Printf("%s is deprecated, use %s", d.Func, d.Instead)
+//	})
+//
+// The hook is called from the goroutine of the call reported; it must
+// not call back into this package.
+func SetDeprecationHook(f func(Deprecation)) {
+	dep.Lock()
+	defer dep.Unlock()
+	dep.hook = f
+	depUpdate()
+}
+
+// depUpdate sets depOn from dep, locked.
+func depUpdate() {
+	var on int32
+	if dep.hook != nil && dep.modern {
+		on = 1
+	}
+	atomic.StoreInt32(&depOn, on)
+}
+
+// modern marks a replacement of legacy functions used.
+func modern() {
+	if atomic.LoadInt32(&depModern) != 0 {
+		return
+	}
+	dep.Lock()
+	defer dep.Unlock()
+	dep.modern = true
+	atomic.StoreInt32(&depModern, 1)
+	depUpdate()
+}
+
+// legacy reports use of the legacy function by code outside this module,
+// if warnings are on and it was not reported yet.
+func legacy(f int) {
+	if atomic.LoadInt32(&depOn) == 0 || atomic.LoadUint32(&depSeen)&(1<<f) != 0 || inModule() {
+		return
+	}
+	for {
+		s := atomic.LoadUint32(&depSeen)
+		if s&(1<<f) != 0 {
+			return
+		}
+		if atomic.CompareAndSwapUint32(&depSeen, s, s|1<<f) {
+			break
+		}
+	}
+	dep.Lock()
+	hook := dep.hook
+	dep.Unlock()
+	if hook != nil {
+		hook(depNames[f])
+	}
+}
+
+// inModule reports whether the caller of a legacy function is a package
+// of this module, whose calls are not for the user to migrate.
+func inModule() bool {
+	var pc [1]uintptr
+	if runtime.Callers(4, pc[:]) == 0 { // Callers, inModule, legacy, the legacy function
+		return false
+	}
+	fr, _ := runtime.CallersFrames(pc[:]).Next()
+	return strings.HasPrefix(fr.Function, "github.com/ohir/xxtea.") ||
+		strings.HasPrefix(fr.Function, "github.com/ohir/xxtea/")
+}
+
+// MakeKey is NewKey returning ErrKeyLen or ErrZeroKey instead of
+// panicking.
+func MakeKey(key []byte) (TeaKey, error) {
+	modern()
+	if len(key) != 16 {
+		return TeaKey{}, ErrKeyLen
+	}
+	var b [16]byte
+	copy(b[:], key)
+	return keyOf(&b, 0)
+}
+
+// TeaKey.EncryptChecked is Encrypt returning the misuse code of bad
+// lengths instead of panicking.
+func (k TeaKey) EncryptChecked(in, out []byte) ([]byte, error) {
+	modern()
+	if err := chkMsg(len(in), len(out)); err != nil {
+		return nil, err
+	}
+	return k.encryptBytes(in, out), nil
+}
+
+// TeaKey.DecryptChecked is Decrypt returning the misuse code of bad
+// lengths instead of panicking.
+func (k TeaKey) DecryptChecked(in, out []byte) ([]byte, error) {
+	modern()
+	if err := chkMsg(len(in), len(out)); err != nil {
+		return nil, err
+	}
+	return k.decryptBytes(in, out), nil
+}
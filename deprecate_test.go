@@ -0,0 +1,46 @@
+package xxtea_test
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+func Test_Deprecation(t *testing.T) {
+	var got []xxtea.Deprecation
+	defer xxtea.SetDeprecationHook(nil)
+	xxtea.SetDeprecationHook(func(d xxtea.Deprecation) { got = append(got, d) })
+	key := xxtea.NewKey([]byte("0123456789ABCDEF"))
+	msg := make([]byte, 16)
+	key.Encrypt(msg, msg)
+	if len(got) != 0 {
+		t.Error("Warned before replacements were used", got)
+	}
+	k2, err := xxtea.MakeKey([]byte("0123456789ABCDEF"))
+	if err != nil || k2 != key {
+		t.Fatal("MakeKey failed", err)
+	}
+	if _, err = key.EncryptChecked(msg, msg[:12]); err != xxtea.ErrLenMismatch {
+		t.Error("EncryptChecked misuse not returned", err)
+	}
+	if _, err = key.DecryptChecked(msg, msg); err != nil {
+		t.Error("DecryptChecked failed", err)
+	}
+	envelope.Seal(key, envelope.Header{}, msg) // calls Encrypt within the module
+	if len(got) != 0 {
+		t.Error("Warned of calls within the module", got)
+	}
+	key.Encrypt(msg, msg)
+	key.Encrypt(msg, msg)
+	xxtea.NewKey([]byte("0123456789ABCDEF"))
+	if len(got) != 2 || got[0].Func != "TeaKey.Encrypt" || got[1].Instead != "MakeKey" {
+		t.Error("Legacy calls not reported once", got)
+	}
+	if _, err = xxtea.MakeKey(make([]byte, 16)); err != xxtea.ErrZeroKey {
+		t.Error("Zero key made", err)
+	}
+	if _, err = xxtea.MakeKey(make([]byte, 15)); err != xxtea.ErrKeyLen {
+		t.Error("Short key made", err)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package eeprom persists small secrets and settings in fixed EEPROM or
+// flash slots, written round robin to spread the wear.
+//
+// Slot content is:
+//
+//	frame length (2B BE) | envelope frame | 0xFF fill
+//
+// The frame is sealed with FlagLength under a subkey of the key, with the
+// record version in the epoch and the write counter in the counter field.
+// An interrupted write leaves a slot that does not authenticate, so
+// Latest falls back to the newest record that does.
+package eeprom
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+// Overhead is the slot space taken by a record besides its payload
+// (payload is also padded to 4B words, 12B at least).
+const Overhead = 2 + envelope.HeaderSize + 2 + envelope.TagSizeV2
+
+var (
+	ErrSlot     = errors.New("eeprom: record does not fit the slot")
+	ErrNoRecord = errors.New("eeprom: no valid record")
+	ErrCounter  = errors.New("eeprom: write counter exhausted")
+)
+
+var lblEEPROM = []byte("eeprom")
+
+// Record is the content of a slot.
+type Record struct {
+	Version uint32 // record layout version, up to the application
+	Counter uint32 // write counter, grows with every write
+	Payload []byte
+}
+
+// Codec encodes records into slots of Size bytes under the Key.
+type Codec struct {
+	Key  xxtea.TeaKey
+	Size int
+}
+
+// Encode returns the record as slot content, filled to Size with 0xFF.
+func (c *Codec) Encode(r Record) ([]byte, error) {
+	h := envelope.Header{Flags: envelope.FlagLength, Epoch: r.Version, Counter: r.Counter}
+	f, err := envelope.Seal(c.Key.Derive(lblEEPROM), h, r.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if 2+len(f) > c.Size {
+		return nil, ErrSlot
+	}
+	slot := make([]byte, c.Size)
+	binary.BigEndian.PutUint16(slot, uint16(len(f)))
+	copy(slot[2:], f)
+	for i := 2 + len(f); i < len(slot); i++ {
+		slot[i] = 0xFF
+	}
+	return slot, nil
+}
+
+// Decode returns the record of slot content.  Erased and torn slots give
+// an error, as forged ones do.
+func (c *Codec) Decode(slot []byte) (r Record, err error) {
+	if len(slot) < 2 {
+		return r, envelope.ErrFrame
+	}
+	n := int(binary.BigEndian.Uint16(slot))
+	if 2+n > len(slot) {
+		return r, envelope.ErrFrame
+	}
+	h, p, err := envelope.Open(c.Key.Derive(lblEEPROM), slot[2:2+n])
+	if err != nil {
+		return r, err
+	}
+	return Record{Version: h.Epoch, Counter: h.Counter, Payload: p}, nil
+}
+
+// Latest returns the valid record of the highest counter and its slot
+// index.  It returns ErrNoRecord if no slot holds a valid record.
+func (c *Codec) Latest(slots [][]byte) (r Record, idx int, err error) {
+	idx = -1
+	for i, s := range slots {
+		rec, err := c.Decode(s)
+		if err == nil && (idx < 0 || rec.Counter > r.Counter) {
+			r, idx = rec, i
+		}
+	}
+	if idx < 0 {
+		return r, idx, ErrNoRecord
+	}
+	return r, idx, nil
+}
+
+// Next returns the slot index and counter of the next write: the slot
+// after that of the latest record, so writes go round robin.  With no
+// valid record it starts at slot 0 and counter 0.
+func (c *Codec) Next(slots [][]byte) (idx int, counter uint32, err error) {
+	r, i, err := c.Latest(slots)
+	if err == ErrNoRecord {
+		return 0, 0, nil
+	}
+	if r.Counter == 1<<32-1 {
+		return 0, 0, ErrCounter
+	}
+	return (i + 1) % len(slots), r.Counter + 1, nil
+}
@@ -0,0 +1,63 @@
+package eeprom
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+const keyBEBE = "0123456789ABCDEF"
+
+func erased(n, size int) [][]byte {
+	slots := make([][]byte, n)
+	for i := range slots {
+		slots[i] = bytes.Repeat([]byte{0xFF}, size)
+	}
+	return slots
+}
+
+func Test_EncodeDecode(t *testing.T) {
+	c := &Codec{Key: xxtea.NewKey([]byte(keyBEBE)), Size: 64}
+	r := Record{Version: 2, Counter: 7, Payload: []byte("wifi-psk")}
+	slot, err := c.Encode(r)
+	if err != nil || len(slot) != 64 || slot[63] != 0xFF {
+		t.Fatal("Encode failed", err)
+	}
+	g, err := c.Decode(slot)
+	if err != nil || g.Version != 2 || g.Counter != 7 || string(g.Payload) != "wifi-psk" {
+		t.Error("Decode failed", err)
+	}
+	if _, err := c.Encode(Record{Payload: make([]byte, 64-Overhead+1)}); err != ErrSlot {
+		t.Error("Oversized record encoded", err)
+	}
+	if _, err := c.Encode(Record{Payload: make([]byte, 64-Overhead)}); err != nil {
+		t.Error("Record of full slot not encoded", err)
+	}
+	if _, err := c.Decode(erased(1, 64)[0]); err == nil {
+		t.Error("Erased slot decoded")
+	}
+}
+
+func Test_Latest(t *testing.T) {
+	c := &Codec{Key: xxtea.NewKey([]byte(keyBEBE)), Size: 48}
+	slots := erased(3, 48)
+	if _, _, err := c.Latest(slots); err != ErrNoRecord {
+		t.Error("Record found in erased slots", err)
+	}
+	for w := 0; w < 5; w++ {
+		i, n, err := c.Next(slots)
+		if err != nil || i != w%3 || n != uint32(w) {
+			t.Fatal("Next failed", w, i, n, err)
+		}
+		slots[i], _ = c.Encode(Record{Counter: n, Payload: []byte{byte(w)}})
+	}
+	r, i, err := c.Latest(slots)
+	if err != nil || i != 1 || r.Counter != 4 || r.Payload[0] != 4 {
+		t.Error("Latest failed", i, r.Counter, err)
+	}
+	slots[1][20] ^= 1 // torn write of the newest record
+	if r, i, _ = c.Latest(slots); i != 0 || r.Counter != 3 {
+		t.Error("Latest did not fall back", i, r.Counter)
+	}
+}
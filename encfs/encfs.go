@@ -0,0 +1,150 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package encfs serves files of an encrypted archive (see package bundle)
+// as an io/fs.FS, so eg. an embedded web UI kept encrypted on flash can be
+// served with http.FileServer(http.FS(fsys)).
+//
+// Archive is authenticated once by Open; a file is decrypted on every
+// open of it, so plaintext stays in memory only while the file is in use.
+package encfs
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/bundle"
+)
+
+type encFS struct {
+	r    *bundle.Reader
+	idx  map[string]int      // file name to entry index
+	dirs map[string][]string // directory to sorted child names
+}
+
+// Open authenticates the archive under the key and returns its file tree.
+// Directories are implied by file names.  Archive must not be modified
+// while the returned FS is in use.
+func Open(archive []byte, key xxtea.TeaKey) (fs.FS, error) {
+	r, err := bundle.NewReader(key, archive)
+	if err != nil {
+		return nil, err
+	}
+	f := &encFS{r: r, idx: make(map[string]int), dirs: map[string][]string{".": nil}}
+	for i, e := range r.Entries {
+		f.idx[e.Name] = i
+		for name := e.Name; name != "."; name = path.Dir(name) {
+			parent := path.Dir(name)
+			f.dirs[parent] = append(f.dirs[parent], path.Base(name))
+		}
+	}
+	for d, names := range f.dirs {
+		sort.Strings(names)
+		f.dirs[d] = dedup(names)
+	}
+	return f, nil
+}
+
+func dedup(s []string) []string {
+	out := s[:0]
+	for i, v := range s {
+		if i == 0 || v != s[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Open implements fs.FS.
+func (f *encFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if i, ok := f.idx[name]; ok {
+		data, err := f.r.ReadFile(i)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &file{bytes.NewReader(data), f.stat(name)}, nil
+	}
+	if _, ok := f.dirs[name]; ok {
+		return &dir{f: f, name: name}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// stat returns info of a file or directory known to exist.
+func (f *encFS) stat(name string) *info {
+	if i, ok := f.idx[name]; ok {
+		e := &f.r.Entries[i]
+		return &info{path.Base(e.Name), e.Size, e.Mode}
+	}
+	return &info{path.Base(name), 0, fs.ModeDir | 0555}
+}
+
+// info implements fs.FileInfo and fs.DirEntry.
+type info struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+func (i *info) Name() string               { return i.name }
+func (i *info) Size() int64                { return i.size }
+func (i *info) Mode() fs.FileMode          { return i.mode }
+func (i *info) ModTime() time.Time         { return time.Time{} }
+func (i *info) IsDir() bool                { return i.mode.IsDir() }
+func (i *info) Sys() any                   { return nil }
+func (i *info) Type() fs.FileMode          { return i.mode.Type() }
+func (i *info) Info() (fs.FileInfo, error) { return i, nil }
+
+// file is an open regular file.  It is an io.Seeker, as http.FileServer
+// wants.
+type file struct {
+	*bytes.Reader
+	info *info
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Close() error               { return nil }
+
+// dir is an open directory.
+type dir struct {
+	f    *encFS
+	name string
+	off  int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.f.stat(d.name), nil }
+func (d *dir) Close() error               { return nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	names := d.f.dirs[d.name][d.off:]
+	if n > 0 && len(names) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < len(names) {
+		names = names[:n]
+	}
+	d.off += len(names)
+	out := make([]fs.DirEntry, len(names))
+	for i, nm := range names {
+		full := nm
+		if d.name != "." {
+			full = d.name + "/" + nm
+		}
+		out[i] = d.f.stat(full)
+	}
+	return out, nil
+}
@@ -0,0 +1,59 @@
+package encfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/bundle"
+)
+
+var key = xxtea.NewKey([]byte("0123456789ABCDEF"))
+
+func archive(t *testing.T) []byte {
+	a, err := bundle.Pack(key, []bundle.File{
+		{Name: "index.html", Mode: 0644, Data: []byte("<h1>device</h1>")},
+		{Name: "static/app.js", Mode: 0644, Data: []byte("init()")},
+		{Name: "static/css/site.css", Mode: 0600, Data: []byte("h1{}")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func Test_FS(t *testing.T) {
+	fsys, err := Open(archive(t), key)
+	if err != nil {
+		t.Fatal("Open failed:", err)
+	}
+	if err = fstest.TestFS(fsys, "index.html", "static/app.js", "static/css/site.css"); err != nil {
+		t.Error(err)
+	}
+	if _, err = fsys.Open("nope"); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("Missing file opened:", err)
+	}
+	if _, err = Open(archive(t), xxtea.NewKey([]byte("FEDCBA9876543210"))); err != bundle.ErrMAC {
+		t.Error("Other key accepted:", err)
+	}
+}
+
+func Test_FileServer(t *testing.T) {
+	fsys, _ := Open(archive(t), key)
+	srv := httptest.NewServer(http.FileServer(http.FS(fsys)))
+	defer srv.Close()
+	res, err := http.Get(srv.URL + "/static/app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 200 || string(b) != "init()" {
+		t.Error("FileServer failed:", res.Status, string(b))
+	}
+}
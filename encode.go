@@ -0,0 +1,83 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// ErrEncoding is returned for ciphertext text that does not decode.
+var ErrEncoding = errors.New("xxtea: bad hex or base64 ciphertext")
+
+// TeaKey.EncryptToHex returns 'in' encrypted, in lower case hex.  Lengths
+// are those of Encrypt, and panic as for it.
+func (k TeaKey) EncryptToHex(in []byte) string {
+	return hex.EncodeToString(k.encryptCopy(in))
+}
+
+// TeaKey.DecryptFromHex returns the plaintext of hex ciphertext, of either
+// case, or lower case only in Strict mode.  It returns ErrEncoding for text
+// that is not hex, and the misuse code of ciphertext of a length Decrypt
+// does not take.
+func (k TeaKey) DecryptFromHex(s string) ([]byte, error) {
+	if Strict() && strings.ContainsAny(s, "ABCDEF") {
+		return nil, ErrEncoding
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, ErrEncoding
+	}
+	return k.decryptText(b)
+}
+
+// TeaKey.EncryptToBase64 returns 'in' encrypted, in base64 of enc, eg.
+// base64.URLEncoding or RawURLEncoding for URLs; nil is StdEncoding.
+// Lengths are those of Encrypt, and panic as for it.
+func (k TeaKey) EncryptToBase64(in []byte, enc *base64.Encoding) string {
+	if enc == nil {
+		enc = base64.StdEncoding
+	}
+	return enc.EncodeToString(k.encryptCopy(in))
+}
+
+// TeaKey.DecryptFromBase64 returns the plaintext of base64 ciphertext of
+// enc; nil is StdEncoding.  In Strict mode the text must be as
+// EncryptToBase64 gives it: no line breaks, and zero padding bits.  Errors
+// are those of DecryptFromHex.
+func (k TeaKey) DecryptFromBase64(s string, enc *base64.Encoding) ([]byte, error) {
+	if enc == nil {
+		enc = base64.StdEncoding
+	}
+	if Strict() {
+		if strings.ContainsAny(s, "\r\n") {
+			return nil, ErrEncoding
+		}
+		enc = enc.Strict()
+	}
+	b, err := enc.DecodeString(s)
+	if err != nil {
+		return nil, ErrEncoding
+	}
+	return k.decryptText(b)
+}
+
+// encryptCopy returns in encrypted into a new slice.
+func (k TeaKey) encryptCopy(in []byte) []byte {
+	if err := chkMsg(len(in), len(in)); err != nil {
+		panic(err)
+	}
+	return k.encryptBytes(in, make([]byte, len(in)))
+}
+
+// decryptText decrypts decoded ciphertext b in place.
+func (k TeaKey) decryptText(b []byte) ([]byte, error) {
+	if err := chkMsg(len(b), len(b)); err != nil {
+		return nil, err
+	}
+	return k.decryptBytes(b, b), nil
+}
@@ -0,0 +1,68 @@
+package xxtea
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func Test_EncryptToText(t *testing.T) {
+	k := NewKey([]byte(keyBEBE))
+	ct := k.Encrypt([]byte(msgMax), make(bs, len(msgMax)))
+	h := k.EncryptToHex([]byte(msgMax))
+	if h != hex.EncodeToString(ct) {
+		t.Error("EncryptToHex failed")
+	}
+	if pt, err := k.DecryptFromHex(h); err != nil || string(pt) != msgMax {
+		t.Error("DecryptFromHex failed", err)
+	}
+	for _, enc := range []*base64.Encoding{nil, base64.StdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		s := k.EncryptToBase64([]byte(msgMax), enc)
+		if enc != nil && s != enc.EncodeToString(ct) {
+			t.Error("EncryptToBase64 failed", s)
+		}
+		if pt, err := k.DecryptFromBase64(s, enc); err != nil || string(pt) != msgMax {
+			t.Error("DecryptFromBase64 failed", err)
+		}
+	}
+	for _, tc := range []struct {
+		s   string
+		err error
+	}{
+		{"xyz", ErrEncoding},
+		{"00112233", ErrMsgShort},
+		{hex.EncodeToString(make(bs, 14)), ErrMsgAlign},
+	} {
+		if _, err := k.DecryptFromHex(tc.s); err != tc.err {
+			t.Error("Bad hex accepted", tc.s, err)
+		}
+	}
+	if _, err := k.DecryptFromBase64("!!!!", nil); err != ErrEncoding {
+		t.Error("Bad base64 accepted", err)
+	}
+	defer SetStrict(false)
+	h = k.EncryptToHex([]byte(msgMin))
+	b := k.EncryptToBase64([]byte(msgMax[:16]), nil) // ends "x==", 4 padding bits of x
+	const std = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	alt := b[:len(b)-3] + string(std[strings.IndexByte(std, b[len(b)-3])^1]) + "=="
+	for _, strict := range []bool{false, true} {
+		SetStrict(strict)
+		_, err1 := k.DecryptFromHex(strings.ToUpper(h))
+		_, err2 := k.DecryptFromBase64(alt, nil)
+		_, err3 := k.DecryptFromBase64(b[:8]+"\n"+b[8:], nil)
+		if strict != (err1 == ErrEncoding) || strict != (err2 == ErrEncoding) || strict != (err3 == ErrEncoding) {
+			t.Error("Non-canonical text not handled as mode says", strict, err1, err2, err3)
+		}
+		if pt, err := k.DecryptFromBase64(b, nil); err != nil || string(pt) != msgMax[:16] {
+			t.Error("Canonical base64 refused", strict, err)
+		}
+	}
+	SetStrict(false)
+	defer func() {
+		if recover() != ErrMsgShort {
+			t.Error("EncryptToHex misuse should panic")
+		}
+	}()
+	k.EncryptToHex(make(bs, 8))
+}
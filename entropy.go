@@ -0,0 +1,103 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"sync"
+)
+
+var (
+	entMu sync.RWMutex
+	ent   io.Reader = rand.Reader
+)
+
+// SetEntropy makes r the source of randomness of this package and its
+// subpackages: nonces, stream ids, random keys and test messages.  It is
+// for targets where crypto/rand is missing or slow, eg. a hardware TRNG
+// under TinyGo.  Nil restores crypto/rand.
+//
+// WARNING: r must be a cryptographically secure source.  Everything built
+// on a weak one is weak.
+func SetEntropy(r io.Reader) {
+	if r == nil {
+		r = rand.Reader
+	}
+	entMu.Lock()
+	ent = r
+	entMu.Unlock()
+}
+
+// Entropy returns the current source of randomness.
+func Entropy() io.Reader {
+	entMu.RLock()
+	defer entMu.RUnlock()
+	return ent
+}
+
+// ReadEntropy fills b from the source of randomness.
+func ReadEntropy(b []byte) error {
+	_, err := io.ReadFull(Entropy(), b)
+	return err
+}
+
+// SeededReader is a deterministic stream of bytes determined by a seed:
+// SHA-256 of seed and a 64-bit block counter.  Set as the entropy source,
+// it makes nonces, stream ids and keys, and so whole protocol traces,
+// reproducible bit-exactly in regression tests and device simulators.
+//
+// WARNING: never use it outside tests; its output is known to anyone who
+// knows the seed.
+type SeededReader struct {
+	seed []byte
+	ctr  uint64
+	buf  []byte
+}
+
+// NewSeededReader returns a SeededReader of the seed.
+func NewSeededReader(seed []byte) *SeededReader {
+	return &SeededReader{seed: append([]byte(nil), seed...)}
+}
+
+// Read fills p with the next bytes of the stream.  It never fails.
+func (r *SeededReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			h := sha256.New()
+			h.Write(r.seed)
+			var c [8]byte
+			binary.BigEndian.PutUint64(c[:], r.ctr)
+			h.Write(c[:])
+			r.buf, r.ctr = h.Sum(nil), r.ctr+1
+		}
+		c := copy(p[n:], r.buf)
+		r.buf, n = r.buf[c:], n+c
+	}
+	return n, nil
+}
+
+// NewRandomKey returns a new key read from the source of randomness, and
+// its 16 bytes as Bytes gives them, eg. to store at provisioning.  An all
+// zero read, to be expected once in 2^128 tries, is read again; a source
+// giving zeros four times in a row is broken and gives ErrZeroKey.  Errors
+// of the source are returned as they are.
+func NewRandomKey() (TeaKey, []byte, error) {
+	var w [16]byte
+	b := make([]byte, 16)
+	for try := 0; try < 4; try++ {
+		if err := ReadEntropy(b); err != nil {
+			return TeaKey{}, nil, err
+		}
+		copy(w[:], b)
+		if k, err := keyOf(&w, 0); err == nil {
+			return k, b, nil
+		}
+	}
+	return TeaKey{}, nil, ErrZeroKey
+}
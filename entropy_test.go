@@ -0,0 +1,74 @@
+package xxtea
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func Test_Entropy(t *testing.T) {
+	defer SetEntropy(nil)
+	SetEntropy(bytes.NewReader([]byte("0123456789")))
+	b := make([]byte, 4)
+	if err := ReadEntropy(b); err != nil || string(b) != "0123" {
+		t.Error("Entropy not read from the set source", err)
+	}
+	SetEntropy(iotest.ErrReader(iotest.ErrTimeout))
+	if err := ReadEntropy(b); err != iotest.ErrTimeout {
+		t.Error("Entropy error not returned", err)
+	}
+	if err := VerifyAgainstReference(NewKey([]byte(keyBEBE)), []int{12}, 1); err != iotest.ErrTimeout {
+		t.Error("VerifyAgainstReference did not use the source", err)
+	}
+	SetEntropy(nil)
+	if Entropy() != rand.Reader {
+		t.Error("Nil did not restore crypto/rand")
+	}
+}
+
+func Test_SeededReader(t *testing.T) {
+	a, b := make([]byte, 100), make([]byte, 100)
+	NewSeededReader([]byte("trace-1")).Read(a)
+	r := NewSeededReader([]byte("trace-1"))
+	r.Read(b[:7]) // chunking does not matter
+	r.Read(b[7:50])
+	r.Read(b[50:])
+	if !bytes.Equal(a, b) {
+		t.Error("Seeded stream not reproducible")
+	}
+	NewSeededReader([]byte("trace-2")).Read(b)
+	if bytes.Equal(a, b) || bytes.Equal(a[:32], a[32:64]) {
+		t.Error("Seeded stream repeats")
+	}
+	defer SetEntropy(nil)
+	SetEntropy(NewSeededReader([]byte("trace-1")))
+	ReadEntropy(b)
+	if !bytes.Equal(a, b) {
+		t.Error("Seeded entropy source not used")
+	}
+}
+
+func Test_NewRandomKey(t *testing.T) {
+	defer SetEntropy(nil)
+	k, b, err := NewRandomKey()
+	if err != nil || k == (TeaKey{}) || !bytes.Equal(b, k.Bytes()) {
+		t.Error("NewRandomKey failed", err)
+	}
+	if l, _, _ := NewRandomKey(); l == k {
+		t.Error("NewRandomKey repeats")
+	}
+	SetEntropy(io.MultiReader(bytes.NewReader(make([]byte, 16)), bytes.NewReader([]byte(keyBEBE))))
+	if k, b, err = NewRandomKey(); err != nil || string(b) != keyBEBE || k != NewKey([]byte(keyBEBE)) {
+		t.Error("Zero read not retried", err)
+	}
+	SetEntropy(bytes.NewReader(make([]byte, 64)))
+	if _, b, err = NewRandomKey(); err != ErrZeroKey || b != nil {
+		t.Error("Zero source accepted", err)
+	}
+	SetEntropy(iotest.ErrReader(iotest.ErrTimeout))
+	if _, _, err = NewRandomKey(); err != iotest.ErrTimeout {
+		t.Error("Entropy error not returned", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package envelope
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/ohir/xxtea"
+)
+
+// AEAD adapter sizes.
+const (
+	AEADNonceSize = 10 // key-id, epoch and counter of the header
+	AEADMaxText   = MaxPayload - 1
+	AEADOverhead  = MinPayload + TagSizeV2 // empty plaintext, worst case
+)
+
+const aeadMisuse = "envelope: AEAD misuse: bad nonce size or plaintext over 207 bytes"
+
+const lblAD = "env-aead"
+
+type aead struct {
+	k xxtea.TeaKey
+}
+
+// NewAEAD returns a cipher.AEAD over Latest version frames under the key.
+//
+// It is a migration aid for code written against cipher.AEAD, not
+// a general purpose AEAD: the nonce is the key-id, epoch and counter of
+// the frame header (which is not sent), plaintext is padded with a 0x80
+// byte and zeros to XXTEA limits, and additional data is bound by deriving
+// the frame key from its SHA-256.  Seal panics on plaintexts longer than
+// AEADMaxText bytes; both Seal and Open panic on nonces of other than
+// AEADNonceSize bytes.  Nonces must never repeat under a key.
+func NewAEAD(k xxtea.TeaKey) cipher.AEAD {
+	return aead{k}
+}
+
+func (aead) NonceSize() int { return AEADNonceSize }
+func (aead) Overhead() int  { return AEADOverhead }
+
+// frame returns the key and header for the nonce and additional data.
+func (a aead) frame(nonce, ad []byte) (xxtea.TeaKey, Header) {
+	if len(nonce) != AEADNonceSize {
+		panic(aeadMisuse)
+	}
+	s := sha256.Sum256(ad)
+	h := Header{
+		Version: Latest,
+		KeyID:   binary.BigEndian.Uint16(nonce),
+		Epoch:   binary.BigEndian.Uint32(nonce[2:]),
+		Counter: binary.BigEndian.Uint32(nonce[6:]),
+	}
+	return a.k.DeriveLabel(lblAD, s[:]), h
+}
+
+func (a aead) Seal(dst, nonce, plaintext, ad []byte) []byte {
+	k, h := a.frame(nonce, ad)
+	if len(plaintext) > AEADMaxText {
+		panic(aeadMisuse)
+	}
+	n := (len(plaintext) + 4) &^ 3
+	if n < MinPayload {
+		n = MinPayload
+	}
+	p := make([]byte, n)
+	copy(p, plaintext)
+	p[len(plaintext)] = 0x80
+	frame, _ := Seal(k, h, p)
+	return append(dst, frame[HeaderSize:]...)
+}
+
+func (a aead) Open(dst, nonce, ciphertext, ad []byte) ([]byte, error) {
+	k, h := a.frame(nonce, ad)
+	frame := make([]byte, HeaderSize+len(ciphertext))
+	h.put(frame)
+	copy(frame[HeaderSize:], ciphertext)
+	_, p, err := Open(k, frame)
+	if err != nil {
+		return nil, err
+	}
+	i := len(p) - 1
+	for i > 0 && p[i] == 0 {
+		i--
+	}
+	if p[i] != 0x80 {
+		return nil, ErrOpenFailed
+	}
+	return append(dst, p[:i]...), nil
+}
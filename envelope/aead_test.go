@@ -0,0 +1,58 @@
+package envelope
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_AEAD(t *testing.T) {
+	a := NewAEAD(xxtea.NewKey([]byte(keyBEBE)))
+	nonce := []byte("\x00\x01\x00\x00\x00\x02\x00\x00\x00\x03")
+	ad := []byte("topic/cmd")
+	for _, n := range []int{0, 1, 11, 12, 100, AEADMaxText} {
+		pt := bytes.Repeat([]byte{0x80}, n) // pad byte lookalikes
+		ct := a.Seal([]byte("dst"), nonce, pt, ad)
+		if len(ct)-3 > n+a.Overhead() || string(ct[:3]) != "dst" {
+			t.Error("Bad ciphertext size", n, len(ct))
+		}
+		got, err := a.Open(nil, nonce, ct[3:], ad)
+		if err != nil || !bytes.Equal(got, pt) {
+			t.Error("Open failed", n, err)
+		}
+	}
+	ct := a.Seal(nil, nonce, []byte("hello"), ad)
+	if _, err := a.Open(nil, nonce, ct, []byte("topic/cfg")); err != ErrOpenFailed {
+		t.Error("Other additional data accepted")
+	}
+	other := append([]byte(nil), nonce...)
+	other[9]++
+	if _, err := a.Open(nil, other, ct, ad); err != ErrOpenFailed {
+		t.Error("Other nonce accepted")
+	}
+	if bytes.Equal(ct, a.Seal(nil, other, []byte("hello"), ad)) {
+		t.Error("Nonce not mixed in")
+	}
+	if _, err := a.Open(nil, nonce, ct[:len(ct)-1], ad); err == nil {
+		t.Error("Short ciphertext accepted")
+	}
+}
+
+func Test_AEAD_Panics(t *testing.T) {
+	a := NewAEAD(xxtea.NewKey([]byte(keyBEBE)))
+	for _, f := range []func(){
+		func() { a.Seal(nil, make([]byte, 12), nil, nil) },
+		func() { a.Seal(nil, make([]byte, 10), make([]byte, AEADMaxText+1), nil) },
+		func() { a.Open(nil, make([]byte, 9), nil, nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("AEAD misuse did not panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
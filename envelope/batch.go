@@ -0,0 +1,72 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package envelope
+
+import (
+	"sync"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/faults"
+)
+
+// Opened is a frame opened by OpenBatch.
+type Opened struct {
+	Header  Header
+	Payload []byte
+	Err     error // ErrOpenFailed if the frame did not open
+}
+
+// OpenBatch opens frames under the key k, as Open does each of them, in
+// two passes: MACs of all frames are checked first, then only frames that
+// authenticate are decrypted, by up to workers goroutines (in the calling
+// one for workers below 2).  A flood of forged frames costs a collector
+// one MAC a frame, never a decryption.  Results are in the order of
+// frames; failures are ErrOpenFailed, as by Open.
+func OpenBatch(k xxtea.TeaKey, frames [][]byte, workers int) []Opened {
+	res := make([]Opened, len(frames))
+	bodies := make([][]byte, len(frames))
+	mk := MACKey(k)
+	var good []int
+	for i, f := range frames {
+		f = faults.Frame(f)
+		h, err := Verify(mk, f)
+		if err != nil {
+			res[i].Err = ErrOpenFailed
+			continue
+		}
+		res[i].Header = h
+		bodies[i] = f[:len(f)-tagSize(h.Version)]
+		good = append(good, i)
+	}
+	open := func(i int) {
+		p, err := openBody(k, res[i].Header, bodies[i])
+		if err != nil {
+			res[i] = Opened{Header: res[i].Header, Err: ErrOpenFailed}
+			return
+		}
+		res[i].Payload = p
+	}
+	if workers < 2 || len(good) < 2 {
+		for _, i := range good {
+			open(i)
+		}
+		return res
+	}
+	if workers > len(good) {
+		workers = len(good)
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			for j := w; j < len(good); j += workers {
+				open(good[j])
+			}
+		}(w)
+	}
+	wg.Wait()
+	return res
+}
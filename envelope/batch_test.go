@@ -0,0 +1,35 @@
+package envelope
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_OpenBatch(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	var frames [][]byte
+	for i, fl := range []uint8{0, FlagLength, FlagSIV, FlagFixed, FlagLength | FlagSIV} {
+		f, err := Seal(key, Header{Flags: fl, Counter: uint32(i)}, []byte("payload of a frame, sealed in turn"[:12+4*i]))
+		if err != nil {
+			t.Fatal("Seal failed", err)
+		}
+		frames = append(frames, f)
+	}
+	forged := append([]byte(nil), frames[0]...)
+	forged[len(forged)-1] ^= 1
+	other, _ := Seal(xxtea.NewKey([]byte("FEDCBA9876543210")), Header{}, []byte(keyBEBE))
+	frames = append(frames, forged, other, frames[1][:5], nil)
+	for _, workers := range []int{0, 1, 3, 64} {
+		res := OpenBatch(key, frames, workers)
+		if len(res) != len(frames) {
+			t.Fatal("OpenBatch lost frames", workers)
+		}
+		for i, f := range frames {
+			h, p, err := Open(key, f)
+			if res[i].Err != err || string(res[i].Payload) != string(p) || err == nil && res[i].Header != h {
+				t.Error("OpenBatch differs from Open", workers, i, res[i].Err, err)
+			}
+		}
+	}
+}
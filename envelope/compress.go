@@ -0,0 +1,114 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package envelope
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+
+	"github.com/ohir/xxtea"
+)
+
+// MaxDecompressed is the most bytes OpenCompressed decompresses a payload
+// to; larger payloads fail, so a peer can not send decompression bombs.
+const MaxDecompressed = 16 << 10
+
+var (
+	ErrCodec      = errors.New("envelope: compression codec not supported")
+	ErrCompressed = errors.New("envelope: compressed payload does not fit")
+)
+
+// Compressor is a compression codec of frame payloads.  Device families
+// ship different decompressors (deflate, s2, custom LZ variants), so
+// codecs are told apart by an id carried in header flags: 1 to 3, zero is
+// an uncompressed payload.
+type Compressor interface {
+	ID() uint8
+	Compress(src []byte) ([]byte, error)
+	// Decompress returns src decompressed, or an error if it would be
+	// over limit bytes.
+	Decompress(src []byte, limit int) ([]byte, error)
+}
+
+// Codec returns the id of the compression codec of the payload, zero for
+// none.
+func (h *Header) Codec() uint8 {
+	return h.Flags & codecMask >> codecShift
+}
+
+// SealCompressed is SealPadded of the payload compressed with c, for
+// payloads that do not fit a frame as they are.  Payloads that do not
+// shrink are sealed uncompressed.  It returns ErrCompressed if the payload
+// does not fit a frame even compressed.
+func SealCompressed(k xxtea.TeaKey, h Header, payload []byte, c Compressor) ([]byte, error) {
+	if id := c.ID(); id == 0 || id > codecMask>>codecShift {
+		return nil, ErrCodec
+	}
+	h.Flags &^= codecMask
+	p, err := c.Compress(payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(p) < len(payload) {
+		h.Flags |= c.ID() << codecShift
+		payload = p
+	}
+	if len(payload) > MaxPayload-1 {
+		return nil, ErrCompressed
+	}
+	return SealPadded(k, h, payload)
+}
+
+// OpenCompressed opens a frame of SealCompressed and returns its payload
+// decompressed with the codec of the id in the header, one of cs.  It
+// returns ErrCodec if there is no such codec.  Failures to open are
+// reported as ErrOpenFailed, as by Open.
+func OpenCompressed(k xxtea.TeaKey, frame []byte, cs ...Compressor) ([]byte, error) {
+	h, err := ParseHeader(frame)
+	if err != nil {
+		return nil, ErrOpenFailed
+	}
+	p, err := OpenPadded(k, frame)
+	if err != nil || h.Codec() == 0 {
+		return p, err
+	}
+	for _, c := range cs {
+		if c.ID() == h.Codec() {
+			return c.Decompress(p, MaxDecompressed)
+		}
+	}
+	return nil, ErrCodec
+}
+
+// Flate is the raw DEFLATE (RFC 1951) codec of compress/flate, with id 1.
+var Flate Compressor = flateCodec{}
+
+type flateCodec struct{}
+
+func (flateCodec) ID() uint8 { return 1 }
+
+func (flateCodec) Compress(src []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w, _ := flate.NewWriter(&b, flate.BestCompression)
+	w.Write(src)
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (flateCodec) Decompress(src []byte, limit int) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(src))
+	p, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(p) > limit {
+		return nil, ErrCompressed
+	}
+	return p, nil
+}
@@ -0,0 +1,54 @@
+package envelope
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+type nopCodec uint8
+
+func (c nopCodec) ID() uint8                                  { return uint8(c) }
+func (nopCodec) Compress(src []byte) ([]byte, error)          { return src[:len(src)-1], nil }
+func (nopCodec) Decompress(src []byte, _ int) ([]byte, error) { return append(src, '!'), nil }
+
+func Test_Compressed(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	msg := bytes.Repeat([]byte(`{"temp":21.5,"hum":40}`), 40)
+	f, err := SealCompressed(key, Header{KeyID: 1}, msg, Flate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := ParseHeader(f); h.Codec() != 1 || h.KeyID != 1 {
+		t.Error("Codec id not in header", h)
+	}
+	if p, err := OpenCompressed(key, f, nopCodec(2), Flate); err != nil || !bytes.Equal(p, msg) {
+		t.Error("OpenCompressed failed", err)
+	}
+	if _, err := OpenCompressed(key, f, nopCodec(2)); err != ErrCodec {
+		t.Error("Unknown codec not refused", err)
+	}
+	f, _ = SealCompressed(key, Header{}, []byte("hi"), Flate)
+	if h, _ := ParseHeader(f); h.Codec() != 0 {
+		t.Error("Payload that did not shrink sent compressed")
+	}
+	if p, err := OpenCompressed(key, f); err != nil || string(p) != "hi" {
+		t.Error("Uncompressed payload not opened", err)
+	}
+	f, _ = SealCompressed(key, Header{Flags: FlagLength}, []byte("hello"), nopCodec(3))
+	if p, err := OpenCompressed(key, f, nopCodec(3)); err != nil || string(p) != "hell!" {
+		t.Error("Custom codec not used", err)
+	}
+	if _, err := SealCompressed(key, Header{}, msg, nopCodec(4)); err != ErrCodec {
+		t.Error("Codec id over 3 taken", err)
+	}
+	if _, err := SealCompressed(key, Header{}, make([]byte, 300), nopCodec(1)); err != ErrCompressed {
+		t.Error("Oversize payload taken", err)
+	}
+	bomb, _ := Flate.Compress(make([]byte, MaxDecompressed+1))
+	f, _ = SealPadded(key, Header{Flags: 1 << codecShift}, bomb)
+	if _, err := OpenCompressed(key, f, Flate); err != ErrCompressed {
+		t.Error("Decompression over limit", err)
+	}
+}
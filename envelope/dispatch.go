@@ -0,0 +1,74 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package envelope
+
+import (
+	"errors"
+	"strconv"
+)
+
+var ErrNoHandler = errors.New("envelope: no handler for frame type")
+
+// Handler handles the payload of an opened frame.
+type Handler interface {
+	Handle(h Header, payload []byte) error
+}
+
+// HandlerFunc adapts a function to the Handler interface.
+type HandlerFunc func(h Header, payload []byte) error
+
+// Handle implements Handler.
+func (f HandlerFunc) Handle(h Header, payload []byte) error {
+	return f(h, payload)
+}
+
+// Dispatcher routes opened frames to Handlers by their application type,
+// the Type field of FlagType frames.  Frames without FlagType are of type
+// zero.  Frames of types with no Handler go to Default, if set.
+//
+// Handlers are registered before frames are dispatched; Dispatch may then
+// be called from many goroutines.
+type Dispatcher struct {
+	Default Handler
+
+	handlers [256]Handler
+}
+
+// NewDispatcher returns an empty Dispatcher.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{}
+}
+
+// Handle registers the handler of frames of the type.  It panics if the
+// type has a handler already.
+func (d *Dispatcher) Handle(typ uint8, handler Handler) {
+	if d.handlers[typ] != nil {
+		panic("envelope: handler of type " + strconv.Itoa(int(typ)) + " registered twice")
+	}
+	d.handlers[typ] = handler
+}
+
+// HandleFunc registers the handler function of frames of the type.
+func (d *Dispatcher) HandleFunc(typ uint8, f func(h Header, payload []byte) error) {
+	d.Handle(typ, HandlerFunc(f))
+}
+
+// Dispatch passes the header and payload of an opened frame, as returned
+// by Open or Receiver.Open, to the handler of its type and returns what
+// the handler returned.  It returns ErrNoHandler if there is none.
+func (d *Dispatcher) Dispatch(h Header, payload []byte) error {
+	var typ uint8
+	if h.Flags&FlagType != 0 {
+		typ = h.Type
+	}
+	hd := d.handlers[typ]
+	if hd == nil {
+		hd = d.Default
+	}
+	if hd == nil {
+		return ErrNoHandler
+	}
+	return hd.Handle(h, payload)
+}
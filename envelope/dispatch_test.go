@@ -0,0 +1,56 @@
+package envelope
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_Dispatch(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	var got []uint8
+	d := NewDispatcher()
+	for _, typ := range []uint8{0, 3, 255} {
+		typ := typ
+		d.HandleFunc(typ, func(h Header, p []byte) error {
+			if string(p) != msgMin {
+				return errors.New("bad payload")
+			}
+			got = append(got, typ)
+			return nil
+		})
+	}
+	for _, h := range []Header{{}, {Flags: FlagType, Type: 3}, {Flags: FlagType | FlagTime | FlagSIV, Type: 255}, {Flags: FlagType}} {
+		f, err := Seal(key, h, []byte(msgMin))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, _ := OverheadFor(len(msgMin), Options{Flags: h.Flags}); n != len(f) {
+			t.Error("OverheadFor of typed frame failed", n, len(f))
+		}
+		oh, p, err := Open(key, f)
+		if err != nil || oh.Type != h.Type {
+			t.Fatal("Typed frame not opened", err)
+		}
+		if err := d.Dispatch(oh, p); err != nil {
+			t.Error("Dispatch failed", err)
+		}
+	}
+	if string(got) != "\x00\x03\xff\x00" {
+		t.Error("Frames routed to wrong handlers", got)
+	}
+	if err := d.Dispatch(Header{Flags: FlagType, Type: 1}, nil); err != ErrNoHandler {
+		t.Error("Unhandled type dispatched", err)
+	}
+	d.Default = HandlerFunc(func(Header, []byte) error { return nil })
+	if err := d.Dispatch(Header{Flags: FlagType, Type: 1}, nil); err != nil {
+		t.Error("Default handler not used", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Second handler of a type registered")
+		}
+	}()
+	d.HandleFunc(3, nil)
+}
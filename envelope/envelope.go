@@ -0,0 +1,413 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package envelope defines the authenticated frame format shared by
+// broadcasts, group commands and point to point messages.
+//
+// Frame layout:
+//
+//	 0        1        2..3     4..7     8..11      12..
+//	version | flags  | key-id | epoch  | counter | [ext] | ciphertext | tag
+//
+// Header fields are big-endian.  Optional extension fields follow the fixed
+// header in the order of their flag bits:
+//
+//	FlagTime    time (4B), coarse Unix seconds
+//	FlagLength  plaintext length (2B)
+//	FlagSIV     synthetic IV (8B)
+//	FlagType    application message type (1B), see Dispatcher
+//
+// Ciphertext is the payload encrypted with XXTEA under a per-frame key
+// derived from the header, so equal payloads never give equal ciphertexts
+// as long as counters do not repeat.  Tag is a truncated HMAC-SHA256 over
+// header and ciphertext (encrypt-then-MAC), 8 bytes long in version 1
+// frames and 16 bytes long in version 2 frames.
+// Encryption and MAC keys are both derived from the frame key given by the
+// caller, which is selected by key-id and epoch.
+//
+// Payload must satisfy XXTEA limits: 12..208 bytes, in multiples of four.
+// With FlagLength, as several vendor C libraries do, payload of any length
+// up to 208 bytes is zero padded to these limits and its length is carried
+// in the header.
+//
+// With FlagFixed every payload is zero padded to 208 bytes, and its length
+// is encrypted in the last two payload bytes, so all frames sent with the
+// same flags are the same size and frame size does not leak what is sent.
+// FlagFixed and FlagLength exclude each other.
+//
+// FlagSIV makes sealing deterministic and nonce-misuse resistant, for
+// devices that can keep neither counters nor a random source: a synthetic
+// IV, a PRF over the header (the associated data) and the padded payload,
+// is carried in the header and so mixed into the frame key.  Equal payloads
+// under equal headers give equal frames, but anything else, even with
+// a repeated counter, looks unrelated.  Open checks the IV after decrypting.
+package envelope
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/faults"
+)
+
+// Frame format versions.
+const (
+	Version1 = 1 // 64-bit tag
+	Version2 = 2 // 128-bit tag
+	Latest   = Version2
+)
+
+// Header flags.
+const (
+	FlagTime   = 1 << iota // header carries a timestamp
+	FlagLength             // header carries the plaintext length
+	FlagFixed              // payload padded to MaxPayload, length encrypted
+	FlagSIV                // header carries a synthetic IV
+	FlagType               // header carries an application message type
+	flagsKnown = FlagTime | FlagLength | FlagFixed | FlagSIV | FlagType | codecMask
+)
+
+// Bits 5 and 6 of header flags are the id of the compression codec of the
+// payload, see SealCompressed.
+const (
+	codecShift = 5
+	codecMask  = 3 << codecShift
+)
+
+// Sizes of frame parts.
+const (
+	HeaderSize = 12 // fixed part
+	TagSizeV1  = 8
+	TagSizeV2  = 16
+	MinPayload = 12
+	MaxPayload = 208
+	MaxFixed   = MaxPayload - 2 // FlagFixed plaintext
+	SIVSize    = 8
+)
+
+var (
+	ErrLength  = errors.New("envelope: payload length out of XXTEA limits")
+	ErrFrame   = errors.New("envelope: malformed frame")
+	ErrVersion = errors.New("envelope: unknown frame version")
+	ErrFlags   = errors.New("envelope: unknown header flags")
+	ErrMAC     = errors.New("envelope: frame does not authenticate")
+
+	ErrOpenFailed = errors.New("envelope: frame can not be opened")
+)
+
+var (
+	lblEnc = []byte("env-enc")
+	lblMAC = []byte("env-mac")
+	lblSIV = []byte("env-siv")
+)
+
+// Header is the clear, authenticated part of a frame.
+type Header struct {
+	Version uint8
+	Flags   uint8
+	KeyID   uint16
+	Epoch   uint32
+	Counter uint32
+	Time    uint32        // with FlagTime only
+	Length  uint16        // with FlagLength only
+	SIV     [SIVSize]byte // with FlagSIV only
+	Type    uint8         // with FlagType only
+}
+
+// Len returns the size of the header in a frame, with extension fields.
+func (h *Header) Len() int {
+	return h.size()
+}
+
+// size returns the header size, with extension fields.
+func (h *Header) size() int {
+	n := HeaderSize
+	if h.Flags&FlagTime != 0 {
+		n += 4
+	}
+	if h.Flags&FlagLength != 0 {
+		n += 2
+	}
+	if h.Flags&FlagSIV != 0 {
+		n += SIVSize
+	}
+	if h.Flags&FlagType != 0 {
+		n++
+	}
+	return n
+}
+
+func (h *Header) put(b []byte) {
+	b[0], b[1] = h.Version, h.Flags
+	binary.BigEndian.PutUint16(b[2:], h.KeyID)
+	binary.BigEndian.PutUint32(b[4:], h.Epoch)
+	binary.BigEndian.PutUint32(b[8:], h.Counter)
+	b = b[HeaderSize:]
+	if h.Flags&FlagTime != 0 {
+		binary.BigEndian.PutUint32(b, h.Time)
+		b = b[4:]
+	}
+	if h.Flags&FlagLength != 0 {
+		binary.BigEndian.PutUint16(b, h.Length)
+		b = b[2:]
+	}
+	if h.Flags&FlagSIV != 0 {
+		copy(b, h.SIV[:])
+		b = b[SIVSize:]
+	}
+	if h.Flags&FlagType != 0 {
+		b[0] = h.Type
+	}
+}
+
+// padded returns the XXTEA payload size for n bytes of plaintext.
+func padded(n int) int {
+	n = (n + 3) &^ 3
+	if n < MinPayload {
+		n = MinPayload
+	}
+	return n
+}
+
+// tagSize returns tag size of the frame version, or 0 for unknown versions.
+func tagSize(v uint8) int {
+	switch v {
+	case Version1:
+		return TagSizeV1
+	case Version2:
+		return TagSizeV2
+	}
+	return 0
+}
+
+// ParseHeader returns the header of a frame without authenticating it.
+func ParseHeader(frame []byte) (h Header, err error) {
+	if len(frame) < HeaderSize {
+		return h, ErrFrame
+	}
+	h.Version, h.Flags = frame[0], frame[1]
+	h.KeyID = binary.BigEndian.Uint16(frame[2:])
+	h.Epoch = binary.BigEndian.Uint32(frame[4:])
+	h.Counter = binary.BigEndian.Uint32(frame[8:])
+	ts := tagSize(h.Version)
+	if ts == 0 {
+		return h, ErrVersion
+	}
+	if h.Flags&^flagsKnown != 0 || h.Flags&(FlagLength|FlagFixed) == FlagLength|FlagFixed {
+		return h, ErrFlags
+	}
+	hs := h.size()
+	if len(frame) < hs {
+		return h, ErrFrame
+	}
+	ext := frame[HeaderSize:]
+	if h.Flags&FlagTime != 0 {
+		h.Time = binary.BigEndian.Uint32(ext)
+		ext = ext[4:]
+	}
+	if h.Flags&FlagLength != 0 {
+		h.Length = binary.BigEndian.Uint16(ext)
+		ext = ext[2:]
+	}
+	if h.Flags&FlagSIV != 0 {
+		copy(h.SIV[:], ext)
+		ext = ext[SIVSize:]
+	}
+	if h.Flags&FlagType != 0 {
+		h.Type = ext[0]
+	}
+	n := len(frame) - hs - ts
+	if n < MinPayload || n > MaxPayload || n&3 != 0 {
+		return h, ErrFrame
+	}
+	if h.Flags&FlagLength != 0 && padded(int(h.Length)) != n || h.Flags&FlagFixed != 0 && n != MaxPayload {
+		return h, ErrFrame
+	}
+	return h, nil
+}
+
+// PlaintextLen returns the payload length of a frame from its header (or
+// size, without FlagLength) without authenticating or decrypting it.  For
+// FlagFixed frames, where the length is encrypted, it returns MaxFixed.  Receivers may use it to
+// pre-allocate buffers or to drop oversize frames early; the frame still
+// has to pass Open.
+func PlaintextLen(frame []byte) (int, error) {
+	h, err := ParseHeader(frame)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case h.Flags&FlagLength != 0:
+		return int(h.Length), nil
+	case h.Flags&FlagFixed != 0:
+		return MaxFixed, nil
+	}
+	return len(frame) - h.size() - tagSize(h.Version), nil
+}
+
+// frameKey returns the per-frame encryption key for the header bytes.
+func frameKey(k xxtea.TeaKey, hdr []byte) xxtea.TeaKey {
+	return k.Derive(lblEnc).Derive(hdr)
+}
+
+// tag returns the MAC of header and ciphertext of the frame.
+func tag(k xxtea.TeaKey, frame []byte) []byte {
+	return macTag(MACKey(k), frame)
+}
+
+// macTag is tag under the MAC subkey.
+func macTag(mk xxtea.TeaKey, frame []byte) []byte {
+	m := hmac.New(sha256.New, mk.Bytes())
+	m.Write(frame)
+	return m.Sum(nil)[:tagSize(frame[0])]
+}
+
+// MACKey returns the subkey of k that authenticates frames.  It verifies
+// frames (see Verify and Relay) but can not decrypt them, so it can be
+// given to gateways trusted to filter forgeries, not with plaintext.  Its
+// holder could tag frames of garbage payload, as such gateways could drop
+// or corrupt frames anyway.
+func MACKey(k xxtea.TeaKey) xxtea.TeaKey {
+	return k.Derive(lblMAC)
+}
+
+// siv returns the synthetic IV of the header with zero SIV field and the
+// padded payload.
+func siv(k xxtea.TeaKey, h Header, payload []byte) (iv [SIVSize]byte) {
+	h.SIV = iv
+	hdr := make([]byte, h.size())
+	h.put(hdr)
+	m := hmac.New(sha256.New, k.Derive(lblSIV).Bytes())
+	m.Write(hdr)
+	m.Write(payload)
+	copy(iv[:], m.Sum(nil))
+	return iv
+}
+
+// Seal returns payload encrypted and authenticated under the key k, framed
+// with the header h.  Zero h.Version means the Latest version.  Zero h.Time
+// with FlagTime set means the current time.  With FlagLength h.Length is
+// set from the payload.  With FlagFixed payload can be up to MaxFixed bytes.
+// With FlagSIV h.SIV is computed.
+func Seal(k xxtea.TeaKey, h Header, payload []byte) ([]byte, error) {
+	n := len(payload)
+	switch {
+	case h.Flags&FlagLength != 0 && n <= MaxPayload:
+		h.Length = uint16(n)
+		n = padded(n)
+	case h.Flags&FlagFixed != 0:
+		if n > MaxFixed {
+			return nil, ErrLength
+		}
+		n = MaxPayload
+	}
+	if n < MinPayload || n > MaxPayload || n&3 != 0 {
+		return nil, ErrLength
+	}
+	if h.Version == 0 {
+		h.Version = Latest
+	}
+	ts := tagSize(h.Version)
+	if ts == 0 {
+		return nil, ErrVersion
+	}
+	if h.Flags&^flagsKnown != 0 || h.Flags&(FlagLength|FlagFixed) == FlagLength|FlagFixed {
+		return nil, ErrFlags
+	}
+	if h.Flags&FlagTime != 0 && h.Time == 0 {
+		h.Time = uint32(xxtea.Now().Unix())
+	}
+	if len(payload) != n {
+		p := make([]byte, n)
+		copy(p, payload)
+		if h.Flags&FlagFixed != 0 {
+			binary.BigEndian.PutUint16(p[MaxFixed:], uint16(len(payload)))
+		}
+		payload = p
+	}
+	if h.Flags&FlagSIV != 0 {
+		h.SIV = siv(k, h, payload)
+	}
+	hs := h.size()
+	frame := make([]byte, hs+n, hs+n+ts)
+	h.put(frame)
+	frameKey(k, frame[:hs]).Encrypt(payload, frame[hs:])
+	return append(frame, tag(k, frame)...), nil
+}
+
+// Open authenticates the frame under the key k then returns its header and
+// decrypted payload, with padding removed for FlagLength and FlagFixed
+// frames.
+//
+// Every failure, be it a malformed frame, an unknown version, a bad MAC or
+// a bad length or padding inside, is reported as ErrOpenFailed, so
+// services answering peers can not leak which check failed.  OpenDetailed
+// tells them apart, for diagnostics.
+func Open(k xxtea.TeaKey, frame []byte) (Header, []byte, error) {
+	h, p, err := OpenDetailed(k, frame)
+	if err != nil {
+		return h, nil, ErrOpenFailed
+	}
+	return h, p, nil
+}
+
+// OpenDetailed is Open returning the specific error of a failure:
+// ErrFrame, ErrVersion, ErrFlags or ErrMAC.  In xxtea.Strict mode padding
+// of FlagLength payloads must be zeros, as it is for FlagFixed ones.
+func OpenDetailed(k xxtea.TeaKey, frame []byte) (Header, []byte, error) {
+	frame = faults.Frame(frame)
+	h, err := ParseHeader(frame)
+	if err != nil {
+		return h, nil, err
+	}
+	body := frame[:len(frame)-tagSize(h.Version)]
+	if !hmac.Equal(tag(k, body), frame[len(body):]) {
+		return h, nil, ErrMAC
+	}
+	p, err := openBody(k, h, body)
+	return h, p, err
+}
+
+// openBody decrypts the body of an authenticated frame of header h and
+// returns its payload, with padding removed.
+func openBody(k xxtea.TeaKey, h Header, body []byte) ([]byte, error) {
+	hs := h.size()
+	payload := make([]byte, len(body)-hs)
+	frameKey(k, body[:hs]).Decrypt(body[hs:], payload)
+	if h.Flags&FlagSIV != 0 {
+		if iv := siv(k, h, payload); !hmac.Equal(iv[:], h.SIV[:]) {
+			return nil, ErrMAC
+		}
+	}
+	switch {
+	case h.Flags&FlagLength != 0:
+		if xxtea.Strict() && !zeros(payload[h.Length:]) {
+			return nil, ErrFrame
+		}
+		payload = payload[:h.Length]
+	case h.Flags&FlagFixed != 0:
+		n := int(binary.BigEndian.Uint16(payload[MaxFixed:]))
+		if n > MaxFixed {
+			return nil, ErrFrame
+		}
+		if !zeros(payload[n:MaxFixed]) {
+			return nil, ErrFrame
+		}
+		payload = payload[:n]
+	}
+	return payload, nil
+}
+
+// zeros reports whether all bytes of b are zero.
+func zeros(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,270 @@
+package envelope
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+const (
+	keyBEBE = "0123456789ABCDEF"
+	keyLELE = "FEDCBA9876543210"
+	msgMin  = `AbCdEFgHiJkL`
+	msg16   = `Sixteen bytes!!!`
+)
+
+func Test_SealOpen(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	h := Header{KeyID: 0x1234, Epoch: 5, Counter: 9}
+	frame, err := Seal(key, h, []byte(msg16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frame) != HeaderSize+len(msg16)+TagSizeV2 {
+		t.Error("Frame size is wrong")
+	}
+	if bytes.Contains(frame, []byte(msg16)[:8]) {
+		t.Error("Payload leaked in clear")
+	}
+	g, p, err := OpenDetailed(key, frame)
+	h.Version = Latest
+	if err != nil || g != h || string(p) != msg16 {
+		t.Error("Open failed", err, g)
+	}
+}
+
+func Test_Version1(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	frame, err := Seal(key, Header{Version: Version1}, []byte(msgMin))
+	if err != nil || len(frame) != HeaderSize+len(msgMin)+TagSizeV1 {
+		t.Fatal("Version 1 frame not sealed", err)
+	}
+	h, p, err := OpenDetailed(key, frame)
+	if err != nil || h.Version != Version1 || string(p) != msgMin {
+		t.Error("Version 1 frame not opened", err)
+	}
+	frame[0] = Version2 // v1 frame read as v2 is too short
+	if _, _, err := OpenDetailed(key, frame); err != ErrFrame {
+		t.Error("Version 1 frame opened as version 2", err)
+	}
+}
+
+func Test_Time(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	h := Header{Flags: FlagTime, Time: 1700000000, Counter: 1}
+	frame, err := Seal(key, h, []byte(msgMin))
+	if err != nil || len(frame) != HeaderSize+4+len(msgMin)+TagSizeV2 {
+		t.Fatal("Timestamped frame not sealed", err)
+	}
+	g, p, err := OpenDetailed(key, frame)
+	if err != nil || g.Time != h.Time || string(p) != msgMin {
+		t.Error("Timestamped frame not opened", err)
+	}
+	frame[15] ^= 1
+	if _, _, err = OpenDetailed(key, frame); err != ErrMAC {
+		t.Error("Altered time accepted")
+	}
+	frame, _ = Seal(key, Header{Flags: FlagTime}, []byte(msgMin))
+	if g, _, _ = OpenDetailed(key, frame); g.Time == 0 {
+		t.Error("Zero time not stamped")
+	}
+	if _, err = Seal(key, Header{Flags: 0x80}, []byte(msgMin)); err != ErrFlags {
+		t.Error("Unknown flags sealed")
+	}
+	frame[1] |= 0x80
+	if _, _, err = OpenDetailed(key, frame); err != ErrFlags {
+		t.Error("Unknown flags accepted")
+	}
+}
+
+func Test_Nonce(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	f1, _ := Seal(key, Header{Counter: 1}, []byte(msgMin))
+	f2, _ := Seal(key, Header{Counter: 2}, []byte(msgMin))
+	if bytes.Equal(f1[HeaderSize:HeaderSize+4], f2[HeaderSize:HeaderSize+4]) {
+		t.Error("Equal payloads under other counters gave equal ciphertext")
+	}
+}
+
+func Test_Forgery(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	frame, _ := Seal(key, Header{Counter: 1}, []byte(msg16))
+	for _, i := range []int{1, 11, HeaderSize, len(frame) - 1} {
+		f := append([]byte(nil), frame...)
+		f[i] ^= 1
+		if _, _, err := OpenDetailed(key, f); err != ErrMAC {
+			t.Error("Altered frame accepted, byte", i)
+		}
+	}
+	if _, _, err := OpenDetailed(xxtea.NewKey([]byte(keyLELE)), frame); err != ErrMAC {
+		t.Error("Frame opened with other key")
+	}
+}
+
+func Test_Malformed(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	if _, err := Seal(key, Header{}, make([]byte, 8)); err != ErrLength {
+		t.Error("Short payload sealed")
+	}
+	if _, err := Seal(key, Header{}, make([]byte, 13)); err != ErrLength {
+		t.Error("Unaligned payload sealed")
+	}
+	if _, err := Seal(key, Header{Version: 99}, make([]byte, 12)); err != ErrVersion {
+		t.Error("Unknown version sealed")
+	}
+	frame, _ := Seal(key, Header{}, []byte(msg16))
+	if _, _, err := OpenDetailed(key, frame[:HeaderSize+TagSizeV2+8]); err != ErrFrame {
+		t.Error("Short frame accepted")
+	}
+	if _, _, err := OpenDetailed(key, frame[:len(frame)-1]); err != ErrFrame {
+		t.Error("Unaligned frame accepted")
+	}
+	frame[0] = 99
+	if _, _, err := OpenDetailed(key, frame); err != ErrVersion {
+		t.Error("Unknown version accepted")
+	}
+}
+
+func Test_Uniform(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	frame, _ := Seal(key, Header{Flags: FlagFixed}, []byte(msg16))
+	if _, p, err := Open(key, frame); err != nil || string(p) != msg16 {
+		t.Fatal("Open failed", err)
+	}
+	bad := map[string][]byte{"short": frame[:HeaderSize]}
+	for _, i := range []int{0, 1, HeaderSize, len(frame) - 1} {
+		f := append([]byte(nil), frame...)
+		f[i] ^= 0x40
+		bad[string(rune('a'+i%26))] = f
+	}
+	// authentic frame of a bad fixed length
+	f, _ := Seal(key, Header{}, make([]byte, MaxPayload))
+	f[1] = FlagFixed
+	bad["length"] = append(f[:len(f)-TagSizeV2], tag(key, f[:len(f)-TagSizeV2])...)
+	for name, f := range bad {
+		if _, _, err := Open(key, f); err != ErrOpenFailed {
+			t.Error("Failure not uniform:", name, err)
+		}
+		if _, _, err := OpenDetailed(key, f); err == nil || err == ErrOpenFailed {
+			t.Error("Failure not detailed:", name, err)
+		}
+	}
+}
+
+func Test_PlaintextLen(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	for _, h := range []Header{{}, {Version: Version1}, {Flags: FlagTime}} {
+		frame, _ := Seal(key, h, []byte(msg16))
+		if n, err := PlaintextLen(frame); err != nil || n != len(msg16) {
+			t.Error("PlaintextLen failed", h, n, err)
+		}
+	}
+	frame, _ := Seal(key, Header{}, []byte(msg16))
+	if _, err := PlaintextLen(frame[:len(frame)-2]); err != ErrFrame {
+		t.Error("PlaintextLen of unaligned frame", err)
+	}
+}
+
+func Test_Length(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	for _, n := range []int{0, 1, 12, 13, 207, 208} {
+		pt := bytes.Repeat([]byte{7}, n)
+		for _, fl := range []uint8{FlagLength, FlagLength | FlagTime} {
+			h := Header{Flags: fl, Time: 99}
+			frame, err := Seal(key, h, pt)
+			if err != nil || len(frame) != h.size()+padded(n)+TagSizeV2 {
+				t.Fatal("Length frame not sealed", n, err)
+			}
+			if l, err := PlaintextLen(frame); err != nil || l != n {
+				t.Error("PlaintextLen failed", n, l, err)
+			}
+			g, p, err := OpenDetailed(key, frame)
+			if err != nil || g.Length != uint16(n) || g.Time != h.Time*uint32(fl&FlagTime) || !bytes.Equal(p, pt) {
+				t.Error("Length frame not opened", n, err)
+			}
+		}
+	}
+	if _, err := Seal(key, Header{Flags: FlagLength}, make([]byte, 209)); err != ErrLength {
+		t.Error("Long payload sealed")
+	}
+	frame, _ := Seal(key, Header{Flags: FlagLength}, []byte("hi"))
+	frame[HeaderSize+1] = 13 // claims more than one word of padding
+	if _, err := PlaintextLen(frame); err != ErrFrame {
+		t.Error("Inconsistent length accepted", err)
+	}
+}
+
+func Test_Fixed(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	size := 0
+	for _, n := range []int{0, 1, 12, 100, MaxFixed} {
+		pt := bytes.Repeat([]byte{7}, n)
+		frame, err := Seal(key, Header{Flags: FlagFixed}, pt)
+		if err != nil {
+			t.Fatal("Fixed frame not sealed", n, err)
+		}
+		if size == 0 {
+			size = len(frame)
+		}
+		if len(frame) != size || size != HeaderSize+MaxPayload+TagSizeV2 {
+			t.Error("Fixed frame size varies", n, len(frame))
+		}
+		if l, err := PlaintextLen(frame); err != nil || l != MaxFixed {
+			t.Error("PlaintextLen of fixed frame", l, err)
+		}
+		if _, p, err := OpenDetailed(key, frame); err != nil || !bytes.Equal(p, pt) {
+			t.Error("Fixed frame not opened", n, err)
+		}
+	}
+	for _, n := range []int{MaxFixed + 1, MaxPayload} {
+		if _, err := Seal(key, Header{Flags: FlagFixed}, make([]byte, n)); err != ErrLength {
+			t.Error("Fixed payload over limit sealed", n)
+		}
+	}
+	if _, err := Seal(key, Header{Flags: FlagFixed | FlagLength}, nil); err != ErrFlags {
+		t.Error("Fixed frame with clear length sealed")
+	}
+	// a regular 208-byte frame relabelled as fixed and re-tagged
+	frame, _ := Seal(key, Header{}, make([]byte, MaxPayload))
+	frame[1] = FlagFixed
+	frame = append(frame[:len(frame)-TagSizeV2], tag(key, frame[:len(frame)-TagSizeV2])...)
+	if _, _, err := OpenDetailed(key, frame); err != ErrFrame {
+		t.Error("Bad fixed length accepted", err)
+	}
+}
+
+func Test_SIV(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	h := Header{Flags: FlagSIV, KeyID: 3}
+	a, err := Seal(key, h, []byte(msg16))
+	if err != nil || len(a) != HeaderSize+SIVSize+len(msg16)+TagSizeV2 {
+		t.Fatal("SIV frame not sealed", err)
+	}
+	if b, _ := Seal(key, h, []byte(msg16)); !bytes.Equal(a, b) {
+		t.Error("SIV sealing not deterministic")
+	}
+	other := []byte(msg16)
+	other[15] ^= 1
+	b, _ := Seal(key, h, other)
+	if bytes.Equal(a[HeaderSize:HeaderSize+SIVSize], b[HeaderSize:HeaderSize+SIVSize]) ||
+		bytes.Equal(a[HeaderSize+SIVSize:HeaderSize+SIVSize+4], b[HeaderSize+SIVSize:HeaderSize+SIVSize+4]) {
+		t.Error("Payload change did not change SIV and ciphertext")
+	}
+	g, p, err := OpenDetailed(key, a)
+	if err != nil || string(p) != msg16 || g.SIV == [SIVSize]byte{} {
+		t.Error("SIV frame not opened", err)
+	}
+	for _, fl := range []uint8{FlagSIV | FlagTime | FlagLength, FlagSIV | FlagFixed} {
+		f, err := Seal(key, Header{Flags: fl, Time: 7}, []byte("odd size"))
+		if _, p, err2 := OpenDetailed(key, f); err != nil || err2 != nil || string(p) != "odd size" {
+			t.Error("SIV with other flags failed", fl, err, err2)
+		}
+	}
+	// a forged SIV re-tagged under the right key fails the IV check
+	a[HeaderSize] ^= 1
+	a = append(a[:len(a)-TagSizeV2], tag(key, a[:len(a)-TagSizeV2])...)
+	if _, _, err := OpenDetailed(key, a); err != ErrMAC {
+		t.Error("Forged SIV accepted", err)
+	}
+}
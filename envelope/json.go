@@ -0,0 +1,104 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/ohir/xxtea"
+)
+
+// Frame is a sealed frame that marshals to JSON, for transports carrying
+// JSON only (webhooks, brokers of JSON messages).  The mapping is
+// canonical: a frame has exactly one JSON form, fields in this order, with
+// byte fields in standard padded base64:
+//
+//	{"v":2,"fl":1,"kid":7,"ep":1,"ctr":9,"time":1700000000,"len":5,"siv":"...","type":3,"ct":"...","tag":"..."}
+//
+// "fl" and "ep" are left out when zero, "time", "len", "siv" and "type" are given
+// if and only if their flag is set.
+type Frame []byte
+
+type frameJSON struct {
+	V    uint8   `json:"v"`
+	Fl   uint8   `json:"fl,omitempty"`
+	KID  uint16  `json:"kid"`
+	Ep   uint32  `json:"ep,omitempty"`
+	Ctr  uint32  `json:"ctr"`
+	Time *uint32 `json:"time,omitempty"`
+	Len  *uint16 `json:"len,omitempty"`
+	SIV  []byte  `json:"siv,omitempty"`
+	Type *uint8  `json:"type,omitempty"`
+	CT   []byte  `json:"ct"`
+	Tag  []byte  `json:"tag"`
+}
+
+// MarshalJSON implements json.Marshaler.  Malformed frames give an error.
+func (f Frame) MarshalJSON() ([]byte, error) {
+	h, err := ParseHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	hs, ts := h.size(), tagSize(h.Version)
+	j := frameJSON{V: h.Version, Fl: h.Flags, KID: h.KeyID, Ep: h.Epoch, Ctr: h.Counter,
+		CT: f[hs : len(f)-ts], Tag: f[len(f)-ts:]}
+	if h.Flags&FlagTime != 0 {
+		j.Time = &h.Time
+	}
+	if h.Flags&FlagLength != 0 {
+		j.Len = &h.Length
+	}
+	if h.Flags&FlagSIV != 0 {
+		j.SIV = h.SIV[:]
+	}
+	if h.Flags&FlagType != 0 {
+		j.Type = &h.Type
+	}
+	return json.Marshal(&j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.  It returns ErrFrame for
+// JSON not in the canonical mapping of a well-formed frame.  In
+// xxtea.Strict mode the JSON must also be byte for byte what MarshalJSON
+// gives, with no white space and no other base64 of the same bytes.
+func (f *Frame) UnmarshalJSON(b []byte) error {
+	var j frameJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	h := Header{Version: j.V, Flags: j.Fl, KeyID: j.KID, Epoch: j.Ep, Counter: j.Ctr}
+	if (j.Time != nil) != (h.Flags&FlagTime != 0) ||
+		(j.Len != nil) != (h.Flags&FlagLength != 0) ||
+		(j.SIV != nil) != (h.Flags&FlagSIV != 0) ||
+		(j.Type != nil) != (h.Flags&FlagType != 0) ||
+		j.SIV != nil && len(j.SIV) != SIVSize ||
+		len(j.Tag) != tagSize(h.Version) {
+		return ErrFrame
+	}
+	if j.Time != nil {
+		h.Time = *j.Time
+	}
+	if j.Len != nil {
+		h.Length = *j.Len
+	}
+	copy(h.SIV[:], j.SIV)
+	if j.Type != nil {
+		h.Type = *j.Type
+	}
+	fr := make([]byte, h.size(), h.size()+len(j.CT)+len(j.Tag))
+	h.put(fr)
+	fr = append(append(fr, j.CT...), j.Tag...)
+	if _, err := ParseHeader(fr); err != nil {
+		return err
+	}
+	if xxtea.Strict() {
+		if c, err := Frame(fr).MarshalJSON(); err != nil || !bytes.Equal(c, b) {
+			return ErrFrame
+		}
+	}
+	*f = fr
+	return nil
+}
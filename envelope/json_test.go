@@ -0,0 +1,55 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_JSON(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	for _, h := range []Header{
+		{KeyID: 7, Counter: 9},
+		{Version: Version1, Epoch: 3},
+		{Flags: FlagTime | FlagLength | FlagSIV, Time: 1700000000, KeyID: 1},
+		{Flags: FlagType | FlagSIV, Type: 5, KeyID: 2},
+	} {
+		f, _ := Seal(key, h, []byte("hello"+msgMin[:7]))
+		b, err := json.Marshal(Frame(f))
+		if err != nil {
+			t.Fatal("Marshal failed", err)
+		}
+		var g Frame
+		if err := json.Unmarshal(b, &g); err != nil || !bytes.Equal(g, f) {
+			t.Error("Unmarshal failed", string(b), err)
+		}
+		if b2, _ := json.Marshal(g); !bytes.Equal(b, b2) {
+			t.Error("Mapping not canonical", string(b), string(b2))
+		}
+		if _, _, err := Open(key, g); err != nil {
+			t.Error("Unmarshalled frame does not open", err)
+		}
+	}
+	f, _ := Seal(key, Header{KeyID: 7, Counter: 9}, []byte(msgMin))
+	b, _ := json.Marshal(Frame(f))
+	if !strings.HasPrefix(string(b), `{"v":2,"kid":7,"ctr":9,"ct":"`) {
+		t.Error("Unexpected JSON", string(b))
+	}
+	for _, bad := range []string{
+		`{"v":2,"kid":7,"ctr":9,"time":5,"ct":"AAAAAAAAAAAAAAAA","tag":"AAAAAAAAAAAAAAAAAAAAAA=="}`,
+		`{"v":2,"fl":1,"kid":7,"ctr":9,"ct":"AAAAAAAAAAAAAAAA","tag":"AAAAAAAAAAAAAAAAAAAAAA=="}`,
+		`{"v":2,"kid":7,"ctr":9,"ct":"AAAAAAAAAAAAAAAA","tag":"AAAA"}`,
+		`{"v":2,"kid":7,"ctr":9,"ct":"AAAA","tag":"AAAAAAAAAAAAAAAAAAAAAA=="}`,
+	} {
+		var g Frame
+		if err := json.Unmarshal([]byte(bad), &g); err == nil {
+			t.Error("Bad JSON frame accepted", bad)
+		}
+	}
+	if _, err := json.Marshal(Frame(f[:5])); err == nil {
+		t.Error("Malformed frame marshalled")
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package envelope
+
+import (
+	"errors"
+
+	"github.com/ohir/xxtea"
+)
+
+var ErrPadding = errors.New("envelope: bad payload padding")
+
+// SealPadded is Seal for payloads of any length up to MaxPayload-1: frames
+// without FlagLength or FlagFixed get the payload padded with 0x80 and
+// zero bytes to a legal XXTEA length, as xxtea.PadISO does.
+func SealPadded(k xxtea.TeaKey, h Header, payload []byte) ([]byte, error) {
+	if h.Flags&(FlagLength|FlagFixed) != 0 {
+		return Seal(k, h, payload)
+	}
+	if len(payload) > MaxPayload-1 {
+		return nil, ErrLength
+	}
+	p := make([]byte, padded(len(payload)+1))
+	copy(p, payload)
+	p[len(payload)] = 0x80
+	return Seal(k, h, p)
+}
+
+// OpenPadded opens a frame of SealPadded and returns its payload.  It does
+// the whole sequence in the right order: version and header checks, the
+// MAC in constant time, decryption, then the declared length of FlagLength
+// and FlagFixed frames or the padding of others.  Any failure, bad padding
+// included, is reported as ErrOpenFailed, as by Open.
+func OpenPadded(k xxtea.TeaKey, frame []byte) ([]byte, error) {
+	p, err := OpenPaddedDetailed(k, frame)
+	if err != nil {
+		return nil, ErrOpenFailed
+	}
+	return p, nil
+}
+
+// OpenPaddedDetailed is OpenPadded returning the errors of OpenDetailed,
+// and ErrPadding for padding that is not 0x80 and zeros up to the nearest
+// legal length.
+func OpenPaddedDetailed(k xxtea.TeaKey, frame []byte) ([]byte, error) {
+	h, p, err := OpenDetailed(k, frame)
+	if err != nil || h.Flags&(FlagLength|FlagFixed) != 0 {
+		return p, err
+	}
+	i := len(p) - 1
+	for i >= 0 && p[i] == 0 {
+		i--
+	}
+	if i < 0 || p[i] != 0x80 || padded(i+1) != len(p) {
+		return nil, ErrPadding
+	}
+	return p[:i], nil
+}
@@ -0,0 +1,38 @@
+package envelope
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_Padded(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	for _, n := range []int{0, 1, 11, 12, 100, MaxPayload - 1} {
+		pt := bytes.Repeat([]byte{0x80}, n)
+		for _, fl := range []uint8{0, FlagLength, FlagTime} {
+			f, err := SealPadded(key, Header{Flags: fl}, pt)
+			if err != nil {
+				t.Fatal("SealPadded failed", n, fl, err)
+			}
+			if p, err := OpenPadded(key, f); err != nil || !bytes.Equal(p, pt) {
+				t.Error("OpenPadded failed", n, fl, err)
+			}
+		}
+	}
+	if _, err := SealPadded(key, Header{}, make([]byte, MaxPayload)); err != ErrLength {
+		t.Error("Long payload sealed", err)
+	}
+	for _, p := range []string{msg16, "abcd\x80" + string(make([]byte, 11))} {
+		f, _ := Seal(key, Header{}, []byte(p))
+		if _, err := OpenPaddedDetailed(key, f); err != ErrPadding {
+			t.Error("Bad padding accepted", p, err)
+		}
+	}
+	f, _ := SealPadded(key, Header{}, []byte("hi"))
+	f[HeaderSize] ^= 1
+	if _, err := OpenPaddedDetailed(key, f); err != ErrMAC {
+		t.Error("Forgery accepted", err)
+	}
+}
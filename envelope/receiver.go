@@ -0,0 +1,204 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package envelope
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ohir/xxtea"
+)
+
+var (
+	ErrNoKey  = errors.New("envelope: no key for frame key-id and epoch")
+	ErrStale  = errors.New("envelope: frame of an expired epoch")
+	ErrReplay = errors.New("envelope: frame counter replayed")
+	ErrSkew   = errors.New("envelope: frame time out of accepted skew")
+)
+
+// DowngradeError is returned by Receiver for frames of a version older than
+// the accepted minimum.
+type DowngradeError struct {
+	Version uint8 // frame version
+	Min     uint8 // minimum accepted version
+}
+
+// errDowngrade is the only downgrade among known versions, preallocated so
+// rejecting frames does not allocate.
+var errDowngrade = &DowngradeError{Version1, Version2}
+
+// downgrade returns the *DowngradeError of the versions.  Errors returned
+// may be shared and must not be modified.
+func downgrade(v, min uint8) error {
+	if v == errDowngrade.Version && min == errDowngrade.Min {
+		return errDowngrade
+	}
+	return &DowngradeError{v, min}
+}
+
+func (e *DowngradeError) Error() string {
+	return "envelope: frame version " + strconv.Itoa(int(e.Version)) +
+		" below accepted minimum " + strconv.Itoa(int(e.Min))
+}
+
+type keyRef struct {
+	kid   uint16
+	epoch uint32
+}
+
+// State is the receiving state of a single key-id: the epoch, counter,
+// time and version of its last authentic frame.
+type State struct {
+	Epoch   uint32
+	Counter uint32
+	Time    uint32
+	Version uint8
+}
+
+// after reports whether the frame comes after the last one of the state.
+func (t *State) after(h *Header) bool {
+	if h.Flags&FlagTime != 0 && h.Time != t.Time {
+		return h.Time > t.Time
+	}
+	return h.Counter > t.Counter
+}
+
+// Receiver opens frames of many key-ids, rejecting replays and frames of
+// expired epochs.
+//
+// Keys are installed with SetKey, possibly ahead of their use.  The first
+// authentic frame of a newer epoch rolls the key-id over: keys of older
+// epochs are dropped and counting starts anew.  Counters must strictly
+// increase within an epoch.
+//
+// Frames older than MinVersion are rejected with a *DowngradeError.  Once a
+// key-id sent an authentic frame of some version, its frames of any older
+// version are rejected the same way, so a fleet upgraded to a new version
+// can not be talked back into the old one.
+//
+// Frames with FlagTime are ordered by their time first, then by counter, so
+// a device that can not persist its counter may restart counting from zero
+// as long as its clock moves on.  If MaxSkew is set, timestamped frames
+// must also fall within MaxSkew of the Receiver's clock.
+//
+// If State is set, receiving state of each key-id is loaded from it on the
+// first frame and stored back after every authentic frame, before the
+// frame is handed to the caller.
+//
+// Malformed and forged frames are reported as ErrOpenFailed, as by Open,
+// unless Detailed is set.
+//
+// Receiver is not safe for concurrent use.
+type Receiver struct {
+	MinVersion uint8         // minimum accepted frame version
+	MaxSkew    time.Duration // accepted clock skew of timestamped frames
+	State      ReplayState   // optional persistence of receiving state
+	Detailed   bool          // report errors of OpenDetailed
+
+	keys   map[keyRef]xxtea.TeaKey
+	tracks map[uint16]*State
+}
+
+// NewReceiver returns an empty Receiver.
+func NewReceiver() *Receiver {
+	return &Receiver{
+		keys:   make(map[keyRef]xxtea.TeaKey),
+		tracks: make(map[uint16]*State),
+	}
+}
+
+// SetKey installs the key for the key-id and epoch.  Keys of epochs already
+// expired for the key-id are ignored.
+func (r *Receiver) SetKey(kid uint16, epoch uint32, k xxtea.TeaKey) {
+	if t := r.tracks[kid]; t != nil && epoch < t.Epoch {
+		return
+	}
+	r.keys[keyRef{kid, epoch}] = k
+}
+
+// Open authenticates and decrypts the frame, then advances the state of the
+// frame's key-id.
+func (r *Receiver) Open(frame []byte) (Header, []byte, error) {
+	h, err := ParseHeader(frame)
+	if err != nil {
+		return h, nil, r.failed(err)
+	}
+	t, err := r.track(h.KeyID)
+	if err != nil {
+		return h, nil, err
+	}
+	least := r.MinVersion
+	if t != nil && t.Version > least {
+		least = t.Version
+	}
+	if h.Version < least {
+		return h, nil, downgrade(h.Version, least)
+	}
+	if t != nil {
+		if h.Epoch < t.Epoch {
+			return h, nil, ErrStale
+		}
+		if h.Epoch == t.Epoch && !t.after(&h) {
+			return h, nil, ErrReplay
+		}
+	}
+	if h.Flags&FlagTime != 0 && r.MaxSkew > 0 {
+		d := xxtea.Now().Sub(time.Unix(int64(h.Time), 0))
+		if d > r.MaxSkew || d < -r.MaxSkew {
+			return h, nil, ErrSkew
+		}
+	}
+	k, ok := r.keys[keyRef{h.KeyID, h.Epoch}]
+	if !ok {
+		return h, nil, ErrNoKey
+	}
+	h, payload, err := OpenDetailed(k, frame)
+	if err != nil {
+		return h, nil, r.failed(err)
+	}
+	var ns State
+	if t != nil {
+		ns = *t
+	}
+	ns.Epoch, ns.Counter, ns.Version = h.Epoch, h.Counter, h.Version
+	if h.Flags&FlagTime != 0 {
+		ns.Time = h.Time
+	}
+	if r.State != nil {
+		if err = r.State.Store(h.KeyID, ns); err != nil {
+			return h, nil, err
+		}
+	}
+	r.tracks[h.KeyID] = &ns
+	for ref := range r.keys {
+		if ref.kid == h.KeyID && ref.epoch < ns.Epoch {
+			delete(r.keys, ref)
+		}
+	}
+	return h, payload, nil
+}
+
+// failed returns the error to report for a frame failing to open.
+func (r *Receiver) failed(err error) error {
+	if r.Detailed {
+		return err
+	}
+	return ErrOpenFailed
+}
+
+// track returns receiving state of the key-id, or nil if there is none yet.
+func (r *Receiver) track(kid uint16) (*State, error) {
+	t := r.tracks[kid]
+	if t != nil || r.State == nil {
+		return t, nil
+	}
+	s, ok, err := r.State.Load(kid)
+	if err != nil || !ok {
+		return nil, err
+	}
+	r.tracks[kid] = &s
+	return &s, nil
+}
@@ -0,0 +1,158 @@
+package envelope
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_ReceiverReplay(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	r := NewReceiver()
+	f1, _ := Seal(key, Header{KeyID: 1, Counter: 1}, []byte(msgMin))
+	f2, _ := Seal(key, Header{KeyID: 1, Counter: 2}, []byte(msgMin))
+	if _, _, err := r.Open(f1); err != ErrNoKey {
+		t.Error("Frame opened with no key set")
+	}
+	r.SetKey(1, 0, key)
+	if _, _, err := r.Open(f2); err != nil {
+		t.Error("Frame not opened", err)
+	}
+	if _, _, err := r.Open(f2); err != ErrReplay {
+		t.Error("Replayed frame opened")
+	}
+	if _, _, err := r.Open(f1); err != ErrReplay {
+		t.Error("Older frame opened")
+	}
+	forged := append([]byte(nil), f2...)
+	forged[11] = 3
+	if _, _, err := r.Open(forged); err != ErrOpenFailed {
+		t.Error("Forged counter accepted")
+	}
+	r.Detailed = true
+	if _, _, err := r.Open(forged); err != ErrMAC {
+		t.Error("Detailed Receiver did not tell the error", err)
+	}
+	r.Detailed = false
+	f3, _ := Seal(key, Header{KeyID: 1, Counter: 3}, []byte(msgMin))
+	if _, _, err := r.Open(f3); err != nil {
+		t.Error("Forgery advanced the counter", err)
+	}
+}
+
+func Test_ReceiverRollover(t *testing.T) {
+	k0 := xxtea.NewKey([]byte(keyBEBE))
+	k1 := xxtea.NewKey([]byte(keyLELE))
+	r := NewReceiver()
+	r.SetKey(7, 0, k0)
+	r.SetKey(7, 1, k1)
+	old, _ := Seal(k0, Header{KeyID: 7, Epoch: 0, Counter: 100}, []byte(msgMin))
+	f0, _ := Seal(k0, Header{KeyID: 7, Epoch: 0, Counter: 50}, []byte(msgMin))
+	f1, _ := Seal(k1, Header{KeyID: 7, Epoch: 1, Counter: 1}, []byte(msgMin))
+	if _, _, err := r.Open(f0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.Open(f1); err != nil {
+		t.Fatal("Rollover frame not opened", err)
+	}
+	if _, _, err := r.Open(old); err != ErrStale {
+		t.Error("Frame of expired epoch opened")
+	}
+	if _, ok := r.keys[keyRef{7, 0}]; ok {
+		t.Error("Expired key kept")
+	}
+	r.SetKey(7, 0, k0)
+	if _, ok := r.keys[keyRef{7, 0}]; ok {
+		t.Error("Expired key installed")
+	}
+	// other key-ids are independent
+	r.SetKey(8, 0, k0)
+	f8, _ := Seal(k0, Header{KeyID: 8, Counter: 1}, []byte(msgMin))
+	if _, _, err := r.Open(f8); err != nil {
+		t.Error("Frame of other key-id not opened", err)
+	}
+}
+
+func Test_ReceiverDowngrade(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	r := NewReceiver()
+	r.SetKey(1, 0, key)
+	r.SetKey(2, 0, key)
+	v1, _ := Seal(key, Header{Version: Version1, KeyID: 1, Counter: 1}, []byte(msgMin))
+	if _, _, err := r.Open(v1); err != nil {
+		t.Error("Version 1 frame not opened with no policy", err)
+	}
+	v2, _ := Seal(key, Header{Version: Version2, KeyID: 1, Counter: 2}, []byte(msgMin))
+	if _, _, err := r.Open(v2); err != nil {
+		t.Error("Version 2 frame not opened", err)
+	}
+	v1, _ = Seal(key, Header{Version: Version1, KeyID: 1, Counter: 3}, []byte(msgMin))
+	_, _, err := r.Open(v1)
+	if de, ok := err.(*DowngradeError); !ok || de.Version != Version1 || de.Min != Version2 {
+		t.Error("Downgrade of upgraded key-id accepted", err)
+	}
+	r.MinVersion = Version2
+	v1, _ = Seal(key, Header{Version: Version1, KeyID: 2, Counter: 1}, []byte(msgMin))
+	if _, _, err = r.Open(v1); err == nil || err.Error() == "" {
+		t.Error("Frame below MinVersion accepted")
+	}
+}
+
+func Test_ReceiverTime(t *testing.T) {
+	defer xxtea.SetClock(nil)
+	key := xxtea.NewKey([]byte(keyBEBE))
+	r := NewReceiver()
+	r.SetKey(1, 0, key)
+	r.MaxSkew = time.Minute
+	clk := time.Unix(1700000000, 0)
+	xxtea.SetClock(xxtea.ClockFunc(func() time.Time { return clk }))
+	seal := func(ts, ctr uint32) []byte {
+		f, _ := Seal(key, Header{Flags: FlagTime, KeyID: 1, Time: ts, Counter: ctr}, []byte(msgMin))
+		return f
+	}
+	if _, _, err := r.Open(seal(1700000000, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.Open(seal(1700000000, 5)); err != ErrReplay {
+		t.Error("Replayed timestamped frame opened")
+	}
+	if _, _, err := r.Open(seal(1700000000, 6)); err != nil {
+		t.Error("Next counter in same second not opened", err)
+	}
+	clk = clk.Add(10 * time.Second)
+	if _, _, err := r.Open(seal(1700000010, 0)); err != nil {
+		t.Error("Counter restart with later time not opened", err)
+	}
+	if _, _, err := r.Open(seal(1700000009, 99)); err != ErrReplay {
+		t.Error("Earlier time opened")
+	}
+	if _, _, err := r.Open(seal(1700000100, 1)); err != ErrSkew {
+		t.Error("Frame from the future opened")
+	}
+	clk = clk.Add(time.Hour)
+	if _, _, err := r.Open(seal(1700000020, 1)); err != ErrSkew {
+		t.Error("Frame from the past opened")
+	}
+}
+
+func Test_ErrorAllocs(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	r := NewReceiver()
+	r.MinVersion = Version2
+	r.SetKey(1, 0, key)
+	v1, _ := Seal(key, Header{Version: Version1, KeyID: 1}, []byte(msgMin))
+	v2, _ := Seal(key, Header{KeyID: 1, Counter: 1}, []byte(msgMin))
+	r.Open(v2)
+	short := v2[:HeaderSize+3]
+	for name, f := range map[string]func(){
+		"downgrade": func() { r.Open(v1) },
+		"replay":    func() { r.Open(v2) },
+		"malformed": func() { r.Open(short) },
+		"header":    func() { ParseHeader(short) },
+	} {
+		if n := testing.AllocsPerRun(100, f); n != 0 {
+			t.Error("Error path allocates:", name, n)
+		}
+	}
+}
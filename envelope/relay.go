@@ -0,0 +1,100 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package envelope
+
+import (
+	"crypto/hmac"
+
+	"github.com/ohir/xxtea"
+)
+
+// Verify checks the frame under the MAC subkey and returns its header,
+// without decrypting the payload.
+func Verify(macKey xxtea.TeaKey, frame []byte) (Header, error) {
+	h, err := ParseHeader(frame)
+	if err != nil {
+		return h, err
+	}
+	body := frame[:len(frame)-tagSize(h.Version)]
+	if !hmac.Equal(macTag(macKey, body), frame[len(body):]) {
+		return h, ErrMAC
+	}
+	return h, nil
+}
+
+// Relay filters frames for a store-and-forward gateway that holds MAC
+// subkeys only: it passes authentic frames that are not replays nor of
+// expired epochs, with the ordering rules of Receiver, and never sees
+// plaintext.
+//
+// Relay is not safe for concurrent use.
+type Relay struct {
+	MinVersion uint8 // minimum accepted frame version
+
+	keys   map[keyRef]xxtea.TeaKey
+	tracks map[uint16]*State
+}
+
+// NewRelay returns an empty Relay.
+func NewRelay() *Relay {
+	return &Relay{
+		keys:   make(map[keyRef]xxtea.TeaKey),
+		tracks: make(map[uint16]*State),
+	}
+}
+
+// SetMACKey installs the MAC subkey (see MACKey) for the key-id and epoch.
+func (r *Relay) SetMACKey(kid uint16, epoch uint32, macKey xxtea.TeaKey) {
+	if t := r.tracks[kid]; t != nil && epoch < t.Epoch {
+		return
+	}
+	r.keys[keyRef{kid, epoch}] = macKey
+}
+
+// Check verifies the frame and advances the state of its key-id.  A nil
+// error means the frame is to be forwarded as is.
+func (r *Relay) Check(frame []byte) (Header, error) {
+	h, err := ParseHeader(frame)
+	if err != nil {
+		return h, err
+	}
+	t := r.tracks[h.KeyID]
+	least := r.MinVersion
+	if t != nil && t.Version > least {
+		least = t.Version
+	}
+	if h.Version < least {
+		return h, downgrade(h.Version, least)
+	}
+	if t != nil {
+		if h.Epoch < t.Epoch {
+			return h, ErrStale
+		}
+		if h.Epoch == t.Epoch && !t.after(&h) {
+			return h, ErrReplay
+		}
+	}
+	mk, ok := r.keys[keyRef{h.KeyID, h.Epoch}]
+	if !ok {
+		return h, ErrNoKey
+	}
+	if h, err = Verify(mk, frame); err != nil {
+		return h, err
+	}
+	ns := State{Epoch: h.Epoch, Counter: h.Counter, Version: h.Version}
+	if t != nil {
+		ns.Time = t.Time
+	}
+	if h.Flags&FlagTime != 0 {
+		ns.Time = h.Time
+	}
+	r.tracks[h.KeyID] = &ns
+	for ref := range r.keys {
+		if ref.kid == h.KeyID && ref.epoch < ns.Epoch {
+			delete(r.keys, ref)
+		}
+	}
+	return h, nil
+}
@@ -0,0 +1,49 @@
+package envelope
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_Relay(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	mk := MACKey(key)
+	r := NewRelay()
+	r.SetMACKey(1, 0, mk)
+	f1, _ := Seal(key, Header{KeyID: 1, Counter: 1}, []byte(msg16))
+	f2, _ := Seal(key, Header{KeyID: 1, Counter: 2}, []byte(msg16))
+	if _, err := r.Check(f1); err != nil {
+		t.Fatal("Authentic frame not passed", err)
+	}
+	if _, err := r.Check(f1); err != ErrReplay {
+		t.Error("Replay passed", err)
+	}
+	bad := append([]byte(nil), f2...)
+	bad[HeaderSize] ^= 1
+	if _, err := r.Check(bad); err != ErrMAC {
+		t.Error("Forgery passed", err)
+	}
+	if h, err := r.Check(f2); err != nil || h.Counter != 2 {
+		t.Error("Next frame not passed", err)
+	}
+	f3, _ := Seal(key, Header{KeyID: 2}, []byte(msg16))
+	if _, err := r.Check(f3); err != ErrNoKey {
+		t.Error("Frame of unknown key passed", err)
+	}
+	r.SetMACKey(1, 1, mk)
+	f4, _ := Seal(key, Header{KeyID: 1, Epoch: 1}, []byte(msg16))
+	f5, _ := Seal(key, Header{KeyID: 1, Counter: 9}, []byte(msg16))
+	if _, err := r.Check(f4); err != nil {
+		t.Error("Frame of next epoch not passed", err)
+	}
+	if _, err := r.Check(f5); err != ErrStale {
+		t.Error("Frame of past epoch passed", err)
+	}
+	if _, err := Verify(MACKey(xxtea.NewKey([]byte(keyLELE))), f1); err != ErrMAC {
+		t.Error("Verified under other key", err)
+	}
+	if mk == key || mk == key.Derive(lblEnc) {
+		t.Error("MAC key is the encryption key")
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package envelope
+
+import "github.com/ohir/xxtea"
+
+// Keyring gives the key of a key-id and epoch.
+type Keyring interface {
+	Key(kid uint16, epoch uint32) (k xxtea.TeaKey, ok bool)
+}
+
+// KeyringFunc adapts a function to the Keyring interface.
+type KeyringFunc func(kid uint16, epoch uint32) (xxtea.TeaKey, bool)
+
+// Key implements Keyring.
+func (f KeyringFunc) Key(kid uint16, epoch uint32) (xxtea.TeaKey, bool) {
+	return f(kid, epoch)
+}
+
+// Key implements Keyring over keys installed with SetKey.
+func (r *Receiver) Key(kid uint16, epoch uint32) (xxtea.TeaKey, bool) {
+	k, ok := r.keys[keyRef{kid, epoch}]
+	return k, ok
+}
+
+// Reseal opens the frame under its key from the old keyring and seals the
+// payload again under the new key, with the same header, for migrating
+// stored frames during key rotation.  The plaintext is wiped before Reseal
+// returns.  It returns ErrNoKey if the old keyring has no key for the
+// frame.
+func Reseal(frame []byte, old Keyring, newKey xxtea.TeaKey) ([]byte, error) {
+	h, err := ParseHeader(frame)
+	if err != nil {
+		return nil, err
+	}
+	k, ok := old.Key(h.KeyID, h.Epoch)
+	if !ok {
+		return nil, ErrNoKey
+	}
+	h, p, err := Open(k, frame)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Seal(newKey, h, p)
+	for i := range p {
+		p[i] = 0
+	}
+	return f, err
+}
@@ -0,0 +1,39 @@
+package envelope
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_Reseal(t *testing.T) {
+	oldK, newK := xxtea.NewKey([]byte(keyBEBE)), xxtea.NewKey([]byte(keyLELE))
+	r := NewReceiver()
+	r.SetKey(7, 2, oldK)
+	for _, h := range []Header{
+		{KeyID: 7, Epoch: 2, Counter: 5},
+		{KeyID: 7, Epoch: 2, Flags: FlagLength | FlagTime, Time: 1700000000},
+		{KeyID: 7, Epoch: 2, Flags: FlagSIV | FlagFixed},
+	} {
+		f, _ := Seal(oldK, h, []byte(msg16))
+		g, err := Reseal(f, r, newK)
+		if err != nil {
+			t.Fatal("Reseal failed", err)
+		}
+		if _, _, err := Open(oldK, g); err != ErrOpenFailed {
+			t.Error("Resealed frame opens under old key")
+		}
+		gh, p, err := Open(newK, g)
+		if err != nil || string(p) != msg16 || gh.Counter != h.Counter || gh.Time != h.Time {
+			t.Error("Resealed frame differs", err)
+		}
+	}
+	f, _ := Seal(oldK, Header{KeyID: 8}, []byte(msg16))
+	if _, err := Reseal(f, r, newK); err != ErrNoKey {
+		t.Error("Frame of unknown key resealed", err)
+	}
+	wrong := KeyringFunc(func(uint16, uint32) (xxtea.TeaKey, bool) { return newK, true })
+	if _, err := Reseal(f, wrong, newK); err != ErrOpenFailed {
+		t.Error("Forged frame resealed", err)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package envelope
+
+// Options describe frames for OverheadFor.
+type Options struct {
+	Version uint8 // frame version, Latest if zero
+	Flags   uint8 // header flags
+	Padded  bool  // sealed by SealPadded
+}
+
+// OverheadFor returns the exact size of the frame sealing plainLen bytes
+// of payload as the options say, for link budgeting without sealing
+// anything.  It returns the error Seal or SealPadded would return for
+// such a payload.
+func OverheadFor(plainLen int, opts Options) (wireLen int, err error) {
+	h := Header{Version: opts.Version, Flags: opts.Flags}
+	if h.Version == 0 {
+		h.Version = Latest
+	}
+	ts := tagSize(h.Version)
+	if ts == 0 {
+		return 0, ErrVersion
+	}
+	if h.Flags&^flagsKnown != 0 || h.Flags&(FlagLength|FlagFixed) == FlagLength|FlagFixed {
+		return 0, ErrFlags
+	}
+	n := plainLen
+	switch {
+	case n < 0:
+		return 0, ErrLength
+	case h.Flags&FlagLength != 0 && n <= MaxPayload:
+		n = padded(n)
+	case h.Flags&FlagFixed != 0:
+		if n > MaxFixed {
+			return 0, ErrLength
+		}
+		n = MaxPayload
+	case opts.Padded:
+		if n > MaxPayload-1 {
+			return 0, ErrLength
+		}
+		n = padded(n + 1)
+	}
+	if n < MinPayload || n > MaxPayload || n&3 != 0 {
+		return 0, ErrLength
+	}
+	return h.size() + n + ts, nil
+}
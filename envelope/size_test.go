@@ -0,0 +1,39 @@
+package envelope
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_OverheadFor(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	for _, o := range []Options{
+		{}, {Version: Version1}, {Flags: FlagTime | FlagSIV}, {Flags: FlagLength},
+		{Flags: FlagFixed | FlagTime}, {Padded: true}, {Version: Version1, Padded: true, Flags: FlagTime},
+	} {
+		for n := 0; n <= MaxPayload+1; n++ {
+			h := Header{Version: o.Version, Flags: o.Flags}
+			var f []byte
+			var err error
+			if o.Padded {
+				f, err = SealPadded(key, h, make([]byte, n))
+			} else {
+				f, err = Seal(key, h, make([]byte, n))
+			}
+			w, err2 := OverheadFor(n, o)
+			if err != err2 || w != len(f) {
+				t.Fatal("OverheadFor differs from Seal", o, n, w, len(f), err, err2)
+			}
+		}
+	}
+	if _, err := OverheadFor(12, Options{Version: 9}); err != ErrVersion {
+		t.Error("Unknown version sized", err)
+	}
+	if _, err := OverheadFor(12, Options{Flags: FlagFixed | FlagLength}); err != ErrFlags {
+		t.Error("Bad flags sized", err)
+	}
+	if _, err := OverheadFor(-1, Options{Flags: FlagLength}); err != ErrLength {
+		t.Error("Negative length sized", err)
+	}
+}
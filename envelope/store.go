@@ -0,0 +1,112 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package envelope
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrCounter = errors.New("envelope: frame counters exhausted")
+
+// ReplayState persists receiving state of key-ids, so a restarted Receiver
+// does not accept frames it has already seen.
+type ReplayState interface {
+	// Load returns the state of the key-id, ok is false if none was stored.
+	Load(kid uint16) (s State, ok bool, err error)
+	// Store durably records the state of the key-id.
+	Store(kid uint16, s State) error
+}
+
+// CounterStore persists sending counters, so a restarted sender never
+// reuses a counter of a key-id and epoch.
+type CounterStore interface {
+	// Reserve durably reserves n counters of the key-id and epoch and
+	// returns the first of them.  Counters reserved once are never handed
+	// out again, even if they were not used.
+	Reserve(kid uint16, epoch uint32, n uint32) (first uint32, err error)
+}
+
+// Counter hands out frame counters of a key-id and epoch, reserving them
+// from Store in blocks of Block counters (1 if zero).  A crash loses at
+// most a block of unused counters, never reuses one.
+//
+// Counter is safe for concurrent use.
+type Counter struct {
+	Store CounterStore
+	KeyID uint16
+	Epoch uint32
+	Block uint32
+
+	mu        sync.Mutex
+	next, end uint64
+}
+
+// Next returns the next unused counter.
+func (c *Counter) Next() (uint32, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.next == c.end {
+		n := c.Block
+		if n == 0 {
+			n = 1
+		}
+		first, err := c.Store.Reserve(c.KeyID, c.Epoch, n)
+		if err != nil {
+			return 0, err
+		}
+		c.next, c.end = uint64(first), uint64(first)+uint64(n)
+	}
+	if c.next > 1<<32-1 {
+		return 0, ErrCounter
+	}
+	c.next++
+	return uint32(c.next - 1), nil
+}
+
+// MemStore is an in-memory ReplayState and CounterStore, for tests and for
+// receivers that do not need to survive a restart.
+//
+// MemStore is safe for concurrent use.
+type MemStore struct {
+	mu       sync.Mutex
+	states   map[uint16]State
+	counters map[keyRef]uint64
+}
+
+// Load implements ReplayState.
+func (m *MemStore) Load(kid uint16) (State, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.states[kid]
+	return s, ok, nil
+}
+
+// Store implements ReplayState.
+func (m *MemStore) Store(kid uint16, s State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.states == nil {
+		m.states = make(map[uint16]State)
+	}
+	m.states[kid] = s
+	return nil
+}
+
+// Reserve implements CounterStore.
+func (m *MemStore) Reserve(kid uint16, epoch uint32, n uint32) (uint32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counters == nil {
+		m.counters = make(map[keyRef]uint64)
+	}
+	ref := keyRef{kid, epoch}
+	first := m.counters[ref]
+	if first+uint64(n) > 1<<32 {
+		return 0, ErrCounter
+	}
+	m.counters[ref] = first + uint64(n)
+	return uint32(first), nil
+}
@@ -0,0 +1,59 @@
+package envelope
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_ReceiverState(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	st := &MemStore{}
+	r := NewReceiver()
+	r.State = st
+	r.SetKey(1, 0, key)
+	f1, _ := Seal(key, Header{KeyID: 1, Counter: 1}, []byte(msgMin))
+	f2, _ := Seal(key, Header{KeyID: 1, Counter: 2}, []byte(msgMin))
+	if _, _, err := r.Open(f2); err != nil {
+		t.Fatal(err)
+	}
+	if s, ok, _ := st.Load(1); !ok || s.Counter != 2 || s.Version != Latest {
+		t.Error("State not stored", s)
+	}
+	r = NewReceiver() // restart
+	r.State = st
+	r.SetKey(1, 0, key)
+	if _, _, err := r.Open(f1); err != ErrReplay {
+		t.Error("Restarted Receiver opened seen frame", err)
+	}
+}
+
+func Test_Counter(t *testing.T) {
+	st := &MemStore{}
+	c := &Counter{Store: st, KeyID: 1, Block: 10}
+	for i := uint32(0); i < 25; i++ {
+		if n, err := c.Next(); err != nil || n != i {
+			t.Fatal("Bad counter", n, "want", i, err)
+		}
+	}
+	c = &Counter{Store: st, KeyID: 1, Block: 10} // restart
+	if n, _ := c.Next(); n != 30 {
+		t.Error("Counter reused after restart", n)
+	}
+	c = &Counter{Store: st, KeyID: 1, Epoch: 1}
+	if n, _ := c.Next(); n != 0 {
+		t.Error("New epoch did not start anew", n)
+	}
+	st.counters[keyRef{2, 0}] = 1<<32 - 1
+	c = &Counter{Store: st, KeyID: 2, Block: 2}
+	if _, err := c.Next(); err != ErrCounter {
+		t.Error("Counter overflow not reported")
+	}
+	c = &Counter{Store: st, KeyID: 2}
+	if n, err := c.Next(); err != nil || n != 1<<32-1 {
+		t.Error("Last counter not handed out", err)
+	}
+	if _, err := c.Next(); err != ErrCounter {
+		t.Error("Counter wrapped around")
+	}
+}
@@ -0,0 +1,46 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_Strict(t *testing.T) {
+	defer xxtea.SetStrict(false)
+	key := xxtea.NewKey([]byte(keyBEBE))
+	// FlagLength frame with junk in its padding, as some C libraries leave
+	h := Header{Version: Latest, Flags: FlagLength, Length: 5}
+	hs := h.size()
+	f := make([]byte, hs+MinPayload)
+	h.put(f)
+	frameKey(key, f[:hs]).Encrypt([]byte("hello junk!!"), f[hs:])
+	f = append(f, tag(key, f)...)
+	if _, p, err := OpenDetailed(key, f); err != nil || string(p) != "hello" {
+		t.Error("Lax Open failed", err)
+	}
+	g, _ := Seal(key, Header{Flags: FlagLength, KeyID: 7}, []byte("hello"))
+	j, _ := json.Marshal(Frame(g))
+	var ind bytes.Buffer
+	json.Indent(&ind, j, "", " ")
+	var fr Frame
+	if err := json.Unmarshal(ind.Bytes(), &fr); err != nil {
+		t.Error("Lax UnmarshalJSON failed", err)
+	}
+
+	xxtea.SetStrict(true)
+	if _, _, err := OpenDetailed(key, f); err != ErrFrame {
+		t.Error("Dirty padding accepted", err)
+	}
+	if _, p, err := OpenDetailed(key, g); err != nil || string(p) != "hello" {
+		t.Error("Strict Open failed", err)
+	}
+	if err := json.Unmarshal(ind.Bytes(), &fr); err == nil {
+		t.Error("Indented JSON accepted")
+	}
+	if err := json.Unmarshal(j, &fr); err != nil || !bytes.Equal(fr, g) {
+		t.Error("Strict UnmarshalJSON failed", err)
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package expstats exports session.Stats as an expvar.Var.
+//
+// It is a package of its own, as importing expvar registers /debug/vars on
+// http.DefaultServeMux and links net/http, which programs of small devices
+// using session do not want.
+package expstats
+
+import (
+	"expvar"
+
+	"github.com/ohir/xxtea/session"
+)
+
+// Var returns an expvar.Var showing current counts of the stats as JSON,
+// to be published with expvar.Publish.
+func Var(s *session.Stats) expvar.Var {
+	return expvar.Func(func() interface{} { return s.Counts() })
+}
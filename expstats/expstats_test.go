@@ -0,0 +1,23 @@
+package expstats
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/session"
+)
+
+func Test_Var(t *testing.T) {
+	key := xxtea.NewKey([]byte("0123456789ABCDEF"))
+	st := &session.Stats{}
+	s, r := session.NewSender(key), session.NewReceiver(key)
+	s.Stats, r.Stats = st, st
+	f, _ := s.Send([]byte("hello"))
+	r.Receive(f)
+	v := Var(st)
+	var c session.Counts
+	if err := json.Unmarshal([]byte(v.String()), &c); err != nil || c != st.Counts() || c.Opened != 1 {
+		t.Error("Var failed", err, v.String())
+	}
+}
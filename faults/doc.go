@@ -0,0 +1,28 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package faults injects random damage into frames the envelope and stream
+// decoders read, so applications can test their handling of hostile or
+// lossy links end to end:
+//
+//	go test -tags xxteafaults ./...
+//
+// with Set called in the test.  Without the xxteafaults build tag hooks do
+// nothing and compile away, and Set is a no-op; tests may check Enabled.
+//
+// Faults are drawn from a seeded source, so a failing run can be repeated.
+package faults
+
+// Config sets probabilities (0..1) of faults per decoded frame.
+type Config struct {
+	Corrupt  float64 // flip a random bit of the frame
+	Truncate float64 // cut the frame, or a stream record, short
+	FlipMAC  float64 // flip a random bit of the frame's tag
+	Seed     int64
+}
+
+// Counts are the numbers of faults injected.
+type Counts struct {
+	Corrupted, Truncated, MACFlips uint64
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build xxteafaults
+
+package faults
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Enabled tells whether faults can be injected in this build.
+const Enabled = true
+
+var (
+	mu  sync.Mutex
+	cfg Config
+	rnd *rand.Rand
+	cnt Counts
+)
+
+// Set starts injecting faults as c says, resetting the counts.  Zero
+// Config stops it.
+func Set(c Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	cfg, cnt = c, Counts{}
+	rnd = rand.New(rand.NewSource(c.Seed))
+}
+
+// Injected returns the counts of faults injected since the last Set.
+func Injected() Counts {
+	mu.Lock()
+	defer mu.Unlock()
+	return cnt
+}
+
+// hit reports whether a fault of probability p is due.  It needs mu held.
+func hit(p float64) bool {
+	return p > 0 && rnd.Float64() < p
+}
+
+// Frame is called by decoders with a frame they are about to open.  It
+// returns the frame, or a damaged copy of it.
+func Frame(b []byte) []byte {
+	mu.Lock()
+	defer mu.Unlock()
+	if rnd == nil || len(b) == 0 {
+		return b
+	}
+	if hit(cfg.Truncate) {
+		cnt.Truncated++
+		return append([]byte(nil), b[:rnd.Intn(len(b))]...)
+	}
+	if hit(cfg.Corrupt) {
+		cnt.Corrupted++
+		b = append([]byte(nil), b...)
+		b[rnd.Intn(len(b))] ^= 1 << rnd.Intn(8)
+		return b
+	}
+	if hit(cfg.FlipMAC) {
+		cnt.MACFlips++
+		b = append([]byte(nil), b...)
+		i := len(b) - 1 - rnd.Intn(macTail(len(b)))
+		b[i] ^= 1 << rnd.Intn(8)
+	}
+	return b
+}
+
+// Cut is called by stream readers with the length of the next record.  It
+// returns a shorter length if the record is due to be cut, desynchronizing
+// the stream as a lost chunk of a lossy link does.
+func Cut(n int) int {
+	mu.Lock()
+	defer mu.Unlock()
+	if rnd == nil || n == 0 || !hit(cfg.Truncate) {
+		return n
+	}
+	cnt.Truncated++
+	return rnd.Intn(n)
+}
+
+// macTail returns how many trailing bytes of an n-byte frame are its tag.
+func macTail(n int) int {
+	if n < 16 {
+		return n
+	}
+	return 16
+}
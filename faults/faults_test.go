@@ -0,0 +1,62 @@
+package faults_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+	"github.com/ohir/xxtea/faults"
+	"github.com/ohir/xxtea/stream"
+)
+
+var key = xxtea.NewKey([]byte("0123456789ABCDEF"))
+
+func Test_Envelope(t *testing.T) {
+	frame, _ := envelope.Seal(key, envelope.Header{Counter: 1}, []byte("Sixteen bytes!!!"))
+	if !faults.Enabled {
+		faults.Set(faults.Config{Corrupt: 1})
+		if _, _, err := envelope.Open(key, frame); err != nil {
+			t.Error("Fault injected without the build tag")
+		}
+		t.Skip("built without xxteafaults")
+	}
+	defer faults.Set(faults.Config{})
+	for _, c := range []faults.Config{{Corrupt: 1}, {Truncate: 1}, {FlipMAC: 1}} {
+		faults.Set(c)
+		for i := 0; i < 100; i++ {
+			if _, _, err := envelope.Open(key, frame); err == nil {
+				t.Fatal("Damaged frame opened", c)
+			}
+		}
+		if n := faults.Injected(); n.Corrupted+n.Truncated+n.MACFlips != 100 {
+			t.Error("Faults miscounted", n)
+		}
+	}
+	faults.Set(faults.Config{Corrupt: 0.5, Seed: 7})
+	var errs int
+	for i := 0; i < 200; i++ {
+		if _, _, err := envelope.Open(key, frame); err != nil {
+			errs++
+		}
+	}
+	if n := faults.Injected(); errs != int(n.Corrupted) || errs < 50 || errs > 150 {
+		t.Error("Faults not as probable as set", errs, n)
+	}
+}
+
+func Test_Stream(t *testing.T) {
+	if !faults.Enabled {
+		t.Skip("built without xxteafaults")
+	}
+	defer faults.Set(faults.Config{})
+	var buf bytes.Buffer
+	w, _ := stream.NewWriter(&buf, key)
+	w.Write(bytes.Repeat([]byte("log line\n"), 100))
+	w.Close()
+	faults.Set(faults.Config{Truncate: 0.3, Seed: 1})
+	if _, err := io.ReadAll(stream.NewReader(bytes.NewReader(buf.Bytes()), key)); err == nil {
+		t.Error("Lossy stream read without error")
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !xxteafaults
+
+package faults
+
+// Enabled tells whether faults can be injected in this build.
+const Enabled = false
+
+// Set does nothing without the xxteafaults build tag.
+func Set(c Config) {}
+
+// Injected returns zero counts without the xxteafaults build tag.
+func Injected() Counts { return Counts{} }
+
+// Frame returns b as is.
+func Frame(b []byte) []byte { return b }
+
+// Cut returns n as is.
+func Cut(n int) int { return n }
@@ -0,0 +1,50 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+var lblFingerprint = []byte("xxtea-fingerprint")
+
+// TeaKey.Fingerprint returns 8 hex digits telling keys apart in logs: the
+// leading bytes of SHA-256 of a label and the key.  Equal keys have equal
+// fingerprints; the key can not be told from it.  The zero key gives
+// "zero".
+func (k TeaKey) Fingerprint() string {
+	if k == (TeaKey{}) {
+		return "zero"
+	}
+	h := sha256.New()
+	h.Write(lblFingerprint)
+	h.Write(k.Bytes())
+	return hex.EncodeToString(h.Sum(nil)[:4])
+}
+
+// TeaKey.String returns the key redacted to its fingerprint, eg.
+// "xxtea.TeaKey(a1b2c3d4…)", never the key words.  The zero key, most
+// likely one never set, gives "xxtea.TeaKey(zero)".
+func (k TeaKey) String() string {
+	if k == (TeaKey{}) {
+		return "xxtea.TeaKey(zero)"
+	}
+	return "xxtea.TeaKey(" + k.Fingerprint() + "…)"
+}
+
+// TeaKey.GoString is String, so %#v does not print the key either.
+func (k TeaKey) GoString() string {
+	return k.String()
+}
+
+// TeaKey.Format prints String for every verb, so %x or %d do not print
+// the key words.  Keys in unexported struct fields are printed by fmt
+// without calling methods; keep them out of printed structs.
+func (k TeaKey) Format(f fmt.State, verb rune) {
+	io.WriteString(f, k.String())
+}
@@ -0,0 +1,36 @@
+package xxtea
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_Format(t *testing.T) {
+	k := NewKey([]byte(keyBEBE))
+	words := []string{fmt.Sprint(k[0]), fmt.Sprintf("%x", k[0]), fmt.Sprintf("%X", k[0])}
+	want := "xxtea.TeaKey(" + k.Fingerprint() + "…)"
+	for _, f := range []string{"%v", "%+v", "%#v", "%s", "%x", "%X", "%d", "%q", "%08x"} {
+		if got := fmt.Sprintf(f, k); got != want {
+			t.Error("TeaKey formatted", f, got)
+		}
+	}
+	for _, s := range []string{
+		fmt.Sprintln(k),
+		fmt.Sprint([]TeaKey{k}),
+		fmt.Sprintf("%+v", struct{ Key TeaKey }{k}),
+		fmt.Sprintf("%v", &k),
+	} {
+		for _, w := range words {
+			if strings.Contains(s, w) {
+				t.Error("Key words printed", s)
+			}
+		}
+	}
+	if len(k.Fingerprint()) != 8 || k.Fingerprint() == NewKey([]byte(keyLELE)).Fingerprint() {
+		t.Error("Fingerprint failed")
+	}
+	if (TeaKey{}).String() != "xxtea.TeaKey(zero)" {
+		t.Error("Zero key fingerprint failed")
+	}
+}
@@ -0,0 +1,108 @@
+package xxtea
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// fuzzKey returns a non-zero key of the first 16 bytes of b, zero padded.
+func fuzzKey(b []byte) (TeaKey, bool) {
+	var kb [16]byte
+	copy(kb[:], b)
+	k, err := MakeKey(kb[:])
+	return k, err == nil
+}
+
+// FuzzRoundTrip checks every message length of 8..208 bytes, cut from
+// msg and trimmed to whole words, against the reference code, the LE and
+// word forms, and the juggling helpers.
+func FuzzRoundTrip(f *testing.F) {
+	f.Add([]byte(keyBEBE), []byte(msgMin))
+	f.Add([]byte(keyLELE), []byte(msgMax))
+	f.Add([]byte{1}, make([]byte, 8))
+	f.Fuzz(func(t *testing.T, key, msg []byte) {
+		k, ok := fuzzKey(key)
+		if !ok {
+			t.Skip()
+		}
+		if len(msg) > 208 {
+			msg = msg[:208]
+		}
+		msg = msg[:len(msg)&^3]
+		n := len(msg)
+		if n < 8 {
+			t.Skip()
+		}
+		if n == 8 {
+			ct := k.EncryptLegacyBTEA(msg, make(bs, n))
+			if !bytes.Equal(k.DecryptLegacyBTEA(ct, ct), msg) {
+				t.Fatal("LegacyBTEA round trip failed")
+			}
+			return
+		}
+		ct := k.Encrypt(msg, make(bs, n))
+		ref := append(bs(nil), msg...)
+		refBtea(ref, int32(n/4), k)
+		if !bytes.Equal(ct, ref) {
+			t.Fatal("Encrypt differs from reference", n)
+		}
+		if !bytes.Equal(k.Decrypt(ct, make(bs, n)), msg) {
+			t.Fatal("Decrypt failed", n)
+		}
+		le := AsLEBE(append(bs(nil), msg...))
+		if !bytes.Equal(AsLEBE(k.EncryptLE(le, le)), ct) {
+			t.Fatal("EncryptLE differs from juggled Encrypt", n)
+		}
+		v := make([]uint32, n/4)
+		for i := range v {
+			v[i] = uint32(msg[4*i])<<24 | uint32(msg[4*i+1])<<16 | uint32(msg[4*i+2])<<8 | uint32(msg[4*i+3])
+		}
+		k.EncryptWords(v)
+		if !bytes.Equal(storeWords(v, make(bs, n)), ct) {
+			t.Fatal("EncryptWords differs from Encrypt", n)
+		}
+		for _, j := range []func([]byte) []byte{AsBELE, AsLEBE, AsLELE} {
+			b := append(bs(nil), msg...)
+			if !bytes.Equal(j(j(b)), msg) {
+				t.Fatal("Juggling is not an involution", n)
+			}
+		}
+		if n&7 == 0 {
+			for _, j := range []func([]byte) []byte{AsBELE64, AsLEBE64} {
+				b := append(bs(nil), msg...)
+				if !bytes.Equal(j(j(b)), msg) {
+					t.Fatal("64-bit juggling is not an involution", n)
+				}
+			}
+		}
+	})
+}
+
+// FuzzDecryptRobustness feeds arbitrary ciphertext to every function that
+// takes it from the outside: they must return an error, never panic.
+func FuzzDecryptRobustness(f *testing.F) {
+	f.Add([]byte(keyBEBE), []byte{})
+	f.Add([]byte(keyBEBE), []byte(msgMin))
+	f.Add([]byte(keyLELE), make([]byte, 211))
+	f.Fuzz(func(t *testing.T, key, ct []byte) {
+		k, ok := fuzzKey(key)
+		if !ok {
+			t.Skip()
+		}
+		for p := PadNone + 1; p <= PadZero; p++ {
+			if pt, err := k.DecryptAny(ct, p); err == nil && len(pt) > len(ct) {
+				t.Fatal("DecryptAny grew the message", p)
+			}
+		}
+		k.DecryptChecked(ct, make(bs, len(ct)))
+		k.DecryptLong(ct)
+		k.DecryptFromHex(hex.EncodeToString(ct))
+		k.DecryptFromHex(string(ct))
+		k.DecryptFromBase64(string(ct), nil)
+		var x TeaKey
+		x.UnmarshalText(ct)
+		ParseKeyHex(string(ct))
+		ParseKeyBase64(string(ct))
+	})
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package group distributes a shared group key to a fleet of devices, each
+// holding only its own xxtea.TeaKey.
+//
+// The group key is wrapped separately under every device key.  Each wrapped
+// copy carries the epoch number of the group key, so the key can be rotated
+// by distributing a new one with a higher epoch.
+//
+// Wrapped key layout, 32 bytes:
+//
+//	epoch (4B BE) | XXTEA( epoch (4B) | group key (16B) | check (8B) )
+//
+// Wrapping is done under a subkey derived from the device key, never with
+// the device key itself.
+package group
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ohir/xxtea"
+)
+
+// WrappedSize is the size of a wrapped group key.
+const WrappedSize = 32
+
+// WrappedKey is a group key wrapped under a single device key.
+type WrappedKey [WrappedSize]byte
+
+var ErrUnwrap = errors.New("group: wrapped key does not authenticate")
+
+var check = []byte("xxteaGRP")
+
+var lblWrap = []byte("group-wrap")
+
+// Epoch returns the epoch of the wrapped key, as given in its clear part.
+func (w WrappedKey) Epoch() uint32 {
+	return binary.BigEndian.Uint32(w[:4])
+}
+
+// Wrap returns the group key of the given epoch wrapped under device key.
+func Wrap(groupKey xxtea.TeaKey, epoch uint32, device xxtea.TeaKey) (w WrappedKey) {
+	binary.BigEndian.PutUint32(w[:4], epoch)
+	binary.BigEndian.PutUint32(w[4:8], epoch)
+	copy(w[8:24], groupKey.Bytes())
+	copy(w[24:], check)
+	device.Derive(lblWrap).Encrypt(w[4:], w[4:])
+	return
+}
+
+// Distribute wraps the group key of the given epoch for each of devices.
+// Returned slice is in devices order.
+func Distribute(groupKey xxtea.TeaKey, epoch uint32, devices []xxtea.TeaKey) []WrappedKey {
+	r := make([]WrappedKey, len(devices))
+	for i, d := range devices {
+		r[i] = Wrap(groupKey, epoch, d)
+	}
+	return r
+}
+
+// Unwrap is the device side of Distribute.  It returns the group key and its
+// epoch, or ErrUnwrap if w was not wrapped for the device key or was altered.
+func Unwrap(device xxtea.TeaKey, w WrappedKey) (groupKey xxtea.TeaKey, epoch uint32, err error) {
+	device.Derive(lblWrap).Decrypt(w[4:], w[4:])
+	ok := subtle.ConstantTimeCompare(w[24:], check) &
+		subtle.ConstantTimeCompare(w[:4], w[4:8])
+	if ok != 1 {
+		return groupKey, 0, ErrUnwrap
+	}
+	for i := range groupKey {
+		groupKey[i] = binary.BigEndian.Uint32(w[8+4*i:])
+	}
+	return groupKey, w.Epoch(), nil
+}
@@ -0,0 +1,49 @@
+package group
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+const (
+	keyBEBE = "0123456789ABCDEF"
+	keyLELE = "FEDCBA9876543210"
+	keyGRP0 = "GroupKeyEpoch000"
+)
+
+func Test_Distribute(t *testing.T) {
+	gk := xxtea.NewKey([]byte(keyGRP0))
+	devs := []xxtea.TeaKey{xxtea.NewKey([]byte(keyBEBE)), xxtea.NewKey([]byte(keyLELE))}
+	ws := Distribute(gk, 7, devs)
+	if len(ws) != 2 || ws[0] == ws[1] {
+		t.Fatal("Distribute gave bad wraps")
+	}
+	for i, d := range devs {
+		if ws[i].Epoch() != 7 {
+			t.Error("Clear epoch is wrong")
+		}
+		k, e, err := Unwrap(d, ws[i])
+		if err != nil || k != gk || e != 7 {
+			t.Error("Unwrap failed for device", i, err)
+		}
+	}
+	if _, _, err := Unwrap(devs[1], ws[0]); err != ErrUnwrap {
+		t.Error("Unwrap with other device key succeeded")
+	}
+}
+
+func Test_Tamper(t *testing.T) {
+	gk := xxtea.NewKey([]byte(keyGRP0))
+	dk := xxtea.NewKey([]byte(keyBEBE))
+	w := Wrap(gk, 1, dk)
+	w[3] = 2 // claim newer epoch
+	if _, _, err := Unwrap(dk, w); err != ErrUnwrap {
+		t.Error("Unwrap accepted altered epoch")
+	}
+	w = Wrap(gk, 1, dk)
+	w[WrappedSize-1] ^= 0x80
+	if _, _, err := Unwrap(dk, w); err != ErrUnwrap {
+		t.Error("Unwrap accepted altered ciphertext")
+	}
+}
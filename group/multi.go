@@ -0,0 +1,78 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package group
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+var (
+	ErrBlob       = errors.New("group: malformed multi-recipient blob")
+	ErrRecipients = errors.New("group: no recipients or too many of them")
+	ErrNotForUs   = errors.New("group: blob has no key for the device")
+)
+
+// SealMulti seals the payload (up to envelope.MaxPayload bytes) once under
+// a fresh content key, wrapped for every recipient, so a single blob can
+// be published to devices each holding only its own key:
+//
+//	count (2B BE) | wrapped content key (32B) ... | envelope frame
+//
+// Keys are wrapped as by Wrap, with a random blob id as the epoch; the id
+// is also the epoch of the frame, so wrapped keys can not be moved to
+// another blob.
+func SealMulti(payload []byte, recipients []xxtea.TeaKey) ([]byte, error) {
+	if len(recipients) == 0 || len(recipients) > 1<<16-1 {
+		return nil, ErrRecipients
+	}
+	var rnd [20]byte
+	if err := xxtea.ReadEntropy(rnd[:]); err != nil {
+		return nil, err
+	}
+	ck := xxtea.NewKey(rnd[:16])
+	id := binary.BigEndian.Uint32(rnd[16:])
+	f, err := envelope.Seal(ck, envelope.Header{Flags: envelope.FlagLength, Epoch: id}, payload)
+	if err != nil {
+		return nil, err
+	}
+	blob := make([]byte, 2, 2+WrappedSize*len(recipients)+len(f))
+	binary.BigEndian.PutUint16(blob, uint16(len(recipients)))
+	for _, w := range Distribute(ck, id, recipients) {
+		blob = append(blob, w[:]...)
+	}
+	return append(blob, f...), nil
+}
+
+// OpenMulti is the device side of SealMulti.  It returns ErrNotForUs if
+// none of the wrapped keys is for the device.
+func OpenMulti(device xxtea.TeaKey, blob []byte) ([]byte, error) {
+	if len(blob) < 2 {
+		return nil, ErrBlob
+	}
+	n := int(binary.BigEndian.Uint16(blob))
+	if n == 0 || len(blob) < 2+n*WrappedSize {
+		return nil, ErrBlob
+	}
+	f := blob[2+n*WrappedSize:]
+	h, err := envelope.ParseHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		var w WrappedKey
+		copy(w[:], blob[2+i*WrappedSize:])
+		ck, id, err := Unwrap(device, w)
+		if err != nil || id != h.Epoch {
+			continue
+		}
+		_, p, err := envelope.Open(ck, f)
+		return p, err
+	}
+	return nil, ErrNotForUs
+}
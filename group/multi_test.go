@@ -0,0 +1,38 @@
+package group
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_SealMulti(t *testing.T) {
+	devs := []xxtea.TeaKey{
+		xxtea.NewKey([]byte("device-key-00001")),
+		xxtea.NewKey([]byte("device-key-00002")),
+		xxtea.NewKey([]byte("device-key-00003")),
+	}
+	blob, err := SealMulti([]byte("reboot at 03:00"), devs)
+	if err != nil || len(blob) < 2+3*WrappedSize {
+		t.Fatal("SealMulti failed", err)
+	}
+	for i, d := range devs {
+		if p, err := OpenMulti(d, blob); err != nil || string(p) != "reboot at 03:00" {
+			t.Error("OpenMulti failed for device", i, err)
+		}
+	}
+	if _, err := OpenMulti(xxtea.NewKey([]byte("device-key-00004")), blob); err != ErrNotForUs {
+		t.Error("Blob opened by outsider", err)
+	}
+	other, _ := SealMulti([]byte("format flash"), devs[:1])
+	spliced := append(append([]byte(nil), blob[:2+3*WrappedSize]...), other[2+WrappedSize:]...)
+	if _, err := OpenMulti(devs[0], spliced); err != ErrNotForUs {
+		t.Error("Spliced blob opened", err)
+	}
+	if _, err := OpenMulti(devs[0], blob[:40]); err != ErrBlob {
+		t.Error("Truncated blob accepted", err)
+	}
+	if _, err := SealMulti(nil, nil); err != ErrRecipients {
+		t.Error("Blob without recipients sealed", err)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package group
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ohir/xxtea"
+)
+
+// MaxDepth is the maximum number of elements of a Path.
+const MaxDepth = 16
+
+var ErrPath = errors.New("group: bad scope path")
+
+const lblPath = "group-path"
+
+// Path names a scope of a fleet, eg. a region or a sub-fleet, as a list of
+// elements from the top: "site-a/building-7/sensors".  Every element is
+// 1..64 printable ASCII characters, no space and no slash.
+//
+// Scope keys form a tree: the key of a path is derived from the key of its
+// parent, and the key of the empty path is the master key.  Whoever holds
+// the key of a scope can derive keys of all scopes under it, and none
+// above or beside it, so a broadcast can target "building-7 sensors" with
+// a key the devices there already hold or derive, instead of
+// distributing a new key to each of them.
+type Path []string
+
+// ParsePath returns the path of its slash separated string form.  The
+// empty string is the empty path.
+func ParsePath(s string) (Path, error) {
+	if s == "" {
+		return Path{}, nil
+	}
+	p := Path(strings.Split(s, "/"))
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// check returns ErrPath if p is too deep or has a bad element.
+func (p Path) check() error {
+	if len(p) > MaxDepth {
+		return ErrPath
+	}
+	for _, e := range p {
+		if len(e) == 0 || len(e) > 64 {
+			return ErrPath
+		}
+		for i := 0; i < len(e); i++ {
+			if e[i] < 0x21 || e[i] > 0x7e || e[i] == '/' {
+				return ErrPath
+			}
+		}
+	}
+	return nil
+}
+
+// String returns the slash separated form of the path.
+func (p Path) String() string {
+	return strings.Join(p, "/")
+}
+
+// Child returns the path of the element under p.
+func (p Path) Child(elem string) Path {
+	return append(p[:len(p):len(p)], elem)
+}
+
+// Contains reports whether q is p or a scope under it.
+func (p Path) Contains(q Path) bool {
+	if len(q) < len(p) {
+		return false
+	}
+	for i := range p {
+		if p[i] != q[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalBinary returns the path as a count of elements (1B) followed by
+// each element prefixed with its length (1B).
+func (p Path) MarshalBinary() ([]byte, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	b := []byte{byte(len(p))}
+	for _, e := range p {
+		b = append(append(b, byte(len(e))), e...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary sets p to the path MarshalBinary returned.
+func (p *Path) UnmarshalBinary(b []byte) error {
+	if len(b) == 0 {
+		return ErrPath
+	}
+	q := make(Path, 0, b[0])
+	n, b := int(b[0]), b[1:]
+	for i := 0; i < n; i++ {
+		if len(b) == 0 || len(b) < 1+int(b[0]) {
+			return ErrPath
+		}
+		q, b = append(q, string(b[1:1+b[0]])), b[1+b[0]:]
+	}
+	if len(b) != 0 {
+		return ErrPath
+	}
+	if err := q.check(); err != nil {
+		return err
+	}
+	*p = q
+	return nil
+}
+
+// ScopeKey returns the key of the scope at path p under the master key.
+func ScopeKey(master xxtea.TeaKey, p Path) (xxtea.TeaKey, error) {
+	return Descend(master, nil, p)
+}
+
+// Descend returns the key of the scope at path to, given the key of the
+// scope at path from.  It returns ErrPath if to is not under from.
+func Descend(k xxtea.TeaKey, from, to Path) (xxtea.TeaKey, error) {
+	if err := to.check(); err != nil {
+		return k, err
+	}
+	if !from.Contains(to) {
+		return k, ErrPath
+	}
+	for _, e := range to[len(from):] {
+		k = k.DeriveLabel(lblPath, []byte(e))
+	}
+	return k, nil
+}
@@ -0,0 +1,53 @@
+package group
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_ScopeKey(t *testing.T) {
+	mk := xxtea.NewKey([]byte(keyGRP0))
+	site, _ := ParsePath("site-a")
+	b7 := site.Child("building-7")
+	sensors := b7.Child("sensors")
+	if sensors.String() != "site-a/building-7/sensors" || len(site) != 1 {
+		t.Fatal("Child failed", sensors, site)
+	}
+	k, err := ScopeKey(mk, sensors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, _ := ScopeKey(mk, site)
+	if d, err := Descend(sk, site, sensors); err != nil || d != k {
+		t.Error("Descend from a parent scope failed", err)
+	}
+	if d, _ := ScopeKey(mk, Path{}); d != mk {
+		t.Error("Empty path is not the master key")
+	}
+	other, _ := ScopeKey(mk, b7.Child("lights"))
+	if other == k || sk == k {
+		t.Error("Scope keys not distinct")
+	}
+	if _, err := Descend(k, sensors, site); err != ErrPath {
+		t.Error("Descended to a scope above", err)
+	}
+	b, err := sensors.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p Path
+	if err := p.UnmarshalBinary(b); err != nil || p.String() != sensors.String() {
+		t.Error("Path round trip failed", p, err)
+	}
+	for _, b := range [][]byte{nil, {1}, {1, 3, 'a', 'b'}, {1, 1, 'a', 0}, {1, 1, ' '}} {
+		if err := p.UnmarshalBinary(b); err != ErrPath {
+			t.Error("Bad binary path taken", b, err)
+		}
+	}
+	for _, s := range []string{"a//b", "/a", "a b", "x/"} {
+		if _, err := ParsePath(s); err != ErrPath {
+			t.Error("Bad path taken", s, err)
+		}
+	}
+}
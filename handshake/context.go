@@ -0,0 +1,90 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package handshake
+
+import (
+	"context"
+	"io"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/internal/ctxio"
+)
+
+// Initiate runs the Initiator side of a handshake over rw, messages sent
+// as they are, and returns the directional keys of the link.  It gives up
+// when ctx is done; if rw takes deadlines, as net.Conn does, blocked reads
+// and writes are bounded by ctx too, so a peer that stalls can not hold the
+// caller longer.
+func Initiate(ctx context.Context, rw io.ReadWriter, psk xxtea.TeaKey) (c2s, s2c xxtea.TeaKey, err error) {
+	defer ctxio.Bound(ctx, ctxio.Both(rw))()
+	i := NewInitiator(psk)
+	hello, err := i.Hello()
+	if err != nil {
+		return c2s, s2c, err
+	}
+	if err = send(ctx, rw, hello); err != nil {
+		return c2s, s2c, err
+	}
+	reply := make([]byte, ReplySize)
+	if err = recv(ctx, rw, reply); err != nil {
+		return c2s, s2c, err
+	}
+	confirm, _, err := i.Finish(reply)
+	if err != nil {
+		return c2s, s2c, err
+	}
+	if err = send(ctx, rw, confirm); err != nil {
+		return c2s, s2c, err
+	}
+	return i.Keys()
+}
+
+// Respond runs the Responder side of a handshake over rw, as Initiate
+// does the other one.
+func Respond(ctx context.Context, rw io.ReadWriter, psk xxtea.TeaKey) (c2s, s2c xxtea.TeaKey, err error) {
+	defer ctxio.Bound(ctx, ctxio.Both(rw))()
+	r := NewResponder(psk)
+	hello := make([]byte, HelloSize)
+	if err = recv(ctx, rw, hello); err != nil {
+		return c2s, s2c, err
+	}
+	reply, err := r.Reply(hello)
+	if err != nil {
+		return c2s, s2c, err
+	}
+	if err = send(ctx, rw, reply); err != nil {
+		return c2s, s2c, err
+	}
+	confirm := make([]byte, ConfirmSize)
+	if err = recv(ctx, rw, confirm); err != nil {
+		return c2s, s2c, err
+	}
+	if _, err = r.Finish(confirm); err != nil {
+		return c2s, s2c, err
+	}
+	return r.Keys()
+}
+
+// send writes the message, unless ctx is done.
+func send(ctx context.Context, w io.Writer, m []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := w.Write(m)
+	return ctxio.Err(ctx, err)
+}
+
+// recv reads a message of len(m) bytes, unless ctx is done.  A stream
+// ending early is ErrMessage.
+func recv(ctx context.Context, r io.Reader, m []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := io.ReadFull(r, m)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = ErrMessage
+	}
+	return ctxio.Err(ctx, err)
+}
@@ -0,0 +1,50 @@
+package handshake
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_Context(t *testing.T) {
+	psk := xxtea.NewKey([]byte(pskBEBE))
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	type keys struct {
+		c2s, s2c xxtea.TeaKey
+		err      error
+	}
+	done := make(chan keys, 1)
+	go func() {
+		c2s, s2c, err := Respond(context.Background(), b, psk)
+		done <- keys{c2s, s2c, err}
+	}()
+	c2s, s2c, err := Initiate(context.Background(), a, psk)
+	r := <-done
+	if err != nil || r.err != nil || c2s != r.c2s || s2c != r.s2c || c2s == s2c {
+		t.Fatal("Handshake over a stream failed", err, r.err)
+	}
+
+	// a silent peer holds the Initiator for the deadline only
+	c, d := net.Pipe()
+	defer c.Close()
+	defer d.Close()
+	go func() {
+		var hello [HelloSize]byte
+		d.Read(hello[:])
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err = Initiate(ctx, c, psk); err != context.DeadlineExceeded {
+		t.Error("Stalled handshake not bounded", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, _, err = Respond(ctx, c, psk); err != context.Canceled {
+		t.Error("Cancelled handshake ran", err)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package handshake implements a tiny two-round key agreement over a
+// pre-shared xxtea.TeaKey, with mutual key confirmation.
+//
+//	Initiator                                  Responder
+//	Hello()          -- ni (16B) -->
+//	                 <-- nr (16B) | echoR (32B) -- Reply(hello)
+//	Finish(reply)    -- echoI (32B) -->
+//	                                           Finish(confirm)
+//
+// Both sides derive the session key and two confirmation keys from the
+// pre-shared key and both nonces.  Each side proves key possession by
+// sending both nonces encrypted under its own confirmation key, so echoes
+// can be neither reflected back nor replayed from an older run.
+//
+// The session key should not be used for both directions of a link, as
+// a shared key and counter space is a common source of counter reuse.
+// Keys method of either side returns it split by SessionKeys into
+// initiator-to-responder (c2s) and responder-to-initiator (s2c) keys.
+//
+// Messages are plain byte slices; transport is the caller's business.
+// Initiate and Respond run a handshake over a byte stream, bounded by a
+// context.Context.
+package handshake
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ohir/xxtea"
+)
+
+// NonceSize is the size of the nonce each side contributes.
+const NonceSize = 16
+
+// Sizes of handshake messages.
+const (
+	HelloSize   = NonceSize
+	ReplySize   = NonceSize + 2*NonceSize
+	ConfirmSize = 2 * NonceSize
+)
+
+var (
+	ErrMessage = errors.New("handshake: malformed message")
+	ErrConfirm = errors.New("handshake: key confirmation failed")
+	ErrState   = errors.New("handshake: method called out of order")
+)
+
+// Domain labels of derived keys.
+const (
+	lblKey = "hs-key"
+	lblIni = "hs-ini"
+	lblRsp = "hs-rsp"
+	lblC2S = "c2s"
+	lblS2C = "s2c"
+)
+
+// SessionKeys returns two keys of a link derived from the master key and
+// both sides' nonces: c2s for messages from client to server, and s2c for
+// the other direction.
+func SessionKeys(master xxtea.TeaKey, clientNonce, serverNonce []byte) (c2s, s2c xxtea.TeaKey) {
+	var n [2]byte
+	binary.BigEndian.PutUint16(n[:], uint16(len(clientNonce)))
+	h := sha256.New()
+	h.Write(n[:])
+	h.Write(clientNonce)
+	h.Write(serverNonce)
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return master.DeriveLabel(lblC2S, sum[:]), master.DeriveLabel(lblS2C, sum[:])
+}
+
+type state struct {
+	psk    xxtea.TeaKey
+	ni, nr [NonceSize]byte
+	step   int
+	key    xxtea.TeaKey
+	done   bool
+}
+
+// Keys returns the directional keys of a completed handshake: c2s for
+// messages from the Initiator, s2c for messages from the Responder.
+func (s *state) Keys() (c2s, s2c xxtea.TeaKey, err error) {
+	if !s.done {
+		return c2s, s2c, ErrState
+	}
+	c2s, s2c = SessionKeys(s.key, s.ni[:], s.nr[:])
+	return c2s, s2c, nil
+}
+
+// derive returns subkey of the pre-shared key for the label and both nonces.
+func (s *state) derive(label string) xxtea.TeaKey {
+	return s.psk.DeriveLabel(label, s.ni[:], s.nr[:])
+}
+
+// echo returns nonces a|b encrypted under the key.
+func echo(k xxtea.TeaKey, a, b []byte) []byte {
+	e := make([]byte, 2*NonceSize)
+	copy(e, a)
+	copy(e[NonceSize:], b)
+	return k.Encrypt(e, e)
+}
+
+// Initiator is the side that starts the handshake.
+type Initiator struct {
+	state
+}
+
+// NewInitiator returns an Initiator using the pre-shared key.
+func NewInitiator(psk xxtea.TeaKey) *Initiator {
+	return &Initiator{state{psk: psk}}
+}
+
+// Hello returns the first message to be sent to the Responder.
+func (i *Initiator) Hello() ([]byte, error) {
+	if i.step != 0 {
+		return nil, ErrState
+	}
+	if err := xxtea.ReadEntropy(i.ni[:]); err != nil {
+		return nil, err
+	}
+	i.step++
+	return append([]byte(nil), i.ni[:]...), nil
+}
+
+// Finish checks the Responder's reply.  It returns the confirmation message
+// to be sent back to the Responder and the session key.
+func (i *Initiator) Finish(reply []byte) (confirm []byte, key xxtea.TeaKey, err error) {
+	if i.step != 1 {
+		return nil, key, ErrState
+	}
+	if len(reply) != ReplySize {
+		return nil, key, ErrMessage
+	}
+	i.step++
+	copy(i.nr[:], reply)
+	exp := echo(i.derive(lblRsp), i.ni[:], i.nr[:])
+	if subtle.ConstantTimeCompare(exp, reply[NonceSize:]) != 1 {
+		return nil, key, ErrConfirm
+	}
+	i.key, i.done = i.derive(lblKey), true
+	return echo(i.derive(lblIni), i.nr[:], i.ni[:]), i.key, nil
+}
+
+// Responder is the side that answers the handshake.
+type Responder struct {
+	state
+}
+
+// NewResponder returns a Responder using the pre-shared key.
+func NewResponder(psk xxtea.TeaKey) *Responder {
+	return &Responder{state{psk: psk}}
+}
+
+// Reply takes the Initiator's hello and returns the reply to be sent back.
+func (r *Responder) Reply(hello []byte) ([]byte, error) {
+	if r.step != 0 {
+		return nil, ErrState
+	}
+	if len(hello) != HelloSize {
+		return nil, ErrMessage
+	}
+	if err := xxtea.ReadEntropy(r.nr[:]); err != nil {
+		return nil, err
+	}
+	r.step++
+	copy(r.ni[:], hello)
+	return append(append([]byte(nil), r.nr[:]...), echo(r.derive(lblRsp), r.ni[:], r.nr[:])...), nil
+}
+
+// Finish checks the Initiator's confirmation and returns the session key.
+func (r *Responder) Finish(confirm []byte) (key xxtea.TeaKey, err error) {
+	if r.step != 1 {
+		return key, ErrState
+	}
+	if len(confirm) != ConfirmSize {
+		return key, ErrMessage
+	}
+	r.step++
+	exp := echo(r.derive(lblIni), r.nr[:], r.ni[:])
+	if subtle.ConstantTimeCompare(exp, confirm) != 1 {
+		return key, ErrConfirm
+	}
+	r.key, r.done = r.derive(lblKey), true
+	return r.key, nil
+}
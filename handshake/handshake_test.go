@@ -0,0 +1,154 @@
+package handshake
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+const (
+	pskBEBE = "0123456789ABCDEF"
+	pskLELE = "FEDCBA9876543210"
+)
+
+func run(t *testing.T, ipsk, rpsk string) (ik, rk xxtea.TeaKey, ierr, rerr error) {
+	in := NewInitiator(xxtea.NewKey([]byte(ipsk)))
+	re := NewResponder(xxtea.NewKey([]byte(rpsk)))
+	hello, err := in.Hello()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := re.Reply(hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirm, ik, ierr := in.Finish(reply)
+	if ierr != nil {
+		return
+	}
+	rk, rerr = re.Finish(confirm)
+	return
+}
+
+func Test_Handshake(t *testing.T) {
+	ik, rk, ierr, rerr := run(t, pskBEBE, pskBEBE)
+	if ierr != nil || rerr != nil {
+		t.Fatal("Handshake failed:", ierr, rerr)
+	}
+	if ik != rk {
+		t.Error("Session keys differ")
+	}
+	if ik == xxtea.NewKey([]byte(pskBEBE)) {
+		t.Error("Session key equals pre-shared key")
+	}
+	ik2, _, _, _ := run(t, pskBEBE, pskBEBE)
+	if ik == ik2 {
+		t.Error("Session key repeated across runs")
+	}
+}
+
+func Test_WrongKey(t *testing.T) {
+	_, _, ierr, _ := run(t, pskBEBE, pskLELE)
+	if ierr != ErrConfirm {
+		t.Error("Initiator accepted reply made with other key")
+	}
+}
+
+func Test_BadConfirm(t *testing.T) {
+	in := NewInitiator(xxtea.NewKey([]byte(pskBEBE)))
+	re := NewResponder(xxtea.NewKey([]byte(pskBEBE)))
+	hello, _ := in.Hello()
+	reply, _ := re.Reply(hello)
+	confirm, _, err := in.Finish(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirm[5] ^= 1
+	if _, err = re.Finish(confirm); err != ErrConfirm {
+		t.Error("Responder accepted forged confirmation")
+	}
+}
+
+func Test_Reflection(t *testing.T) {
+	// responder echo sent back as initiator's confirmation must fail
+	in := NewInitiator(xxtea.NewKey([]byte(pskBEBE)))
+	re := NewResponder(xxtea.NewKey([]byte(pskBEBE)))
+	hello, _ := in.Hello()
+	reply, _ := re.Reply(hello)
+	if _, err := re.Finish(reply[NonceSize:]); err != ErrConfirm {
+		t.Error("Responder accepted its own echo")
+	}
+}
+
+func Test_Malformed(t *testing.T) {
+	in := NewInitiator(xxtea.NewKey([]byte(pskBEBE)))
+	re := NewResponder(xxtea.NewKey([]byte(pskBEBE)))
+	if _, err := re.Reply(make([]byte, HelloSize-1)); err != ErrMessage {
+		t.Error("Short hello accepted")
+	}
+	in.Hello()
+	if _, _, err := in.Finish(make([]byte, ReplySize+1)); err != ErrMessage {
+		t.Error("Long reply accepted")
+	}
+	re = NewResponder(xxtea.NewKey([]byte(pskBEBE)))
+	re.Reply(make([]byte, HelloSize))
+	if _, err := re.Finish(make([]byte, 3)); err != ErrMessage {
+		t.Error("Short confirm accepted")
+	}
+}
+
+func Test_State(t *testing.T) {
+	in := NewInitiator(xxtea.NewKey([]byte(pskBEBE)))
+	re := NewResponder(xxtea.NewKey([]byte(pskBEBE)))
+	if _, _, err := in.Finish(make([]byte, ReplySize)); err != ErrState {
+		t.Error("Initiator Finish before Hello accepted")
+	}
+	if _, err := re.Finish(make([]byte, ConfirmSize)); err != ErrState {
+		t.Error("Responder Finish before Reply accepted")
+	}
+	hello, _ := in.Hello()
+	if _, err := in.Hello(); err != ErrState {
+		t.Error("Second Hello accepted")
+	}
+	re.Reply(hello)
+	if _, err := re.Reply(hello); err != ErrState {
+		t.Error("Second Reply accepted")
+	}
+}
+
+func Test_SessionKeys(t *testing.T) {
+	m := xxtea.NewKey([]byte(pskBEBE))
+	c2s, s2c := SessionKeys(m, []byte("client"), []byte("server"))
+	if c2s == s2c || c2s == m || s2c == m {
+		t.Error("Directional keys not distinct")
+	}
+	if a, b := SessionKeys(m, []byte("client"), []byte("server")); a != c2s || b != s2c {
+		t.Error("SessionKeys not deterministic")
+	}
+	if a, _ := SessionKeys(m, []byte("clients"), []byte("erver")); a == c2s {
+		t.Error("Nonce boundary not bound")
+	}
+	if a, _ := SessionKeys(m, []byte("server"), []byte("client")); a == c2s {
+		t.Error("Nonce roles not bound")
+	}
+}
+
+func Test_Keys(t *testing.T) {
+	in := NewInitiator(xxtea.NewKey([]byte(pskBEBE)))
+	re := NewResponder(xxtea.NewKey([]byte(pskBEBE)))
+	if _, _, err := in.Keys(); err != ErrState {
+		t.Error("Keys before handshake", err)
+	}
+	hello, _ := in.Hello()
+	reply, _ := re.Reply(hello)
+	confirm, _, _ := in.Finish(reply)
+	if _, _, err := re.Keys(); err != ErrState {
+		t.Error("Responder keys before Finish", err)
+	}
+	re.Finish(confirm)
+	ic, is, ierr := in.Keys()
+	rc, rs, rerr := re.Keys()
+	if ierr != nil || rerr != nil || ic != rc || is != rs || ic == is {
+		t.Error("Directional keys differ", ierr, rerr)
+	}
+}
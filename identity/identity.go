@@ -0,0 +1,77 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package identity implements the compact device identity token: the
+// "hello" a device sends first to prove who it is and what it runs.
+//
+// Token is an envelope frame sealed under a subkey of the device key, with
+// 24 bytes of claims as its payload:
+//
+//	device id (8B) | firmware version (4B) | capabilities (4B) | nonce (8B)
+//
+// The nonce should be the server's challenge, if the server issues one,
+// or a device random otherwise.  Checking it is left to the caller.
+package identity
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+// ClaimsSize is the size of the sealed claims.
+const ClaimsSize = 24
+
+var ErrClaims = errors.New("identity: malformed claims")
+
+var lblIdent = []byte("identity")
+
+// Claims is the verified content of an identity token.
+type Claims struct {
+	DeviceID uint64
+	Firmware uint32
+	Caps     uint32 // capabilities bitmap
+	Nonce    [8]byte
+}
+
+// Has reports whether all capability bits of c are set in the claims.
+func (cl *Claims) Has(c uint32) bool {
+	return cl.Caps&c == c
+}
+
+// Seal returns the claims sealed into a token under the device key.  The
+// kid is put in clear to let the server pick the key; see KeyID.
+func Seal(key xxtea.TeaKey, kid uint16, c Claims) ([]byte, error) {
+	var b [ClaimsSize]byte
+	binary.BigEndian.PutUint64(b[:], c.DeviceID)
+	binary.BigEndian.PutUint32(b[8:], c.Firmware)
+	binary.BigEndian.PutUint32(b[12:], c.Caps)
+	copy(b[16:], c.Nonce[:])
+	return envelope.Seal(key.Derive(lblIdent), envelope.Header{KeyID: kid}, b[:])
+}
+
+// KeyID returns the key-id of the token, without verifying it.
+func KeyID(token []byte) (uint16, error) {
+	h, err := envelope.ParseHeader(token)
+	return h.KeyID, err
+}
+
+// Verify authenticates the token under the device key and returns its
+// claims.
+func Verify(key xxtea.TeaKey, token []byte) (c Claims, err error) {
+	_, b, err := envelope.Open(key.Derive(lblIdent), token)
+	if err != nil {
+		return c, err
+	}
+	if len(b) != ClaimsSize {
+		return c, ErrClaims
+	}
+	c.DeviceID = binary.BigEndian.Uint64(b)
+	c.Firmware = binary.BigEndian.Uint32(b[8:])
+	c.Caps = binary.BigEndian.Uint32(b[12:])
+	copy(c.Nonce[:], b[16:])
+	return c, nil
+}
@@ -0,0 +1,49 @@
+package identity
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+const (
+	keyBEBE = "0123456789ABCDEF"
+	keyLELE = "FEDCBA9876543210"
+)
+
+func Test_SealVerify(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	c := Claims{DeviceID: 0x0102030405060708, Firmware: 0x00010203, Caps: 5, Nonce: [8]byte{1, 2, 3}}
+	tok, err := Seal(key, 42, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid, err := KeyID(tok); err != nil || kid != 42 {
+		t.Error("KeyID failed", kid, err)
+	}
+	got, err := Verify(key, tok)
+	if err != nil || got != c {
+		t.Error("Verify failed", got, err)
+	}
+	if !got.Has(4) || !got.Has(5) || got.Has(2) {
+		t.Error("Has is broken")
+	}
+	if _, err = Verify(xxtea.NewKey([]byte(keyLELE)), tok); err != envelope.ErrOpenFailed {
+		t.Error("Token verified with other key")
+	}
+	if _, _, err = envelope.Open(key, tok); err != envelope.ErrOpenFailed {
+		t.Error("Token opened with the bare device key")
+	}
+}
+
+func Test_Malformed(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	f, _ := envelope.Seal(key.Derive(lblIdent), envelope.Header{}, make([]byte, 12))
+	if _, err := Verify(key, f); err != ErrClaims {
+		t.Error("Short claims accepted")
+	}
+	if _, err := KeyID(f[:4]); err == nil {
+		t.Error("Short token accepted")
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package btea is the corrected block TEA (XXTEA) over words, without the
+// message size limits package xxtea imposes.  It serves compatibility
+// codecs only, that must speak to software encrypting whole files.
+package btea
+
+const delta uint32 = 0x9e3779b9
+
+func mx(sum, y, z, p, e uint32, k *[4]uint32) uint32 {
+	return ((z>>5 ^ y<<2) + (y>>3 ^ z<<4)) ^ ((sum ^ y) + (k[p&3^e] ^ z))
+}
+
+// Encrypt encrypts v in place.  It does nothing for less than two words.
+func Encrypt(v []uint32, k *[4]uint32) {
+	n := uint32(len(v))
+	if n < 2 {
+		return
+	}
+	rounds := 6 + 52/n
+	var sum, y, p uint32
+	z := v[n-1]
+	for ; rounds > 0; rounds-- {
+		sum += delta
+		e := sum >> 2 & 3
+		for p = 0; p < n-1; p++ {
+			y = v[p+1]
+			v[p] += mx(sum, y, z, p, e, k)
+			z = v[p]
+		}
+		y = v[0]
+		v[n-1] += mx(sum, y, z, p, e, k)
+		z = v[n-1]
+	}
+}
+
+// Decrypt decrypts v in place.  It does nothing for less than two words.
+func Decrypt(v []uint32, k *[4]uint32) {
+	n := uint32(len(v))
+	if n < 2 {
+		return
+	}
+	rounds := 6 + 52/n
+	sum := rounds * delta
+	var z, p uint32
+	y := v[0]
+	for ; rounds > 0; rounds-- {
+		e := sum >> 2 & 3
+		for p = n - 1; p > 0; p-- {
+			z = v[p-1]
+			v[p] -= mx(sum, y, z, p, e, k)
+			y = v[p]
+		}
+		z = v[n-1]
+		v[0] -= mx(sum, y, z, p, e, k)
+		y = v[0]
+		sum -= delta
+	}
+}
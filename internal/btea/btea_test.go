@@ -0,0 +1,53 @@
+package btea
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_AgainstXXTEA(t *testing.T) {
+	kb := []byte("0123456789ABCDEF")
+	k := [4]uint32(xxtea.NewKey(kb))
+	for n := 12; n <= 208; n += 4 {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte(i * 7)
+		}
+		v := make([]uint32, n/4)
+		for i := range v {
+			v[i] = binary.BigEndian.Uint32(b[4*i:])
+		}
+		Encrypt(v, &k)
+		xxtea.NewKey(kb).Encrypt(b, b)
+		for i := range v {
+			if v[i] != binary.BigEndian.Uint32(b[4*i:]) {
+				t.Fatal("Encrypt differs from xxtea for", n)
+			}
+		}
+		Decrypt(v, &k)
+		if v[1] != 0x1c232a31 {
+			t.Fatalf("Decrypt failed for %d: %08x", n, v[1])
+		}
+	}
+}
+
+func Test_Long(t *testing.T) {
+	k := [4]uint32{1, 2, 3, 4}
+	v := make([]uint32, 1000)
+	v[999] = 42
+	Encrypt(v, &k)
+	if v[999] == 42 {
+		t.Error("Encrypt did nothing")
+	}
+	Decrypt(v, &k)
+	if v[999] != 42 || v[0] != 0 {
+		t.Error("Long round trip failed")
+	}
+	one := []uint32{7}
+	Encrypt(one, &k)
+	if one[0] != 7 {
+		t.Error("Single word changed")
+	}
+}
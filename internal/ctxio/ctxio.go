@@ -0,0 +1,78 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ctxio bounds blocking reads and writes by a context.Context.
+// Readers and writers taking deadlines, as net.Conn does, are given a
+// deadline in the past once the context is done, so a blocked call
+// returns.  Others can only be checked between calls.
+package ctxio
+
+import (
+	"context"
+	"time"
+)
+
+// Setter sets a deadline, eg. net.Conn.SetReadDeadline.
+type Setter func(time.Time) error
+
+// Read returns the read deadline setter of x, or nil if it has none.
+func Read(x interface{}) Setter {
+	if d, ok := x.(interface{ SetReadDeadline(time.Time) error }); ok {
+		return d.SetReadDeadline
+	}
+	return nil
+}
+
+// Write returns the write deadline setter of x, or nil if it has none.
+func Write(x interface{}) Setter {
+	if d, ok := x.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		return d.SetWriteDeadline
+	}
+	return nil
+}
+
+// Both returns the setter of both deadlines of x, or nil if it has none.
+func Both(x interface{}) Setter {
+	if d, ok := x.(interface{ SetDeadline(time.Time) error }); ok {
+		return d.SetDeadline
+	}
+	return nil
+}
+
+// Bound sets a past deadline with set once ctx is done, until stop is
+// called.  Stop clears the deadline if Bound set one, and leaves deadlines
+// of the caller alone otherwise.  Nil set, or a ctx that is never done,
+// bounds nothing.
+func Bound(ctx context.Context, set Setter) (stop func()) {
+	if set == nil || ctx.Done() == nil {
+		return func() {}
+	}
+	quit, done := make(chan struct{}), make(chan struct{})
+	fired := false
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			set(time.Unix(1, 0))
+			fired = true
+		case <-quit:
+		}
+	}()
+	return func() {
+		close(quit)
+		<-done
+		if fired {
+			set(time.Time{})
+		}
+	}
+}
+
+// Err returns the error of ctx if it is done, err otherwise, so timeouts
+// caused by Bound read as cancellations.
+func Err(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
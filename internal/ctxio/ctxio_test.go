@@ -0,0 +1,39 @@
+package ctxio
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Bound(t *testing.T) {
+	var mu sync.Mutex
+	var got []time.Time
+	set := func(d time.Time) error {
+		mu.Lock()
+		got = append(got, d)
+		mu.Unlock()
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	Bound(ctx, set)()
+	if len(got) != 0 {
+		t.Error("Deadline of the caller cleared", got)
+	}
+	stop := Bound(ctx, set)
+	cancel()
+	for i := 0; i < 1000; i++ {
+		mu.Lock()
+		n := len(got)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+	if len(got) != 2 || !got[0].Before(time.Now()) || !got[1].IsZero() {
+		t.Error("Deadline not set and cleared", got)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package kat reads, checks and writes known answer vectors of XXTEA, to
+// validate ports to other languages against this implementation.
+//
+// Vector files are text, one vector a line, of four fields separated by
+// white space: word order ("be" or "le"), then key, plaintext and
+// ciphertext in hex.  Empty lines and lines starting with '#' are skipped:
+//
+//	# order key plaintext ciphertext
+//	be 000102030405060708090a0b0c0d0e0f 0001...0b 42a1...c9
+//
+// With "le" the 4B words of key, plaintext and ciphertext are serialized
+// little-endian, as NewKeyLE and EncryptLE take them.  A line parses with
+// scanf("%2s %32s %416s %416s") in C.  File testdata/xxtea.kat of this
+// package holds 16 vectors of each order made by Generate with the seed
+// "xxtea-kat".
+package kat
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ohir/xxtea"
+)
+
+// Vector is a known answer of XXTEA.
+type Vector struct {
+	Order      xxtea.WordOrder
+	Key        []byte
+	Plaintext  []byte
+	Ciphertext []byte
+}
+
+var (
+	ErrFormat   = errors.New("kat: malformed vector")
+	ErrMismatch = errors.New("kat: vector does not match")
+)
+
+// New returns the vector of the plaintext encrypted under the key, both
+// with words in the order.  Keys and lengths Encrypt does not take give
+// the error of xxtea.MakeKey or TeaKey.EncryptChecked.
+func New(order xxtea.WordOrder, key, pt []byte) (Vector, error) {
+	k, err := makeKey(order, key)
+	if err != nil {
+		return Vector{}, err
+	}
+	ct := swap(order, append([]byte(nil), pt...))
+	if _, err := k.EncryptChecked(ct, ct); err != nil {
+		return Vector{}, err
+	}
+	return Vector{
+		Order:      order,
+		Key:        append([]byte(nil), key...),
+		Plaintext:  append([]byte(nil), pt...),
+		Ciphertext: swap(order, ct),
+	}, nil
+}
+
+// makeKey returns the key of bytes in the order.
+func makeKey(order xxtea.WordOrder, key []byte) (xxtea.TeaKey, error) {
+	if order > xxtea.WordsLE {
+		return xxtea.TeaKey{}, ErrFormat
+	}
+	return xxtea.MakeKey(swap(order, append([]byte(nil), key...)))
+}
+
+// swap returns b reordered from WordsLE to WordsBE, or back.  Lengths not
+// divisible by four are passed as they are, for the caller to reject.
+func swap(order xxtea.WordOrder, b []byte) []byte {
+	if order == xxtea.WordsLE && len(b)&3 == 0 {
+		xxtea.AsLEBE(b)
+	}
+	return b
+}
+
+// Check returns nil if the ciphertext is the plaintext encrypted under the
+// key, and the plaintext the ciphertext decrypted.  It returns ErrMismatch
+// if not, or the error of New for vectors Encrypt does not take.
+func (v Vector) Check() error {
+	w, err := New(v.Order, v.Key, v.Plaintext)
+	if err != nil {
+		return err
+	}
+	if string(w.Ciphertext) != string(v.Ciphertext) {
+		return ErrMismatch
+	}
+	k, _ := makeKey(v.Order, v.Key)
+	pt := swap(v.Order, append([]byte(nil), v.Ciphertext...))
+	if _, err := k.DecryptChecked(pt, pt); err != nil {
+		return err
+	}
+	if string(swap(v.Order, pt)) != string(v.Plaintext) {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// String returns the vector as a line of a vector file, without newline.
+func (v Vector) String() string {
+	o := "be"
+	if v.Order == xxtea.WordsLE {
+		o = "le"
+	}
+	return o + " " + hex.EncodeToString(v.Key) + " " +
+		hex.EncodeToString(v.Plaintext) + " " + hex.EncodeToString(v.Ciphertext)
+}
+
+// Parse returns the vector of a line of a vector file.  It returns
+// ErrFormat for lines of other than four fields, an order other than "be"
+// or "le", or fields not in hex.  The vector is not checked.
+func Parse(line string) (Vector, error) {
+	f := strings.Fields(line)
+	if len(f) != 4 {
+		return Vector{}, ErrFormat
+	}
+	var v Vector
+	switch f[0] {
+	case "be":
+	case "le":
+		v.Order = xxtea.WordsLE
+	default:
+		return Vector{}, ErrFormat
+	}
+	for i, p := range []*[]byte{&v.Key, &v.Plaintext, &v.Ciphertext} {
+		b, err := hex.DecodeString(f[i+1])
+		if err != nil {
+			return Vector{}, ErrFormat
+		}
+		*p = b
+	}
+	return v, nil
+}
+
+// Read returns the vectors of a vector file.  Malformed lines give an
+// error wrapping ErrFormat that tells the line number.
+func Read(r io.Reader) ([]Vector, error) {
+	var vs []Vector
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		v, err := Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d", err, n)
+		}
+		vs = append(vs, v)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+// Write writes the vectors as a vector file, after a comment line naming
+// the fields.
+func Write(w io.Writer, vs []Vector) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("# order key plaintext ciphertext\n")
+	for _, v := range vs {
+		b.WriteString(v.String())
+		b.WriteByte('\n')
+	}
+	return b.Flush()
+}
+
+// lengths are the message lengths Generate starts with: the shortest and
+// longest message, and those changing the number of rounds (52/n + 6).
+var lengths = []int{12, 16, 20, 24, 28, 52, 56, 100, 104, 108, 204, 208}
+
+// Generate returns count vectors in the order made from xxtea.SeededReader
+// of the seed, so the same seed gives the same vectors.  Message lengths
+// are those of edge cases first, then random.
+func Generate(seed []byte, order xxtea.WordOrder, count int) []Vector {
+	r := xxtea.NewSeededReader(seed)
+	vs := make([]Vector, 0, count)
+	for i := 0; i < count; i++ {
+		n := 0
+		if i < len(lengths) {
+			n = lengths[i]
+		} else {
+			var b [1]byte
+			r.Read(b[:])
+			n = 12 + int(b[0])%50*4
+		}
+		key, pt := make([]byte, 16), make([]byte, n)
+		r.Read(pt)
+		for {
+			r.Read(key)
+			v, err := New(order, key, pt)
+			if err == nil {
+				vs = append(vs, v)
+				break
+			}
+		}
+	}
+	return vs
+}
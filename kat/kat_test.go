@@ -0,0 +1,95 @@
+package kat
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_Shipped(t *testing.T) {
+	f, err := os.Open("testdata/xxtea.kat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	vs, err := Read(f)
+	if err != nil || len(vs) != 32 {
+		t.Fatal("Read failed", len(vs), err)
+	}
+	for i, v := range vs {
+		if err := v.Check(); err != nil {
+			t.Error("Shipped vector check failed", i, err)
+		}
+		ct := make([]byte, len(v.Plaintext))
+		if v.Order == xxtea.WordsLE {
+			xxtea.NewKeyLE(v.Key).EncryptLE(v.Plaintext, ct)
+		} else {
+			xxtea.NewKey(v.Key).Encrypt(v.Plaintext, ct)
+		}
+		if !bytes.Equal(ct, v.Ciphertext) {
+			t.Error("Shipped vector does not encrypt", i)
+		}
+	}
+	want := append(Generate([]byte("xxtea-kat"), xxtea.WordsBE, 16),
+		Generate([]byte("xxtea-kat"), xxtea.WordsLE, 16)...)
+	var b bytes.Buffer
+	Write(&b, want)
+	if got, _ := os.ReadFile("testdata/xxtea.kat"); !bytes.Equal(got, b.Bytes()) {
+		t.Error("Shipped vectors differ from Generate")
+	}
+}
+
+func Test_ReadWrite(t *testing.T) {
+	vs := Generate([]byte("seed"), xxtea.WordsLE, 20)
+	var b bytes.Buffer
+	if err := Write(&b, vs); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&b)
+	if err != nil || len(got) != len(vs) {
+		t.Fatal("Read failed", err)
+	}
+	for i := range vs {
+		if got[i].String() != vs[i].String() {
+			t.Error("Round trip failed", i)
+		}
+	}
+	if vs[0].String() == Generate([]byte("other"), xxtea.WordsLE, 1)[0].String() {
+		t.Error("Seed ignored")
+	}
+}
+
+func Test_Errors(t *testing.T) {
+	for _, line := range []string{
+		"be 00 00",
+		"xx 00 00 00",
+		"be 0g 00 00",
+		"be 00 00 00 00",
+	} {
+		if _, err := Parse(line); err != ErrFormat {
+			t.Error("Bad line accepted", line, err)
+		}
+	}
+	_, err := Read(strings.NewReader("# c\n\nbe 00\n"))
+	if !errors.Is(err, ErrFormat) || !strings.Contains(err.Error(), "line 3") {
+		t.Error("Bad file accepted", err)
+	}
+	v := Generate([]byte("seed"), xxtea.WordsBE, 1)[0]
+	v.Ciphertext[0] ^= 1
+	if err := v.Check(); err != ErrMismatch {
+		t.Error("Bad ciphertext accepted", err)
+	}
+	if _, err := New(xxtea.WordsBE, make([]byte, 16), make([]byte, 12)); err != xxtea.ErrZeroKey {
+		t.Error("Zero key accepted", err)
+	}
+	if _, err := New(xxtea.WordsLE, make([]byte, 15), make([]byte, 12)); err != xxtea.ErrKeyLen {
+		t.Error("Short key accepted", err)
+	}
+	if _, err := New(xxtea.WordsBE, []byte("0123456789abcdef"), make([]byte, 13)); err != xxtea.ErrMsgAlign {
+		t.Error("Unaligned message accepted", err)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keyring
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+var (
+	ErrFormat     = errors.New("keyring: malformed keyring file")
+	ErrPassphrase = errors.New("keyring: file is protected by a passphrase")
+	ErrUnwrap     = errors.New("keyring: key does not open under the KEK")
+)
+
+// PassphraseIter is the PBKDF2-HMAC-SHA256 iteration count of passphrase
+// protected files written.  Files keep the count they were written with.
+var PassphraseIter = 600000
+
+const lblWrap = "keyring-wrap"
+
+// file is the keyring file, JSON encoded.  Keys are envelope frames with
+// the key-id and the activation time (as the epoch, Unix seconds) in the
+// header, and the key as the payload.
+type file struct {
+	V    int      `json:"v"`
+	Salt []byte   `json:"salt,omitempty"` // passphrase salt
+	Iter int      `json:"iter,omitempty"` // passphrase iterations
+	Keys [][]byte `json:"keys"`
+}
+
+// Save writes the keyring to the file at path, keys wrapped under the KEK.
+// The file is replaced atomically.
+func Save(path string, r *Keyring, kek xxtea.TeaKey) error {
+	return save(path, r, kek, file{V: 1})
+}
+
+// SavePassphrase is Save under a KEK derived from the passphrase and
+// a random salt.
+func SavePassphrase(path string, r *Keyring, passphrase string) error {
+	f := file{V: 1, Salt: make([]byte, 16), Iter: PassphraseIter}
+	if err := xxtea.ReadEntropy(f.Salt); err != nil {
+		return err
+	}
+	kek, err := passphraseKEK(passphrase, f.Salt, f.Iter)
+	if err != nil {
+		return err
+	}
+	return save(path, r, kek, f)
+}
+
+// Load reads the keyring from the file at path, unwrapping keys under the
+// KEK.  It returns ErrUnwrap if a key does not open under it.
+func Load(path string, kek xxtea.TeaKey) (*Keyring, error) {
+	f, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	if f.Salt != nil {
+		return nil, ErrPassphrase
+	}
+	return unwrap(f, kek)
+}
+
+// LoadPassphrase is Load of a file written by SavePassphrase.
+func LoadPassphrase(path string, passphrase string) (*Keyring, error) {
+	f, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Salt) == 0 || f.Iter <= 0 {
+		return nil, ErrFormat
+	}
+	kek, err := passphraseKEK(passphrase, f.Salt, f.Iter)
+	if err != nil {
+		return nil, err
+	}
+	return unwrap(f, kek)
+}
+
+func save(path string, r *Keyring, kek xxtea.TeaKey, f file) error {
+	wk := kek.DeriveLabel(lblWrap)
+	for _, e := range r.Entries() {
+		h := envelope.Header{KeyID: e.KeyID, Epoch: uint32(e.Activate.Unix())}
+		fr, err := envelope.Seal(wk, h, e.Key.Bytes())
+		if err != nil {
+			return err
+		}
+		f.Keys = append(f.Keys, fr)
+	}
+	b, err := json.MarshalIndent(&f, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, append(b, '\n'))
+}
+
+// writeFile replaces the file at path with b: it writes a temporary file
+// in the same directory, syncs it and renames it over the old one.
+func writeFile(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	if d, err := os.Open(filepath.Dir(path)); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+func read(path string) (f file, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return f, err
+	}
+	if json.Unmarshal(b, &f) != nil || f.V != 1 {
+		return f, ErrFormat
+	}
+	return f, nil
+}
+
+func unwrap(f file, kek xxtea.TeaKey) (*Keyring, error) {
+	wk := kek.DeriveLabel(lblWrap)
+	r := New()
+	for _, fr := range f.Keys {
+		h, p, err := envelope.Open(wk, fr)
+		if err != nil {
+			return nil, ErrUnwrap
+		}
+		if len(p) != 16 || binary.BigEndian.Uint64(p)|binary.BigEndian.Uint64(p[8:]) == 0 {
+			return nil, ErrFormat
+		}
+		if err = r.add(Entry{h.KeyID, xxtea.NewKey(p), time.Unix(int64(h.Epoch), 0)}); err != nil {
+			return nil, ErrFormat
+		}
+	}
+	return r, nil
+}
+
+// passphraseKEK returns the KEK of the passphrase: the first 16 bytes of
+// PBKDF2-HMAC-SHA256 of it.
+func passphraseKEK(passphrase string, salt []byte, iter int) (xxtea.TeaKey, error) {
+	if iter <= 0 {
+		return xxtea.TeaKey{}, ErrFormat
+	}
+	return xxtea.NewKeyFromPassphrase([]byte(passphrase), salt, xxtea.PassphraseParams{Iterations: iter})
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package keyring keeps the keys of a gateway through their lifecycle:
+// key-ids, keys and the times they become active, with Rotate scheduling
+// a new key and Save and Load keeping the keyring in a file.  Seal seals
+// frames under the current key, and Open opens them under the key of their
+// key-id, so frames sealed before a rotation open after it.
+//
+// Keys never touch the disk in clear.  Each of them is sealed as an
+// envelope frame under a subkey of a key-encryption key (KEK), given by the
+// caller or derived from a passphrase, with its key-id and activation time
+// in the authenticated header, so entries can be neither altered nor
+// swapped.  Files are written to a temporary file first and renamed over
+// the old one, so a crash leaves either the old keyring or the new one.
+package keyring
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ohir/xxtea"
+)
+
+var (
+	ErrKeyID = errors.New("keyring: key-id already in keyring")
+	ErrFull  = errors.New("keyring: no key-id left")
+)
+
+// Entry is a key of the keyring.
+type Entry struct {
+	KeyID    uint16
+	Key      xxtea.TeaKey
+	Activate time.Time // when the key becomes current, to a second in files
+}
+
+// Keyring is a set of keys by key-id.  It implements envelope.Keyring.
+//
+// Keyring is safe for concurrent use.
+type Keyring struct {
+	mu      sync.RWMutex
+	entries []Entry // by Activate, then KeyID
+}
+
+// New returns an empty Keyring.
+func New() *Keyring {
+	return &Keyring{}
+}
+
+// Add adds the entry.  It returns ErrKeyID if its key-id is taken.
+func (r *Keyring) Add(e Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.add(e)
+}
+
+func (r *Keyring) add(e Entry) error {
+	for _, o := range r.entries {
+		if o.KeyID == e.KeyID {
+			return ErrKeyID
+		}
+	}
+	r.entries = append(r.entries, e)
+	sort.SliceStable(r.entries, func(i, j int) bool {
+		a, b := &r.entries[i], &r.entries[j]
+		return a.Activate.Before(b.Activate) || a.Activate.Equal(b.Activate) && a.KeyID < b.KeyID
+	})
+	return nil
+}
+
+// Rotate schedules the new key to become current at the given time, under
+// the key-id following the highest one in the keyring, and returns that
+// key-id.  Older keys stay in the keyring to open frames still in flight
+// until they are removed with Remove.
+func (r *Keyring) Rotate(newKey xxtea.TeaKey, at time.Time) (uint16, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kid := 0
+	for _, e := range r.entries {
+		if int(e.KeyID) >= kid {
+			kid = int(e.KeyID) + 1
+		}
+	}
+	if kid > 1<<16-1 {
+		return 0, ErrFull
+	}
+	return uint16(kid), r.add(Entry{uint16(kid), newKey, at})
+}
+
+// Remove removes the key of the key-id, if any.
+func (r *Keyring) Remove(kid uint16) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, e := range r.entries {
+		if e.KeyID == kid {
+			r.entries = append(r.entries[:i], r.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// Current returns the entry activated last, by the xxtea Clock.  It is
+// false if no key is active yet.
+func (r *Keyring) Current() (Entry, bool) {
+	now := xxtea.Now()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if !r.entries[i].Activate.After(now) {
+			return r.entries[i], true
+		}
+	}
+	return Entry{}, false
+}
+
+// Key implements envelope.Keyring.  Keys are per key-id, of any epoch, and
+// are returned whether active yet or not.
+func (r *Keyring) Key(kid uint16, epoch uint32) (xxtea.TeaKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, e := range r.entries {
+		if e.KeyID == kid {
+			return e.Key, true
+		}
+	}
+	return xxtea.TeaKey{}, false
+}
+
+// Entries returns all entries, by activation time.
+func (r *Keyring) Entries() []Entry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Entry(nil), r.entries...)
+}
@@ -0,0 +1,136 @@
+package keyring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+var (
+	keyA = xxtea.NewKey([]byte("0123456789ABCDEF"))
+	keyB = xxtea.NewKey([]byte("FEDCBA9876543210"))
+	kek  = xxtea.NewKey([]byte("KeyEncryptionKey"))
+)
+
+func Test_Rotate(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	xxtea.SetClock(xxtea.ClockFunc(func() time.Time { return t0 }))
+	defer xxtea.SetClock(nil)
+	r := New()
+	if _, ok := r.Current(); ok {
+		t.Error("Empty keyring has a current key")
+	}
+	r.Add(Entry{KeyID: 7, Key: keyA, Activate: t0.Add(-time.Hour)})
+	if err := r.Add(Entry{KeyID: 7, Key: keyB}); err != ErrKeyID {
+		t.Error("Duplicate key-id added", err)
+	}
+	kid, err := r.Rotate(keyB, t0.Add(time.Hour))
+	if err != nil || kid != 8 {
+		t.Fatal("Rotate failed", kid, err)
+	}
+	if e, ok := r.Current(); !ok || e.KeyID != 7 {
+		t.Error("Scheduled key current early", e)
+	}
+	xxtea.SetClock(xxtea.ClockFunc(func() time.Time { return t0.Add(time.Hour) }))
+	if e, ok := r.Current(); !ok || e.KeyID != 8 || e.Key != keyB {
+		t.Error("Rotated key not current", e)
+	}
+	var kr envelope.Keyring = r
+	if k, ok := kr.Key(7, 0); !ok || k != keyA {
+		t.Error("Old key lost")
+	}
+	r.Remove(7)
+	if _, ok := r.Key(7, 0); ok || len(r.Entries()) != 1 {
+		t.Error("Key not removed")
+	}
+}
+
+func Test_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	r := New()
+	r.Add(Entry{KeyID: 1, Key: keyA, Activate: time.Unix(1700000000, 0)})
+	r.Rotate(keyB, time.Unix(1700086400, 0))
+	if err := Save(path, r, kek); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Load(path, kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if es, ws := g.Entries(), r.Entries(); len(es) != 2 || es[0] != ws[0] || es[1].KeyID != 2 || !es[1].Activate.Equal(ws[1].Activate) {
+		t.Error("Keyring not restored", es)
+	}
+	if _, err := Load(path, keyA); err != ErrUnwrap {
+		t.Error("Loaded under wrong KEK", err)
+	}
+	if m, _ := filepath.Glob(path + ".tmp*"); len(m) != 0 {
+		t.Error("Temporary file left", m)
+	}
+	b, _ := os.ReadFile(path)
+	b[len(b)/2] ^= 1
+	os.WriteFile(path, b, 0o600)
+	if _, err := Load(path, kek); err == nil {
+		t.Error("Altered file loaded")
+	}
+
+	PassphraseIter = 1000
+	defer func() { PassphraseIter = 600000 }()
+	if err := SavePassphrase(path, r, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if g, err := LoadPassphrase(path, "correct horse"); err != nil || len(g.Entries()) != 2 {
+		t.Error("LoadPassphrase failed", err)
+	}
+	if _, err := LoadPassphrase(path, "battery staple"); err != ErrUnwrap {
+		t.Error("Loaded with wrong passphrase", err)
+	}
+	if _, err := Load(path, kek); err != ErrPassphrase {
+		t.Error("Passphrase file loaded with a KEK", err)
+	}
+}
+
+func Test_SealOpen(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	xxtea.SetClock(xxtea.ClockFunc(func() time.Time { return t0 }))
+	defer xxtea.SetClock(nil)
+	r := New()
+	if _, err := r.Seal(envelope.Header{}, []byte("reading")); err != ErrNoCurrent {
+		t.Error("Sealed without a current key", err)
+	}
+	r.Add(Entry{KeyID: 7, Key: keyA, Activate: t0.Add(-time.Hour)})
+	r.Rotate(keyB, t0.Add(time.Hour))
+	old, err := r.Seal(envelope.Header{KeyID: 99, Counter: 1}, []byte("reading"))
+	if err != nil {
+		t.Fatal("Seal failed", err)
+	}
+	xxtea.SetClock(xxtea.ClockFunc(func() time.Time { return t0.Add(time.Hour) }))
+	cur, _ := r.Seal(envelope.Header{Counter: 2}, []byte("another reading"))
+	for _, tc := range []struct {
+		f   []byte
+		kid uint16
+		p   string
+	}{{old, 7, "reading"}, {cur, 8, "another reading"}} {
+		h, p, err := r.Open(tc.f)
+		if err != nil || h.KeyID != tc.kid || string(p) != tc.p {
+			t.Error("Open failed", tc.kid, h.KeyID, err)
+		}
+	}
+	if p, _ := envelope.OpenPadded(keyA, old); string(p) != "reading" {
+		t.Error("Frame not sealed under the key of its key-id")
+	}
+	cur[len(cur)-1] ^= 1
+	if _, _, err = r.Open(cur); err != envelope.ErrOpenFailed {
+		t.Error("Forged frame opened", err)
+	}
+	if _, _, err = r.Open(cur[:3]); err != envelope.ErrOpenFailed {
+		t.Error("Short frame opened", err)
+	}
+	r.Remove(7)
+	if _, _, err = r.Open(old); err != envelope.ErrNoKey {
+		t.Error("Frame of a removed key opened", err)
+	}
+}
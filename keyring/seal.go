@@ -0,0 +1,46 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package keyring
+
+import (
+	"errors"
+
+	"github.com/ohir/xxtea/envelope"
+)
+
+var ErrNoCurrent = errors.New("keyring: no key active yet")
+
+// Seal seals the payload under the current key as envelope.SealPadded
+// does, with the key-id of that key set in the header, so Open of a
+// keyring holding it finds the key after rotation.  It returns
+// ErrNoCurrent if no key is active yet.
+func (r *Keyring) Seal(h envelope.Header, payload []byte) ([]byte, error) {
+	e, ok := r.Current()
+	if !ok {
+		return nil, ErrNoCurrent
+	}
+	h.KeyID = e.KeyID
+	return envelope.SealPadded(e.Key, h, payload)
+}
+
+// Open opens a frame of Seal, or of envelope.SealPadded under any key of
+// the keyring, active yet or not, picked by the key-id in its header.  It
+// returns envelope.ErrNoKey for key-ids not in the keyring, and
+// envelope.ErrOpenFailed for every other failure.
+func (r *Keyring) Open(frame []byte) (envelope.Header, []byte, error) {
+	h, err := envelope.ParseHeader(frame)
+	if err != nil {
+		return envelope.Header{}, nil, envelope.ErrOpenFailed
+	}
+	k, ok := r.Key(h.KeyID, h.Epoch)
+	if !ok {
+		return envelope.Header{}, nil, envelope.ErrNoKey
+	}
+	p, err := envelope.OpenPadded(k, frame)
+	if err != nil {
+		return envelope.Header{}, nil, err
+	}
+	return h, p, nil
+}
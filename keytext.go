@@ -0,0 +1,136 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/bits"
+)
+
+var (
+	// ErrKeyText is returned for key text that is not a key.  All-zeros
+	// keys are ErrZeroKey.
+	ErrKeyText = errors.New("xxtea: key must be 32 hex digits or 16 bytes in base64")
+	// ErrKeyTag is returned by UnmarshalText for text without a byte order
+	// tag.
+	ErrKeyTag = errors.New("xxtea: key text must start with be: or le:")
+)
+
+// inRange returns -1 (all bits set) if lo <= x <= hi, and 0 otherwise,
+// without branching on x.
+func inRange(x, lo, hi int32) int32 {
+	return ((lo - 1 - x) & (x - hi - 1)) >> 31
+}
+
+// keyOf returns the key of b, or ErrKeyText if bad is not zero.  Whether
+// the key is all zeros is found without branching on key bytes.
+func keyOf(b *[16]byte, bad int32) (k TeaKey, err error) {
+	var c byte
+	for _, x := range b {
+		c |= x
+	}
+	if bad != 0 {
+		err = ErrKeyText
+	} else if c == 0 {
+		err = ErrZeroKey
+	} else {
+		k = NewKey(b[:])
+	}
+	for i := range b {
+		b[i] = 0
+	}
+	return k, err
+}
+
+// ParseKeyHex returns the key given as 32 hex digits, of either case.
+//
+// Unlike encoding/hex it takes time independent of the digits, with no
+// table lookups or branches on them, so services taking keys over
+// management APIs do not leak key bytes through timing.  Only the length
+// of s and whether it is a valid key tell on the time taken.  Surrounding
+// white space is not allowed; trim it before.
+func ParseKeyHex(s string) (TeaKey, error) {
+	if len(s) != 32 {
+		return TeaKey{}, ErrKeyText
+	}
+	var b [16]byte
+	var bad int32
+	for i := 0; i < 32; i++ {
+		x := int32(s[i])
+		d, u, l := inRange(x, '0', '9'), inRange(x, 'A', 'F'), inRange(x, 'a', 'f')
+		v := d&(x-'0') | u&(x-'A'+10) | l&(x-'a'+10)
+		bad |= ^(d | u | l)
+		b[i>>1] |= byte(v << (4 * (1 - i&1)))
+	}
+	return keyOf(&b, bad)
+}
+
+// ParseKeyBase64 returns the key given as 16 bytes in standard base64,
+// with or without padding.  It takes time independent of the key, as
+// ParseKeyHex does.
+func ParseKeyBase64(s string) (TeaKey, error) {
+	switch {
+	case len(s) == 24 && s[22:] == "==":
+		s = s[:22]
+	case len(s) != 22:
+		return TeaKey{}, ErrKeyText
+	}
+	var b [16]byte
+	var bad int32
+	var acc uint32
+	n, j := 0, 0
+	for i := 0; i < 22; i++ {
+		x := int32(s[i])
+		u, l, d := inRange(x, 'A', 'Z'), inRange(x, 'a', 'z'), inRange(x, '0', '9')
+		p, sl := inRange(x, '+', '+'), inRange(x, '/', '/')
+		v := u&(x-'A') | l&(x-'a'+26) | d&(x-'0'+52) | p&62 | sl&63
+		bad |= ^(u | l | d | p | sl)
+		acc, n = acc<<6|uint32(v), n+6
+		if n >= 8 {
+			n -= 8
+			b[j] = byte(acc >> uint(n))
+			j++
+		}
+	}
+	bad |= int32(acc & 0xf) // the 4 bits left over must be zero
+	return keyOf(&b, bad)
+}
+
+// TeaKey.MarshalText returns the key as "be:" and 32 hex digits of its
+// big-endian bytes, so keys can live in JSON or YAML configs.  The text
+// is the secret key itself; fmt prints keys redacted, MarshalText does
+// not.
+func (k TeaKey) MarshalText() ([]byte, error) {
+	b := make([]byte, 35)
+	copy(b, "be:")
+	hex.Encode(b[3:], k.Bytes())
+	return b, nil
+}
+
+// TeaKey.UnmarshalText sets the key from text of MarshalText, or from
+// "le:" and 32 hex digits of bytes holding four little-endian words, as
+// NewKeyLE takes them.  It returns ErrKeyTag for text of no tag, and as
+// ParseKeyHex does ErrKeyText or ErrZeroKey, leaving the key unchanged.
+func (k *TeaKey) UnmarshalText(text []byte) error {
+	if len(text) < 3 {
+		return ErrKeyTag
+	}
+	tag := string(text[:3])
+	if tag != "be:" && tag != "le:" {
+		return ErrKeyTag
+	}
+	x, err := ParseKeyHex(string(text[3:]))
+	if err != nil {
+		return err
+	}
+	if tag == "le:" {
+		for i := range x {
+			x[i] = bits.ReverseBytes32(x[i])
+		}
+	}
+	*k = x
+	return nil
+}
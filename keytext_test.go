@@ -0,0 +1,77 @@
+package xxtea
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_ParseKeyText(t *testing.T) {
+	b := make(bs, 16)
+	for i := 0; i < 200; i++ {
+		rand.Read(b)
+		want := NewKey(b)
+		h := hex.EncodeToString(b)
+		for _, s := range []string{h, strings.ToUpper(h)} {
+			if k, err := ParseKeyHex(s); err != nil || k != want {
+				t.Fatal("ParseKeyHex failed", s, err)
+			}
+		}
+		for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding} {
+			s := enc.EncodeToString(b)
+			if k, err := ParseKeyBase64(s); err != nil || k != want {
+				t.Fatal("ParseKeyBase64 failed", s, err)
+			}
+		}
+	}
+	for _, s := range []string{"", "0123456789abcdef0123456789abcde", "0123456789abcdef0123456789abcdeg",
+		" 123456789abcdef0123456789abcdef", "0123456789abcdef0123456789abcde:"} {
+		if _, err := ParseKeyHex(s); err != ErrKeyText {
+			t.Error("Bad hex taken", s, err)
+		}
+	}
+	for _, s := range []string{"", "AAECAwQFBgcICQoLDA0ODw=", "AAECAwQFBgcICQoLDA0ODx==", "AAECAwQFBgcICQoLDA0OD-==", "AAECAwQFBgcICQoLDA0ODw=A"} {
+		if _, err := ParseKeyBase64(s); err != ErrKeyText {
+			t.Error("Bad base64 taken", s, err)
+		}
+	}
+	if _, err := ParseKeyHex(strings.Repeat("0", 32)); err != ErrZeroKey {
+		t.Error("Zero hex key taken", err)
+	}
+	if _, err := ParseKeyBase64("AAAAAAAAAAAAAAAAAAAAAA=="); err != ErrZeroKey {
+		t.Error("Zero base64 key taken", err)
+	}
+}
+
+func Test_MarshalText(t *testing.T) {
+	k := NewKey([]byte(keyBEBE))
+	b, err := json.Marshal(struct{ Key TeaKey }{k})
+	if err != nil || string(b) != `{"Key":"be:`+hex.EncodeToString([]byte(keyBEBE))+`"}` {
+		t.Error("MarshalText failed", string(b), err)
+	}
+	var c struct{ Key TeaKey }
+	if err := json.Unmarshal(b, &c); err != nil || c.Key != k {
+		t.Error("UnmarshalText failed", err)
+	}
+	le := "le:" + hex.EncodeToString([]byte(keyBEBE))
+	if err := c.Key.UnmarshalText([]byte(le)); err != nil || c.Key != NewKeyLE([]byte(keyBEBE)) {
+		t.Error("UnmarshalText of le: failed", err)
+	}
+	for _, tc := range []struct {
+		s   string
+		err error
+	}{
+		{hex.EncodeToString([]byte(keyBEBE)), ErrKeyTag},
+		{"xx:" + hex.EncodeToString([]byte(keyBEBE)), ErrKeyTag},
+		{"", ErrKeyTag},
+		{"be:0123", ErrKeyText},
+		{"le:" + strings.Repeat("0", 32), ErrZeroKey},
+	} {
+		if err := c.Key.UnmarshalText([]byte(tc.s)); err != tc.err || c.Key != NewKeyLE([]byte(keyBEBE)) {
+			t.Error("Bad key text accepted", tc.s, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import "encoding/binary"
+
+// NewKeyLE returns the key of 16 bytes holding four little-endian words,
+// as a typical LE microcontroller dumps its key array.  It is
+// NewKey(AsLEBE(key)) without juggling a copy, and panics as NewKey does.
+func NewKeyLE(key []byte) (k TeaKey) {
+	if len(key) != 16 {
+		panic(ErrKeyLen)
+	}
+	for n := 0; n < 16; n += 4 {
+		k[n>>2] = binary.LittleEndian.Uint32(key[n:])
+	}
+	if k == (TeaKey{}) {
+		panic(ErrZeroKey)
+	}
+	return
+}
+
+// TeaKey.EncryptLE is Encrypt over little-endian words: 'in' is read and
+// 'out' written as a LE peer holds its uint32 array in memory.  It gives
+// AsLEBE(k.Encrypt(AsLEBE(in), out)) without juggling.  It returns the
+// same 'out' slice it has got.
+//
+// Slices must be the same length in 12..208 range, in multiples of four.
+// Both arguments can be the same slice.
+func (k TeaKey) EncryptLE(in, out []byte) []byte {
+	if err := chkMsg(len(in), len(out)); err != nil {
+		panic(err)
+	}
+	var v [52]uint32
+	n := len(in) / 4
+	for i := range v[:n] {
+		v[i] = binary.LittleEndian.Uint32(in[4*i:])
+	}
+	k.encrypt(v[:n])
+	for i, w := range v[:n] {
+		binary.LittleEndian.PutUint32(out[4*i:], w)
+	}
+	return out
+}
+
+// TeaKey.DecryptLE is the inverse of EncryptLE.
+func (k TeaKey) DecryptLE(in, out []byte) []byte {
+	if err := chkMsg(len(in), len(out)); err != nil {
+		panic(err)
+	}
+	var v [52]uint32
+	n := len(in) / 4
+	for i := range v[:n] {
+		v[i] = binary.LittleEndian.Uint32(in[4*i:])
+	}
+	k.decrypt(v[:n])
+	for i, w := range v[:n] {
+		binary.LittleEndian.PutUint32(out[4*i:], w)
+	}
+	return out
+}
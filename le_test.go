@@ -0,0 +1,40 @@
+package xxtea
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_LE(t *testing.T) {
+	kb := []byte(keyBEBE)
+	k := NewKeyLE(kb)
+	if k != NewKey(AsLEBE(append(bs(nil), kb...))) {
+		t.Error("NewKeyLE differs from NewKey of AsLEBE")
+	}
+	for n := 12; n <= 208; n += 4 {
+		pt := []byte(msgMax)[:n]
+		want := AsLEBE(k.Encrypt(AsLEBE(append(bs(nil), pt...)), make(bs, n)))
+		ct := k.EncryptLE(pt, make(bs, n))
+		if !bytes.Equal(ct, want) {
+			t.Error("EncryptLE differs from juggled Encrypt", n)
+		}
+		if !bytes.Equal(k.DecryptLE(ct, ct), pt) {
+			t.Error("DecryptLE failed", n)
+		}
+	}
+	for _, f := range []func(){
+		func() { NewKeyLE(make(bs, 15)) },
+		func() { NewKeyLE(make(bs, 16)) },
+		func() { k.EncryptLE(make(bs, 14), make(bs, 14)) },
+		func() { k.DecryptLE(make(bs, 12), make(bs, 16)) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("LE misuse should panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+// TeaKey.EncryptLegacyBTEA encrypts with the original Block TEA of "Tea
+// extensions" (1997), before the "Correction to xtea" made it XXTEA: each
+// word is mixed with its predecessor only, in C:
+//
+//	z = v[p] += (z<<4 ^ z>>5) + z ^ k[p&3^e] + sum
+//
+// which Saarinen's 1998 attack breaks with chosen plaintexts.  Output
+// differs from Encrypt.  It returns the same 'out' slice it has got.
+//
+// Use it only to read and write data of devices built against that
+// routine.  Words are big-endian as for Encrypt.  Slices must be the same
+// length in 8..208 range, in multiples of four; the original takes two
+// words.
+func (k TeaKey) EncryptLegacyBTEA(in, out []byte) []byte {
+	chkBTEA(in, out)
+	v, n := loadWords(in)
+	var sum uint32
+	z := v[n-1]
+	for q := 6 + 52/n; q > 0; q-- {
+		sum += delta
+		e := (sum >> 2) & 3
+		for p := 0; p < n; p++ {
+			v[p] += ((z<<4 ^ z>>5) + z) ^ (k[uint32(p)&3^e] + sum)
+			z = v[p]
+		}
+	}
+	return storeWords(v[:n], out)
+}
+
+// TeaKey.DecryptLegacyBTEA is the inverse of EncryptLegacyBTEA.
+func (k TeaKey) DecryptLegacyBTEA(in, out []byte) []byte {
+	chkBTEA(in, out)
+	v, n := loadWords(in)
+	q := 6 + 52/n
+	sum := uint32(q) * delta
+	for ; q > 0; q-- {
+		e := (sum >> 2) & 3
+		for p := n - 1; p > 0; p-- {
+			z := v[p-1]
+			v[p] -= ((z<<4 ^ z>>5) + z) ^ (k[uint32(p)&3^e] + sum)
+		}
+		z := v[n-1]
+		v[0] -= ((z<<4 ^ z>>5) + z) ^ (k[e] + sum)
+		sum -= delta
+	}
+	return storeWords(v[:n], out)
+}
+
+// chkBTEA panics on lengths Block TEA can not take: those of Encrypt and
+// two words.
+func chkBTEA(in, out []byte) {
+	if len(in) != 8 || len(out) != 8 {
+		if err := chkMsg(len(in), len(out)); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// loadWords returns the big-endian words of in, of checked length.
+func loadWords(in []byte) (v [52]uint32, n int) {
+	n = len(in) / 4
+	for i := 0; i < len(in); i += 4 {
+		v[i>>2] = uint32(in[i+3]) | uint32(in[i+2])<<8 | // from bytes
+			uint32(in[i+1])<<16 | uint32(in[i])<<24
+	}
+	return
+}
+
+// storeWords writes words v to out, big-endian, and returns out.
+func storeWords(v []uint32, out []byte) []byte {
+	for i, w := range v { // to bytes
+		out[4*i+3], out[4*i+2], out[4*i+1], out[4*i] = byte(w), byte(w>>8), byte(w>>16), byte(w>>24)
+	}
+	return out
+}
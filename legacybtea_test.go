@@ -0,0 +1,75 @@
+package xxtea
+
+import (
+	"bytes"
+	"testing"
+)
+
+// refBlockTEA is btea of "Tea extensions" over n words, with C precedence
+// of + over ^ spelled out.
+func refBlockTEA(v []uint32, n int, k TeaKey) {
+	var z, sum, e uint32
+	var p, q int
+	if n > 1 { // Coding Part
+		z = v[n-1]
+		q = 6 + 52/n
+		for ; q > 0; q-- {
+			sum += delta
+			e = sum >> 2 & 3
+			for p = 0; p < n; p++ {
+				v[p] += ((z<<4 ^ z>>5) + z) ^ (k[p&3^int(e)] + sum)
+				z = v[p]
+			}
+		}
+	} else if n < -1 { // Decoding Part
+		n = -n
+		q = 6 + 52/n
+		sum = uint32(q) * delta
+		for sum != 0 {
+			e = sum >> 2 & 3
+			for p = n - 1; p > 0; p-- {
+				z = v[p-1]
+				v[p] -= ((z<<4 ^ z>>5) + z) ^ (k[p&3^int(e)] + sum)
+			}
+			z = v[n-1]
+			v[0] -= ((z<<4 ^ z>>5) + z) ^ (k[p&3^int(e)] + sum)
+			sum -= delta
+		}
+	}
+}
+
+func Test_LegacyBTEA(t *testing.T) {
+	k := NewKey([]byte(keyBEBE))
+	for n := 8; n <= 208; n += 4 {
+		pt := []byte(msgMax)[:n]
+		ct := k.EncryptLegacyBTEA(pt, make(bs, n))
+		v := make([]uint32, n/4)
+		for i := range v {
+			v[i] = uint32(pt[4*i])<<24 | uint32(pt[4*i+1])<<16 | uint32(pt[4*i+2])<<8 | uint32(pt[4*i+3])
+		}
+		refBlockTEA(v, n/4, k)
+		if !bytes.Equal(storeWords(v, make(bs, n)), ct) {
+			t.Error("EncryptLegacyBTEA differs from reference", n)
+		}
+		refBlockTEA(v, -n/4, k)
+		if !bytes.Equal(storeWords(v, make(bs, n)), pt) {
+			t.Error("Reference decoding failed", n)
+		}
+		if n >= 12 && bytes.Equal(ct, k.Encrypt(pt, make(bs, n))) {
+			t.Error("EncryptLegacyBTEA is XXTEA", n)
+		}
+		if !bytes.Equal(k.DecryptLegacyBTEA(ct, ct), pt) {
+			t.Error("DecryptLegacyBTEA failed", n)
+		}
+	}
+	for _, n := range []int{4, 13, 212} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("EncryptLegacyBTEA misuse should panic", n)
+				}
+			}()
+			k.EncryptLegacyBTEA(make(bs, n), make(bs, n))
+		}()
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+// KeyPadPolicy tells how NewKeyLegacy expands keys shorter than 16 bytes.
+type KeyPadPolicy uint8
+
+const (
+	KeyPadZero   KeyPadPolicy = iota // append zero bytes
+	KeyPadRepeat                     // repeat key bytes from the start
+)
+
+// NewKeyLegacy expands a 1 to 16 bytes long key to 16 bytes the way some
+// Arduino-era libraries do, then returns it as NewKey would.
+//
+// WARNING: short keys are weak keys.  A 4 byte key is a 32-bit key no
+// matter how it is expanded, and is found by brute force in minutes.  Use
+// NewKeyLegacy only to talk to fielded devices that can not be reflashed.
+//
+// Libraries reading key bytes as little-endian words need the expanded key
+// juggled: NewKey(AsLEBE(NewKeyLegacy(key, policy).Bytes())).
+func NewKeyLegacy(key []byte, policy KeyPadPolicy) TeaKey {
+	if len(key) == 0 || len(key) > 16 {
+		panic(ErrKeyLen)
+	}
+	if policy > KeyPadRepeat {
+		panic(ErrMisuse)
+	}
+	var b [16]byte
+	n := copy(b[:], key)
+	if policy == KeyPadRepeat {
+		for ; n < 16; n += len(key) {
+			copy(b[n:], key)
+		}
+	}
+	return NewKey(b[:])
+}
+
+// NewKeyCompat prepares a passphrase the way the popular xxtea-js, PHP and
+// similar libraries do: its UTF-8 bytes are zero padded or truncated to 16
+// bytes, then read as four little-endian words.  The same passphrase gives
+// the same key here as there.
+//
+// WARNING: this is no key derivation; passphrases of such libraries are
+// weak keys.  Empty passphrase panics, as an all-zeros key does.
+func NewKeyCompat(passphrase string) TeaKey {
+	var b [16]byte
+	copy(b[:], passphrase)
+	return NewKey(AsLEBE(b[:]))
+}
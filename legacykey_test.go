@@ -0,0 +1,52 @@
+package xxtea
+
+import "testing"
+
+func Test_NewKeyLegacy(t *testing.T) {
+	if NewKeyLegacy([]byte("0123"), KeyPadZero) != NewKey([]byte("0123\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")) {
+		t.Error("KeyPadZero failed")
+	}
+	if NewKeyLegacy([]byte("abcdefg"), KeyPadRepeat) != NewKey([]byte("abcdefgabcdefgab")) {
+		t.Error("KeyPadRepeat failed")
+	}
+	if NewKeyLegacy([]byte(keyBEBE), KeyPadRepeat) != NewKey([]byte(keyBEBE)) {
+		t.Error("Full key changed")
+	}
+}
+
+func Test_NewKeyLegacy_Panics(t *testing.T) {
+	for _, f := range []func(){
+		func() { NewKeyLegacy(nil, KeyPadZero) },
+		func() { NewKeyLegacy(make([]byte, 17), KeyPadZero) },
+		func() { NewKeyLegacy([]byte{0, 0}, KeyPadRepeat) },
+		func() { NewKeyLegacy([]byte("k"), KeyPadRepeat+1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("legacy key misuse should panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func Test_NewKeyCompat(t *testing.T) {
+	k := NewKeyCompat("1234567890")
+	if k != (TeaKey{0x34333231, 0x38373635, 0x00003039, 0}) {
+		t.Errorf("NewKeyCompat failed: %08x", k)
+	}
+	if NewKeyCompat("0123456789ABCDEF-ignored") != NewKey([]byte(keyLEBE)) {
+		t.Error("Long passphrase not truncated")
+	}
+	if NewKeyCompat("zażółć") == NewKeyCompat("zazolc") {
+		t.Error("UTF-8 bytes not used")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("empty passphrase should panic")
+		}
+	}()
+	NewKeyCompat("")
+}
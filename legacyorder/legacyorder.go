@@ -0,0 +1,65 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package legacyorder holds "mid-endian" byte juggles, needed only for some
+// legacy (PIC-based) controllers, that serialize 4B words as pairs of
+// 2B halves.  Package xxtea has the common AsLEBE, AsBELE and AsLELE.
+package legacyorder
+
+const em = "legacyorder: argument length must be at least 4 and divisible by 4"
+
+// AsLB16 reverses byte order in a 2B chunk, preserves chunks order
+//
+// (LB16) 1032547698BADCFE <=> 0123456789ABCDEF (BEBE)
+//
+// Function does its juggling in-place then returns the same 'd' slice.
+// It expects len(d) to be at least 4 and divisible by 4.
+// Limit is imposed for consistency with other As... functions.
+func AsLB16(d []byte) []byte {
+	var i int
+	for i < chk4len(len(d)) {
+		d[i], d[i+1] = d[i+1], d[i]
+		i += 2
+	}
+	return d
+}
+
+// AsME16 reverses byte order in each even 2B chunk
+//
+// (ME16) 1023546798ABDCEF <=> 0123456789ABCDEF (BEBE)
+//
+// Function does its juggling in-place then returns the same 'd' slice.
+// It expects len(d) to be at least 4 and divisible by 4.
+func AsME16(d []byte) []byte {
+	var i int // uh, now we have slices.Reverse
+	for i < chk4len(len(d)) {
+		d[i], d[i+1] = d[i+1], d[i]
+		i += 4
+	}
+	return d
+}
+
+// AsMX16 reverses byte order in each odd 2B chunk
+//
+// (MX16) 0132457689BACDFE <=> 0123456789ABCDEF (BEBE)
+//
+// Function does its juggling in-place then returns the same 'd' slice.
+// It expects len(d) to be at least 4 and divisible by 4.
+func AsMX16(d []byte) []byte {
+	var i int
+	for i < chk4len(len(d)) {
+		d[i+2], d[i+3] = d[i+3], d[i+2]
+		i += 4
+	}
+	return d
+}
+
+// chk4len tests if length is >= 4 and divisible by 4, otherwise it panics.
+// It returns index of the last element in a slice if l is slice length.
+func chk4len(l int) int {
+	if l < 4 || l&3 != 0 {
+		panic(em)
+	}
+	return l - 1
+}
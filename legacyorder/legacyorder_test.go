@@ -0,0 +1,37 @@
+package legacyorder
+
+import "testing"
+
+const (
+	keyBEBE = "0123456789ABCDEF"
+	datLB16 = "1032547698BADCFE"
+	datME16 = "1023546798ABDCEF"
+	datMX16 = "0132457689BACDFE"
+)
+
+func Test_Juggles(t *testing.T) {
+	if string(AsLB16([]byte(datLB16))) != keyBEBE {
+		t.Error("AsLB16 logic is broken")
+	}
+	if string(AsME16([]byte(datME16))) != keyBEBE {
+		t.Error("AsME16 logic is broken")
+	}
+	if string(AsMX16([]byte(datMX16))) != keyBEBE {
+		t.Error("AsMX16 logic is broken")
+	}
+}
+
+func Test_Juggles_Panics(t *testing.T) {
+	for _, f := range []func([]byte) []byte{AsLB16, AsME16, AsMX16} {
+		for _, n := range []int{0, 2, 6} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Error("bad length should panic:", n)
+					}
+				}()
+				f(make([]byte, n))
+			}()
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import "encoding/binary"
+
+// LongChainSize is the number of ciphertext bytes of a chunk chained into
+// the next one by EncryptLong.
+const LongChainSize = 16
+
+var lblLong = "long"
+
+// longChunks returns the number of chunks of n (padded) bytes.
+func longChunks(n int) int {
+	return (n + 207) / 208
+}
+
+// longSplit returns the end of chunk i of c chunks splitting n bytes in
+// whole words as evenly as possible.
+func longSplit(n, c, i int) int {
+	w := n / 4
+	return 4 * (w * (i + 1) / c)
+}
+
+// longKey returns the key of chunk i of c.
+func (k TeaKey) longKey(i, c int) TeaKey {
+	var b [8]byte
+	binary.BigEndian.PutUint32(b[:], uint32(i))
+	binary.BigEndian.PutUint32(b[4:], uint32(c))
+	return k.DeriveLabel(lblLong, b[:])
+}
+
+// TeaKey.EncryptLong returns 'in' of any length encrypted into a new
+// slice, for messages longer than Encrypt takes:
+//
+//   - in is padded as PadISO does, 0x80 then zeros to a multiple of four
+//     and at least 12 bytes;
+//   - padded data is split into c = ceil(len/208) chunks of whole words,
+//     as even as possible, so each is 12..208 bytes long;
+//   - chunk i is encrypted under its own subkey,
+//     DeriveLabel("long", i, c) with i and c as 4B big-endian, binding
+//     chunks to their place and count;
+//   - last LongChainSize bytes of the ciphertext of chunk i-1 are XORed
+//     into the first bytes of chunk i before encryption, so a chunk depends
+//     on all chunks before it.
+//
+// Output is as long as the padded input.  Like Encrypt it is deterministic
+// and not authenticated: equal messages give equal ciphertexts, and it
+// does not detect tampering.  Use package envelope, or a MAC over the
+// output, where that matters.
+func (k TeaKey) EncryptLong(in []byte) []byte {
+	out := make([]byte, isoLen(len(in)))
+	copy(out, in)
+	out[len(in)] = 0x80
+	c := longChunks(len(out))
+	for i, s := 0, 0; i < c; i++ {
+		e := longSplit(len(out), c, i)
+		b := out[s:e]
+		if i > 0 {
+			for j, x := range out[s-LongChainSize : s] {
+				b[j] ^= x
+			}
+		}
+		k.longKey(i, c).encryptBytes(b, b)
+		s = e
+	}
+	return out
+}
+
+// TeaKey.DecryptLong undoes EncryptLong.  It returns ErrPadding for input
+// EncryptLong could not have made, which mostly means a wrong key.
+func (k TeaKey) DecryptLong(in []byte) ([]byte, error) {
+	n := len(in)
+	if n < 12 || n&3 != 0 {
+		return nil, ErrPadding
+	}
+	out := make([]byte, n)
+	c := longChunks(n)
+	for i, s := 0, 0; i < c; i++ {
+		e := longSplit(n, c, i)
+		k.longKey(i, c).decryptBytes(in[s:e], out[s:e])
+		if i > 0 {
+			for j, x := range in[s-LongChainSize : s] {
+				out[s+j] ^= x
+			}
+		}
+		s = e
+	}
+	i := n - 1
+	for i >= 0 && out[i] == 0 {
+		i--
+	}
+	if i < 0 || out[i] != 0x80 || isoLen(i) != n {
+		return nil, ErrPadding
+	}
+	return out[:i], nil
+}
@@ -0,0 +1,39 @@
+package xxtea
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_Long(t *testing.T) {
+	key := NewKey(bs(keyBEBE))
+	src := bytes.Repeat(bs(msgMax), 20)
+	for _, n := range []int{0, 1, 11, 207, 208, 209, 416, 417, 1000, 4096} {
+		ct := key.EncryptLong(src[:n])
+		if len(ct) != isoLen(n) {
+			t.Error("EncryptLong length wrong", n, len(ct))
+		}
+		if pt, err := key.DecryptLong(ct); err != nil || !bytes.Equal(pt, src[:n]) {
+			t.Error("DecryptLong failed", n, err)
+		}
+	}
+	// equal chunks encrypt differently, and a change spreads forward
+	ct := key.EncryptLong(src[:1000])
+	if bytes.Equal(ct[:200], ct[200:400]) {
+		t.Error("Equal chunks gave equal ciphertext")
+	}
+	p := append([]byte(nil), src[:1000]...)
+	p[0] ^= 1
+	ct2 := key.EncryptLong(p)
+	for i := 0; i < len(ct); i += 200 {
+		if bytes.Equal(ct[i:i+4], ct2[i:i+4]) {
+			t.Error("Change did not spread to chunk at", i)
+		}
+	}
+	if _, err := NewKey(bs(keyLELE)).DecryptLong(ct); err != ErrPadding {
+		t.Error("Wrong key not noticed", err)
+	}
+	if _, err := key.DecryptLong(ct[:len(ct)-2]); err != ErrPadding {
+		t.Error("Unaligned input accepted", err)
+	}
+}
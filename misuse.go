@@ -0,0 +1,58 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+// XxteaError is the reason code of a misuse of this package.  Misuse
+// panics with one of the codes below, and error returning functions
+// return them, so callers can tell a short key from a bad length:
+//
+//	defer func() {
+//		if e, ok := recover().(xxtea.XxteaError); ok && e == xxtea.ErrMsgShort {
+//			...
+//		}
+//	}()
+type XxteaError uint8
+
+const (
+	ErrMisuse      XxteaError = iota // other misuse, eg. a bad label or policy
+	ErrKeyLen                        // key not 16 bytes long
+	ErrZeroKey                       // all-zeros key
+	ErrMsgShort                      // message under 12 bytes
+	ErrMsgLong                       // message over 208 bytes
+	ErrMsgAlign                      // message length not a multiple of four
+	ErrLenMismatch                   // in and out lengths differ
+)
+
+var misuseText = [...]string{
+	ErrMisuse:      "XXTEA cipher misuse! Read teh Docs, Luke!",
+	ErrKeyLen:      "key must be 16 bytes long",
+	ErrZeroKey:     "all-zeros key",
+	ErrMsgShort:    "message shorter than 12 bytes",
+	ErrMsgLong:     "message longer than 208 bytes",
+	ErrMsgAlign:    "message length not a multiple of 4",
+	ErrLenMismatch: "in and out lengths differ",
+}
+
+func (e XxteaError) Error() string {
+	if int(e) < len(misuseText) {
+		return "xxtea: " + misuseText[e]
+	}
+	return ErrMisuse.Error()
+}
+
+// chkMsg returns the misuse of in and out message lengths, or nil.
+func chkMsg(in, out int) error {
+	switch {
+	case in < 12:
+		return ErrMsgShort
+	case in > 208:
+		return ErrMsgLong
+	case in&3 != 0:
+		return ErrMsgAlign
+	case in != out:
+		return ErrLenMismatch
+	}
+	return nil
+}
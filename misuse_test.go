@@ -0,0 +1,39 @@
+package xxtea
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_Misuse(t *testing.T) {
+	key := NewKey(bs(keyBEBE))
+	for _, c := range []struct {
+		f    func()
+		want XxteaError
+	}{
+		{func() { NewKey(bs("short")) }, ErrKeyLen},
+		{func() { NewKey(make([]byte, 16)) }, ErrZeroKey},
+		{func() { key.Encrypt(make([]byte, 8), make([]byte, 8)) }, ErrMsgShort},
+		{func() { key.Encrypt(make([]byte, 212), make([]byte, 212)) }, ErrMsgLong},
+		{func() { key.Decrypt(make([]byte, 13), make([]byte, 13)) }, ErrMsgAlign},
+		{func() { key.Decrypt(make([]byte, 12), make([]byte, 16)) }, ErrLenMismatch},
+		{func() { key.DeriveLabel("no space") }, ErrMisuse},
+		{func() { AsLEBE(make([]byte, 6)) }, ErrMsgAlign},
+	} {
+		func() {
+			defer func() {
+				if e, _ := recover().(XxteaError); e != c.want {
+					t.Error("Wrong misuse code", e, c.want)
+				}
+			}()
+			c.f()
+		}()
+	}
+	var err error = ErrMsgShort
+	if !errors.Is(err, ErrMsgShort) || errors.Is(err, ErrMsgLong) {
+		t.Error("Codes do not compare")
+	}
+	if ErrKeyLen.Error() != "xxtea: key must be 16 bytes long" || XxteaError(99).Error() != ErrMisuse.Error() {
+		t.Error("Error text failed")
+	}
+}
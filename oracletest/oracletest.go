@@ -0,0 +1,152 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package oracletest probes code that opens frames for padding-oracle style
+// behaviour: failures that tell a peer which check failed, by a distinct
+// error or by a distinct time taken.  It is meant for downstream CI, to
+// keep integrations of envelope (or of any other frame format) honest:
+//
+//	func TestNoOracle(t *testing.T) {
+//		frame, _ := envelope.SealPadded(key, envelope.Header{}, msg)
+//		oracletest.Check(t, func(b []byte) error {
+//			_, err := myservice.Handle(b) // what a peer would see
+//			return err
+//		}, frame, oracletest.Options{Clear: envelope.HeaderSize})
+//	}
+//
+// Probe takes a frame the open function accepts and feeds it mutations of
+// it: every bit of every byte flipped in turn, the frame truncated and
+// extended.  All of them must be rejected, with one and the same error.
+// Bit flips past the Clear prefix must also take about the same time,
+// within the MaxRatio of medians of Rounds runs.
+package oracletest
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Defaults of zero Options fields.
+const (
+	DefaultRounds   = 16
+	DefaultMaxRatio = 4.0
+)
+
+// Options tune Probe.
+type Options struct {
+	Rounds   int     // timed runs of each mutation
+	MaxRatio float64 // slowest to fastest median of bit flips, below zero to skip timing
+	Clear    int     // leading bytes sent in clear, eg. the header, left out of timing
+}
+
+// Result is the outcome of a single mutation.
+type Result struct {
+	Mutation string        // "flip 5.3" (byte 5, bit 3), "cut 4" or "add 4"
+	Err      string        // error returned, empty if the mutation was accepted
+	Median   time.Duration // median time of open
+}
+
+// Report is what Probe found.
+type Report struct {
+	Results  []Result
+	Findings []string // problems found, none for an honest open function
+}
+
+// OK reports whether there are no findings.
+func (r *Report) OK() bool {
+	return len(r.Findings) == 0
+}
+
+// Probe feeds mutations of the frame to open and reports what differs.
+func Probe(open func(frame []byte) error, frame []byte, opts Options) *Report {
+	if opts.Rounds <= 0 {
+		opts.Rounds = DefaultRounds
+	}
+	if opts.MaxRatio == 0 {
+		opts.MaxRatio = DefaultMaxRatio
+	}
+	r := &Report{}
+	if err := open(append([]byte(nil), frame...)); err != nil {
+		r.Findings = append(r.Findings, "valid frame rejected: "+err.Error())
+		return r
+	}
+	run := func(name string, m []byte) Result {
+		ts := make([]time.Duration, opts.Rounds)
+		var res Result
+		res.Mutation = name
+		b := make([]byte, len(m))
+		for i := range ts {
+			copy(b, m)
+			start := time.Now()
+			err := open(b)
+			ts[i] = time.Since(start)
+			if i == 0 && err != nil {
+				res.Err = err.Error()
+			}
+		}
+		sort.Slice(ts, func(i, j int) bool { return ts[i] < ts[j] })
+		res.Median = ts[len(ts)/2]
+		r.Results = append(r.Results, res)
+		return res
+	}
+	var fast, slow Result
+	m := make([]byte, len(frame))
+	for i := range frame {
+		for bit := 0; bit < 8; bit++ {
+			copy(m, frame)
+			m[i] ^= 1 << bit
+			res := run("flip "+strconv.Itoa(i)+"."+strconv.Itoa(bit), m)
+			if i < opts.Clear {
+				continue
+			}
+			if fast.Mutation == "" || res.Median < fast.Median {
+				fast = res
+			}
+			if res.Median > slow.Median {
+				slow = res
+			}
+		}
+	}
+	for _, n := range []int{1, 4} {
+		if n < len(frame) {
+			run("cut "+strconv.Itoa(n), frame[:len(frame)-n])
+		}
+		run("add "+strconv.Itoa(n), append(append([]byte(nil), frame...), make([]byte, n)...))
+	}
+	errs := map[string]string{} // error to the first mutation giving it
+	for _, res := range r.Results {
+		if res.Err == "" {
+			r.Findings = append(r.Findings, "mutated frame accepted: "+res.Mutation)
+			continue
+		}
+		if _, ok := errs[res.Err]; !ok {
+			errs[res.Err] = res.Mutation
+		}
+	}
+	if len(errs) > 1 {
+		var es []string
+		for e, mu := range errs {
+			es = append(es, strconv.Quote(e)+" ("+mu+")")
+		}
+		sort.Strings(es)
+		for _, e := range es {
+			r.Findings = append(r.Findings, "distinct error: "+e)
+		}
+	}
+	if opts.MaxRatio > 0 && fast.Median > 0 && float64(slow.Median) > opts.MaxRatio*float64(fast.Median) {
+		r.Findings = append(r.Findings, "timing: "+slow.Mutation+" took "+slow.Median.String()+
+			", "+fast.Mutation+" took "+fast.Median.String())
+	}
+	return r
+}
+
+// Check runs Probe and reports every finding as a test error.
+func Check(t testing.TB, open func(frame []byte) error, frame []byte, opts Options) {
+	t.Helper()
+	for _, f := range Probe(open, frame, opts).Findings {
+		t.Error("oracletest:", f)
+	}
+}
@@ -0,0 +1,62 @@
+package oracletest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+var key = xxtea.NewKey([]byte("0123456789ABCDEF"))
+
+func Test_Honest(t *testing.T) {
+	f, _ := envelope.SealPadded(key, envelope.Header{}, []byte("hello"))
+	Check(t, func(b []byte) error {
+		_, err := envelope.OpenPadded(key, b)
+		return err
+	}, f, Options{Rounds: 3, MaxRatio: -1})
+}
+
+func Test_Leaky(t *testing.T) {
+	f, _ := envelope.SealPadded(key, envelope.Header{}, []byte("hello"))
+	r := Probe(func(b []byte) error {
+		_, err := envelope.OpenPaddedDetailed(key, b)
+		return err
+	}, f, Options{Rounds: 1, MaxRatio: -1})
+	if r.OK() || !strings.Contains(strings.Join(r.Findings, "\n"), "distinct error") {
+		t.Error("Distinct errors not found", r.Findings)
+	}
+	// no authentication at all
+	ct := key.EncryptAny([]byte("hello world!"), xxtea.PadNone)
+	r = Probe(func(b []byte) error {
+		if len(b) != len(ct) {
+			return errors.New("bad length")
+		}
+		return nil
+	}, ct, Options{Rounds: 1, MaxRatio: -1})
+	if r.OK() || !strings.HasPrefix(r.Findings[0], "mutated frame accepted") {
+		t.Error("Accepted mutations not found", r.Findings)
+	}
+	if r := Probe(func([]byte) error { return errors.New("no") }, ct, Options{}); r.OK() {
+		t.Error("Valid frame rejection not found")
+	}
+}
+
+func Test_Timing(t *testing.T) {
+	f := []byte("0123456789ABCDEF")
+	r := Probe(func(b []byte) error {
+		if len(b) == len(f) && b[len(f)-1] != f[len(f)-1] {
+			time.Sleep(time.Millisecond)
+		}
+		if string(b) == string(f) {
+			return nil
+		}
+		return errors.New("no")
+	}, f, Options{Rounds: 3})
+	if r.OK() || !strings.HasPrefix(r.Findings[len(r.Findings)-1], "timing: flip 15.") {
+		t.Error("Timing difference not found", r.Findings)
+	}
+}
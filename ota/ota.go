@@ -0,0 +1,124 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ota seals firmware images for over-the-air updates: encrypted in
+// XXTEA chunks under a device (or group) key and signed with Ed25519 by the
+// vendor tooling.
+//
+// Signature covers the header and the ciphertext, and Open always verifies
+// it before decrypting anything (verify-then-decrypt), so a tampered image
+// is never fed through the cipher nor handed to the caller.
+//
+// Bundle layout:
+//
+//	"XOTA" | image length (4B BE) | nonce (8B) | ciphertext | signature (64B)
+//
+// Image is zero-padded to a multiple of four (and at least 12 bytes) and
+// split into chunks of at most 208 bytes.  Every chunk is encrypted under
+// its own key derived from the key, the nonce and the chunk index.
+package ota
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ohir/xxtea"
+)
+
+// Sizes of bundle parts.
+const (
+	HeaderSize = 16
+	NonceSize  = 8
+)
+
+var (
+	ErrFormat    = errors.New("ota: malformed bundle")
+	ErrSignature = errors.New("ota: bad bundle signature")
+)
+
+var magic = []byte("XOTA")
+
+// padded returns image length padded to XXTEA limits.
+func padded(n int) int {
+	n = (n + 3) &^ 3
+	if n < 12 {
+		n = 12
+	}
+	return n
+}
+
+// chunk returns size of the next chunk out of rem bytes left, so that
+// the last chunk is never shorter than 12 bytes.
+func chunk(rem int) int {
+	switch {
+	case rem <= 208:
+		return rem
+	case rem-208 < 12:
+		return rem - 12
+	}
+	return 208
+}
+
+// crypt runs fn over chunks of data, each with its own key.
+func crypt(key xxtea.TeaKey, nonce, data []byte, enc bool) {
+	var idx [4]byte
+	var i uint32
+	for len(data) > 0 {
+		c := data[:chunk(len(data))]
+		binary.BigEndian.PutUint32(idx[:], i)
+		if k := key.DeriveLabel("ota", nonce, idx[:]); enc {
+			k.Encrypt(c, c)
+		} else {
+			k.Decrypt(c, c)
+		}
+		data = data[len(c):]
+		i++
+	}
+}
+
+// Seal encrypts the image under key, then signs the bundle with priv.
+func Seal(priv ed25519.PrivateKey, key xxtea.TeaKey, image []byte) ([]byte, error) {
+	if uint64(len(image)) > 1<<32-1 {
+		return nil, ErrFormat
+	}
+	n := padded(len(image))
+	b := make([]byte, HeaderSize+n, HeaderSize+n+ed25519.SignatureSize)
+	copy(b, magic)
+	binary.BigEndian.PutUint32(b[4:], uint32(len(image)))
+	if err := xxtea.ReadEntropy(b[8:HeaderSize]); err != nil {
+		return nil, err
+	}
+	copy(b[HeaderSize:], image)
+	crypt(key, b[8:HeaderSize], b[HeaderSize:], true)
+	return append(b, ed25519.Sign(priv, b)...), nil
+}
+
+// Verify checks bundle format and its signature, without decrypting.
+func Verify(pub ed25519.PublicKey, bundle []byte) error {
+	if len(bundle) < HeaderSize+12+ed25519.SignatureSize ||
+		string(bundle[:4]) != string(magic) {
+		return ErrFormat
+	}
+	body := bundle[:len(bundle)-ed25519.SignatureSize]
+	if padded(int(binary.BigEndian.Uint32(bundle[4:]))) != len(body)-HeaderSize {
+		return ErrFormat
+	}
+	if !ed25519.Verify(pub, body, bundle[len(body):]) {
+		return ErrSignature
+	}
+	return nil
+}
+
+// Open verifies the bundle signature with pub and only then decrypts the
+// image under key.  Bundle is left intact.
+func Open(pub ed25519.PublicKey, key xxtea.TeaKey, bundle []byte) ([]byte, error) {
+	if err := Verify(pub, bundle); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(bundle[4:])
+	img := append([]byte(nil), bundle[HeaderSize:len(bundle)-ed25519.SignatureSize]...)
+	crypt(key, bundle[8:HeaderSize], img, false)
+	return img[:n], nil
+}
@@ -0,0 +1,83 @@
+package ota
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+const (
+	keyBEBE = "0123456789ABCDEF"
+	keyLELE = "FEDCBA9876543210"
+)
+
+func Test_SealOpen(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	key := xxtea.NewKey([]byte(keyBEBE))
+	for _, n := range []int{0, 1, 12, 13, 208, 209, 212, 219, 220, 416, 1000, 4099} {
+		img := bytes.Repeat([]byte{0xA5}, n)
+		b, err := Seal(priv, key, img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n >= 16 && bytes.Contains(b, img[:16]) {
+			t.Error("Image leaked in clear, size", n)
+		}
+		got, err := Open(pub, key, b)
+		if err != nil || !bytes.Equal(got, img) {
+			t.Error("Open failed, size", n, err)
+		}
+	}
+}
+
+func Test_Chunks(t *testing.T) {
+	for n := 12; n < 2000; n += 4 {
+		for rem := n; rem > 0; {
+			c := chunk(rem)
+			if c < 12 || c > 208 || c&3 != 0 {
+				t.Fatal("Bad chunk", c, "of", n)
+			}
+			rem -= c
+		}
+	}
+}
+
+func Test_Tamper(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	key := xxtea.NewKey([]byte(keyBEBE))
+	b, _ := Seal(priv, key, make([]byte, 500))
+	for _, i := range []int{5, 9, HeaderSize + 300, len(b) - 1} {
+		f := append([]byte(nil), b...)
+		f[i] ^= 1
+		if _, err := Open(pub, key, f); err == nil {
+			t.Error("Tampered bundle opened, byte", i)
+		}
+	}
+	other, _, _ := ed25519.GenerateKey(nil)
+	if _, err := Open(other, key, b); err != ErrSignature {
+		t.Error("Bundle opened with other vendor key")
+	}
+	img, err := Open(pub, xxtea.NewKey([]byte(keyLELE)), b)
+	if err != nil || bytes.Equal(img, make([]byte, 500)) {
+		t.Error("Bundle decrypted with other device key")
+	}
+}
+
+func Test_Malformed(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	key := xxtea.NewKey([]byte(keyBEBE))
+	b, _ := Seal(priv, key, make([]byte, 40))
+	if err := Verify(pub, b[:HeaderSize+ed25519.SignatureSize]); err != ErrFormat {
+		t.Error("Short bundle accepted")
+	}
+	f := append([]byte(nil), b...)
+	f[0] = 'Y'
+	if err := Verify(pub, f); err != ErrFormat {
+		t.Error("Bad magic accepted")
+	}
+	if err := Verify(pub, append(b[:HeaderSize+40:HeaderSize+40], b...)); err != ErrFormat {
+		t.Error("Length mismatch accepted")
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/ohir/xxtea/pairing
+
+go 1.18
+
+require (
+	filippo.io/nistec v0.0.3
+	github.com/ohir/xxtea v0.0.0
+)
+
+replace github.com/ohir/xxtea => ../
@@ -0,0 +1,247 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pairing implements a SPAKE2-like password-authenticated pairing
+// that ends with both sides holding a fresh xxtea.TeaKey.
+//
+// Two parties knowing the same short code (eg. an 8-digit number printed
+// on a device label) agree on a strong 128-bit key.  An eavesdropper learns
+// nothing, and an active attacker gets a single code guess per run.
+//
+//	Initiator (phone)                          Responder (device)
+//	Start()          -- pA (65B) -->
+//	                 <-- pB (65B) | cB (32B) -- Reply(start)
+//	Finish(reply)    -- cA (32B) -->
+//	                                           Finish(confirm)
+//
+// Group math is done on the NIST P-256 curve, by filippo.io/nistec, with
+// the M and N points taken from RFC 9382.  Transcript hashing and key confirmation use SHA-256 and
+// HMAC.  This is not a byte-exact RFC 9382 implementation.
+package pairing
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"filippo.io/nistec"
+	"github.com/ohir/xxtea"
+)
+
+// Sizes of pairing messages.
+const (
+	PointSize   = 65
+	StartSize   = PointSize
+	ReplySize   = PointSize + sha256.Size
+	ConfirmSize = sha256.Size
+)
+
+var (
+	ErrMessage = errors.New("pairing: malformed message")
+	ErrConfirm = errors.New("pairing: key confirmation failed")
+	ErrState   = errors.New("pairing: method called out of order")
+)
+
+var (
+	// order is the order of the P-256 group.
+	order, _ = new(big.Int).SetString("ffffffff00000000ffffffffffffffffbce6faada7179e84f3b9cac2fc632551", 16)
+
+	pointM = point("02886e2f97ace46e55ba9dd7242579f2993b64e16ef3dcab95afd497333d8fa12f")
+	pointN = point("03d8bbd6c639c62937b04d997f38c3770719c629d7014d49a24b4f98baa1292b49")
+)
+
+func point(s string) *nistec.P256Point {
+	b, _ := hex.DecodeString(s)
+	p, err := nistec.NewP256Point().SetBytes(b)
+	if err != nil {
+		panic("pairing: bad constant")
+	}
+	return p
+}
+
+// scalar returns n as the 32 big-endian bytes nistec takes.
+func scalar(n *big.Int) []byte {
+	return n.FillBytes(make([]byte, 32))
+}
+
+// NewCode returns a random 8-digit pairing code.
+func NewCode() (string, error) {
+	n, err := rand.Int(xxtea.Entropy(), big.NewInt(100000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%08d", n), nil
+}
+
+type state struct {
+	w      *big.Int // password scalar
+	s      *big.Int // own secret scalar
+	pa, pb []byte   // public shares
+	ke     []byte   // session key bytes
+	ca, cb []byte   // confirmations
+	step   int
+}
+
+func newState(code string) state {
+	h := sha256.Sum256([]byte("xxtea-pairing" + code))
+	w := new(big.Int).SetBytes(h[:])
+	return state{w: w.Mod(w, order)}
+}
+
+// share returns s*G + w*P serialized.
+func (st *state) share(p *nistec.P256Point) ([]byte, error) {
+	var err error
+	for st.s == nil || st.s.Sign() == 0 {
+		if st.s, err = rand.Int(xxtea.Entropy(), order); err != nil {
+			return nil, err
+		}
+	}
+	x, err := nistec.NewP256Point().ScalarBaseMult(scalar(st.s))
+	if err != nil {
+		return nil, err
+	}
+	wp, err := nistec.NewP256Point().ScalarMult(p, scalar(st.w))
+	if err != nil {
+		return nil, err
+	}
+	return x.Add(x, wp).Bytes(), nil
+}
+
+// finish computes shared secret from peer's share, unblinding it with
+// w*P, then derives session key and both confirmations.
+func (st *state) finish(peer []byte, p *nistec.P256Point) bool {
+	if len(peer) != PointSize {
+		return false
+	}
+	x, err := nistec.NewP256Point().SetBytes(peer)
+	if err != nil {
+		return false
+	}
+	wp, err := nistec.NewP256Point().ScalarMult(p, scalar(st.w))
+	if err != nil {
+		return false
+	}
+	x.Add(x, wp.Negate(wp))
+	if x, err = x.ScalarMult(x, scalar(st.s)); err != nil {
+		return false
+	}
+	k := x.Bytes()
+	if len(k) != PointSize { // the point at infinity
+		return false
+	}
+	h := sha256.New()
+	for _, b := range [][]byte{st.pa, st.pb, k, st.w.Bytes()} {
+		var l [8]byte
+		binary.LittleEndian.PutUint64(l[:], uint64(len(b)))
+		h.Write(l[:])
+		h.Write(b)
+	}
+	tt := h.Sum(nil)
+	ka := tt[16:]
+	st.ke = tt[:16]
+	m := hmac.New(sha256.New, ka)
+	m.Write([]byte("ConfirmationKeys"))
+	kc := m.Sum(nil)
+	st.ca = mac(kc[:16], tt)
+	st.cb = mac(kc[16:], tt)
+	return true
+}
+
+func mac(k, b []byte) []byte {
+	m := hmac.New(sha256.New, k)
+	m.Write(b)
+	return m.Sum(nil)
+}
+
+// Initiator is the side that starts the pairing.
+type Initiator struct {
+	state
+}
+
+// NewInitiator returns an Initiator using the pairing code.
+func NewInitiator(code string) *Initiator {
+	return &Initiator{newState(code)}
+}
+
+// Start returns the first message to be sent to the Responder.
+func (i *Initiator) Start() (msg []byte, err error) {
+	if i.step != 0 {
+		return nil, ErrState
+	}
+	if i.pa, err = i.share(pointM); err != nil {
+		return nil, err
+	}
+	i.step++
+	return i.pa, nil
+}
+
+// Finish checks the Responder's reply.  It returns the confirmation message
+// to be sent back to the Responder and the paired key.
+func (i *Initiator) Finish(reply []byte) (confirm []byte, key xxtea.TeaKey, err error) {
+	if i.step != 1 {
+		return nil, key, ErrState
+	}
+	i.step++
+	if len(reply) != ReplySize {
+		return nil, key, ErrMessage
+	}
+	i.pb = append([]byte(nil), reply[:PointSize]...)
+	if !i.finish(i.pb, pointN) {
+		return nil, key, ErrMessage
+	}
+	if !hmac.Equal(i.cb, reply[PointSize:]) {
+		return nil, key, ErrConfirm
+	}
+	return i.ca, xxtea.NewKey(i.ke), nil
+}
+
+// Responder is the side that answers the pairing.
+type Responder struct {
+	state
+}
+
+// NewResponder returns a Responder using the pairing code.
+func NewResponder(code string) *Responder {
+	return &Responder{newState(code)}
+}
+
+// Reply takes the Initiator's start message and returns the reply to be
+// sent back.
+func (r *Responder) Reply(start []byte) (reply []byte, err error) {
+	if r.step != 0 {
+		return nil, ErrState
+	}
+	r.step++
+	if len(start) != StartSize {
+		return nil, ErrMessage
+	}
+	r.pa = append([]byte(nil), start...)
+	if r.pb, err = r.share(pointN); err != nil {
+		return nil, err
+	}
+	if !r.finish(r.pa, pointM) {
+		return nil, ErrMessage
+	}
+	return append(append([]byte(nil), r.pb...), r.cb...), nil
+}
+
+// Finish checks the Initiator's confirmation and returns the paired key.
+func (r *Responder) Finish(confirm []byte) (key xxtea.TeaKey, err error) {
+	if r.step != 1 {
+		return key, ErrState
+	}
+	r.step++
+	if len(confirm) != ConfirmSize {
+		return key, ErrMessage
+	}
+	if !hmac.Equal(r.ca, confirm) {
+		return key, ErrConfirm
+	}
+	return xxtea.NewKey(r.ke), nil
+}
@@ -0,0 +1,102 @@
+package pairing
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func run(t *testing.T, icode, rcode string) (ik, rk xxtea.TeaKey, ierr, rerr error) {
+	in := NewInitiator(icode)
+	re := NewResponder(rcode)
+	start, err := in.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := re.Reply(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirm, ik, ierr := in.Finish(reply)
+	if ierr != nil {
+		return
+	}
+	rk, rerr = re.Finish(confirm)
+	return
+}
+
+func Test_Pairing(t *testing.T) {
+	code, err := NewCode()
+	if err != nil || len(code) != 8 {
+		t.Fatal("NewCode failed:", code, err)
+	}
+	ik, rk, ierr, rerr := run(t, code, code)
+	if ierr != nil || rerr != nil {
+		t.Fatal("Pairing failed:", ierr, rerr)
+	}
+	if ik != rk {
+		t.Error("Paired keys differ")
+	}
+	ik2, _, _, _ := run(t, code, code)
+	if ik == ik2 {
+		t.Error("Paired key repeated across runs")
+	}
+}
+
+func Test_WrongCode(t *testing.T) {
+	if _, _, ierr, _ := run(t, "12345678", "12345679"); ierr != ErrConfirm {
+		t.Error("Initiator accepted reply made with other code")
+	}
+}
+
+func Test_BadConfirm(t *testing.T) {
+	in := NewInitiator("00000001")
+	re := NewResponder("00000001")
+	start, _ := in.Start()
+	reply, _ := re.Reply(start)
+	confirm, _, err := in.Finish(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirm[0] ^= 1
+	if _, err = re.Finish(confirm); err != ErrConfirm {
+		t.Error("Responder accepted forged confirmation")
+	}
+}
+
+func Test_Malformed(t *testing.T) {
+	re := NewResponder("00000001")
+	if _, err := re.Reply(make([]byte, StartSize)); err != ErrMessage {
+		t.Error("Off-curve start accepted")
+	}
+	in := NewInitiator("00000001")
+	in.Start()
+	if _, _, err := in.Finish(make([]byte, ReplySize-1)); err != ErrMessage {
+		t.Error("Short reply accepted")
+	}
+	re = NewResponder("00000001")
+	start, _ := NewInitiator("00000001").Start()
+	re.Reply(start)
+	if _, err := re.Finish(make([]byte, 3)); err != ErrMessage {
+		t.Error("Short confirm accepted")
+	}
+}
+
+func Test_State(t *testing.T) {
+	in := NewInitiator("00000001")
+	re := NewResponder("00000001")
+	if _, _, err := in.Finish(make([]byte, ReplySize)); err != ErrState {
+		t.Error("Initiator Finish before Start accepted")
+	}
+	if _, err := re.Finish(make([]byte, ConfirmSize)); err != ErrState {
+		t.Error("Responder Finish before Reply accepted")
+	}
+	start, _ := in.Start()
+	if _, err := in.Start(); err != ErrState {
+		t.Error("Second Start accepted")
+	}
+	re.Reply(start)
+	if _, err := re.Reply(start); err != ErrState {
+		t.Error("Second Reply accepted")
+	}
+}
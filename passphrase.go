@@ -0,0 +1,82 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+// DefaultPassphraseIter is the PBKDF2 iteration count of zero
+// PassphraseParams, as OWASP advises for PBKDF2-HMAC-SHA256 in 2023.
+const DefaultPassphraseIter = 600000
+
+var ErrPassphrase = errors.New("xxtea: empty passphrase or salt")
+
+// PassphraseParams tell NewKeyFromPassphrase how to derive a key.  Zero
+// params are PBKDF2-HMAC-SHA256 of DefaultPassphraseIter iterations.
+type PassphraseParams struct {
+	Iterations int // PBKDF2 iterations, DefaultPassphraseIter if zero
+	// KDF, if set, replaces PBKDF2, eg. with Argon2id of x/crypto:
+	//
+	//	func(pass, salt []byte) []byte {
+	//		return argon2.IDKey(pass, salt, 3, 64*1024, 4, 16)
+	//	}
+	//
+	// It must return at least 16 bytes; the first 16 make the key.
+	KDF func(pass, salt []byte) []byte
+}
+
+// NewKeyFromPassphrase derives a key from a human passphrase and a salt,
+// eg. the device serial number, as the params say.  It returns
+// ErrPassphrase for an empty passphrase or salt, ErrMisuse for bad params
+// and ErrZeroKey for the all-zeros key, once in 2^128.
+//
+// Use it rather than truncating a plain hash of the passphrase: the
+// iteration count, or memory cost of the KDF, is what makes guessing a
+// passphrase from a ciphertext slow.
+func NewKeyFromPassphrase(pass, salt []byte, params PassphraseParams) (TeaKey, error) {
+	if len(pass) == 0 || len(salt) == 0 {
+		return TeaKey{}, ErrPassphrase
+	}
+	var b [16]byte
+	switch {
+	case params.KDF != nil:
+		k := params.KDF(pass, salt)
+		if len(k) < 16 {
+			return TeaKey{}, ErrMisuse
+		}
+		copy(b[:], k)
+	case params.Iterations < 0:
+		return TeaKey{}, ErrMisuse
+	default:
+		n := params.Iterations
+		if n == 0 {
+			n = DefaultPassphraseIter
+		}
+		pbkdf2(b[:], pass, salt, n)
+	}
+	return keyOf(&b, 0)
+}
+
+// pbkdf2 fills dk, up to 32 bytes, with PBKDF2-HMAC-SHA256 of the
+// passphrase and salt.
+func pbkdf2(dk, pass, salt []byte, iter int) {
+	m := hmac.New(sha256.New, pass)
+	m.Write(salt)
+	m.Write([]byte{0, 0, 0, 1})
+	u := m.Sum(nil)
+	t := append([]byte(nil), u...)
+	for i := 1; i < iter; i++ {
+		m.Reset()
+		m.Write(u)
+		u = m.Sum(u[:0])
+		for j := range t {
+			t[j] ^= u[j]
+		}
+	}
+	copy(dk, t)
+}
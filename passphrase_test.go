@@ -0,0 +1,41 @@
+package xxtea
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func Test_NewKeyFromPassphrase(t *testing.T) {
+	for _, tc := range []struct {
+		iter int
+		want string // PBKDF2-HMAC-SHA256 of "password", "salt"
+	}{
+		{1, "120fb6cffcf8b32c43e7225256c4f837"},
+		{4096, "c5e478d59288c841aa530db6845c4c8d"},
+	} {
+		k, err := NewKeyFromPassphrase([]byte("password"), []byte("salt"), PassphraseParams{Iterations: tc.iter})
+		if err != nil || hex.EncodeToString(k.Bytes()) != tc.want {
+			t.Error("NewKeyFromPassphrase failed", tc.iter, err)
+		}
+	}
+	kdf := func(pass, salt []byte) []byte { return append(append([]byte{}, pass...), salt...) }
+	k, err := NewKeyFromPassphrase([]byte("0123456789"), []byte("abcdef"), PassphraseParams{KDF: kdf})
+	if err != nil || string(k.Bytes()) != "0123456789abcdef" {
+		t.Error("Custom KDF not used", err)
+	}
+	for _, tc := range []struct {
+		pass, salt string
+		p          PassphraseParams
+		err        error
+	}{
+		{"", "salt", PassphraseParams{}, ErrPassphrase},
+		{"password", "", PassphraseParams{}, ErrPassphrase},
+		{"password", "salt", PassphraseParams{Iterations: -1}, ErrMisuse},
+		{"short", "kdf", PassphraseParams{KDF: kdf}, ErrMisuse},
+		{"pass", "salt", PassphraseParams{KDF: func(_, _ []byte) []byte { return make(bs, 16) }}, ErrZeroKey},
+	} {
+		if _, err := NewKeyFromPassphrase([]byte(tc.pass), []byte(tc.salt), tc.p); err != tc.err {
+			t.Error("NewKeyFromPassphrase misuse accepted", tc.pass, tc.salt, err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package pbframe_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+	"github.com/ohir/xxtea/pbframe"
+)
+
+// Request is a message of a gRPC service carrying a device frame, as its
+// generated code would have it.
+type Request struct {
+	Device  string
+	Payload *pbframe.EncryptedPayload
+}
+
+// opened is the context key of the opened payload.
+type opened struct{}
+
+// The interceptor opens frames of requests before they reach handlers.  Its
+// signature is that of grpc.UnaryServerInterceptor, with the info argument
+// left out; with grpc it is installed by grpc.UnaryInterceptor.
+func Example_interceptor() {
+	key := xxtea.NewKey([]byte("0123456789ABCDEF"))
+	interceptor := func(ctx context.Context, req any, handler func(context.Context, any) (any, error)) (any, error) {
+		r, ok := req.(*Request)
+		if !ok || r.Payload == nil {
+			return handler(ctx, req)
+		}
+		f, err := r.Payload.Frame()
+		if err != nil {
+			return nil, err
+		}
+		p, err := envelope.OpenPadded(key, f)
+		if err != nil {
+			return nil, err
+		}
+		return handler(context.WithValue(ctx, opened{}, p), req)
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return fmt.Sprintf("%s: %s", req.(*Request).Device, ctx.Value(opened{})), nil
+	}
+
+	f, _ := envelope.SealPadded(key, envelope.Header{KeyID: 1}, []byte("temp=21.5"))
+	m, _ := pbframe.FromFrame(f)
+	var wire pbframe.EncryptedPayload // as received
+	wire.Unmarshal(m.Marshal())
+	fmt.Println(interceptor(context.Background(), &Request{"dev-7", &wire}, handler))
+	// Output: dev-7: temp=21.5 <nil>
+}
@@ -0,0 +1,10 @@
+module github.com/ohir/xxtea/pbframe
+
+go 1.23.0
+
+require (
+	github.com/ohir/xxtea v0.0.0
+	google.golang.org/protobuf v1.36.8
+)
+
+replace github.com/ohir/xxtea => ../
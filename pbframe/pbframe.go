@@ -0,0 +1,174 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pbframe carries envelope frames in the protobuf EncryptedPayload
+// message of pbframe.proto, for gRPC-first backends passing device frames
+// through their existing plumbing.  Services embed EncryptedPayload in
+// their own messages (import "pbframe.proto") and use Marshal and
+// Unmarshal, or the generated code of the same file, interchangeably.
+//
+// Message is encoded with protowire, with no generated code, and decoding
+// skips unknown fields as protobuf requires.
+//
+// It is a separate module, so that the xxtea module does not depend on
+// the protobuf runtime.
+package pbframe
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/ohir/xxtea/envelope"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var ErrMessage = errors.New("pbframe: malformed EncryptedPayload")
+
+// Field numbers of EncryptedPayload.
+const (
+	fieldKID protowire.Number = 1 + iota
+	fieldCtr
+	fieldCT
+	fieldTag
+	fieldEpoch
+	fieldVersion
+	fieldFlags
+	fieldExt
+)
+
+// EncryptedPayload is the message of pbframe.proto.
+type EncryptedPayload struct {
+	KID     uint16
+	Ctr     uint32
+	CT      []byte
+	Tag     []byte
+	Epoch   uint32
+	Version uint8
+	Flags   uint8
+	Ext     []byte // header extension fields
+}
+
+// FromFrame splits the frame into an EncryptedPayload.  Slices of the
+// result share the frame bytes.  The frame is not authenticated.
+func FromFrame(frame []byte) (*EncryptedPayload, error) {
+	h, err := envelope.ParseHeader(frame)
+	if err != nil {
+		return nil, err
+	}
+	hs := h.Len()
+	ts := envelope.TagSizeV2
+	if h.Version == envelope.Version1 {
+		ts = envelope.TagSizeV1
+	}
+	return &EncryptedPayload{
+		KID:     h.KeyID,
+		Ctr:     h.Counter,
+		CT:      frame[hs : len(frame)-ts],
+		Tag:     frame[len(frame)-ts:],
+		Epoch:   h.Epoch,
+		Version: h.Version,
+		Flags:   h.Flags,
+		Ext:     frame[envelope.HeaderSize:hs],
+	}, nil
+}
+
+// Frame joins the fields back into a frame, to be opened as usual.  It
+// returns the error of envelope.ParseHeader for fields that do not make
+// a well-formed frame.
+func (m *EncryptedPayload) Frame() ([]byte, error) {
+	f := make([]byte, envelope.HeaderSize, envelope.HeaderSize+len(m.Ext)+len(m.CT)+len(m.Tag))
+	f[0], f[1] = m.Version, m.Flags
+	binary.BigEndian.PutUint16(f[2:], m.KID)
+	binary.BigEndian.PutUint32(f[4:], m.Epoch)
+	binary.BigEndian.PutUint32(f[8:], m.Ctr)
+	f = append(append(append(f, m.Ext...), m.CT...), m.Tag...)
+	h, err := envelope.ParseHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	if h.Len() != envelope.HeaderSize+len(m.Ext) {
+		return nil, envelope.ErrFrame
+	}
+	return f, nil
+}
+
+// Marshal returns the protobuf encoding of the message.  Zero fields are
+// left out, as proto3 does.
+func (m *EncryptedPayload) Marshal() []byte {
+	var b []byte
+	varint := func(n protowire.Number, v uint64) {
+		if v != 0 {
+			b = protowire.AppendTag(b, n, protowire.VarintType)
+			b = protowire.AppendVarint(b, v)
+		}
+	}
+	bytes := func(n protowire.Number, v []byte) {
+		if len(v) != 0 {
+			b = protowire.AppendTag(b, n, protowire.BytesType)
+			b = protowire.AppendBytes(b, v)
+		}
+	}
+	varint(fieldKID, uint64(m.KID))
+	varint(fieldCtr, uint64(m.Ctr))
+	bytes(fieldCT, m.CT)
+	bytes(fieldTag, m.Tag)
+	varint(fieldEpoch, uint64(m.Epoch))
+	varint(fieldVersion, uint64(m.Version))
+	varint(fieldFlags, uint64(m.Flags))
+	bytes(fieldExt, m.Ext)
+	return b
+}
+
+// Unmarshal sets the message from its protobuf encoding.  Byte fields
+// share b.  It returns ErrMessage for malformed encodings and for values
+// out of range of their frame fields.
+func (m *EncryptedPayload) Unmarshal(b []byte) error {
+	*m = EncryptedPayload{}
+	for len(b) > 0 {
+		n, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return ErrMessage
+		}
+		b = b[l:]
+		var v uint64
+		var bs []byte
+		switch {
+		case n >= fieldKID && n <= fieldExt && typ == protowire.VarintType:
+			v, l = protowire.ConsumeVarint(b)
+		case n >= fieldKID && n <= fieldExt && typ == protowire.BytesType:
+			bs, l = protowire.ConsumeBytes(b)
+		case n >= fieldKID && n <= fieldExt:
+			return ErrMessage
+		default:
+			l = protowire.ConsumeFieldValue(n, typ, b)
+		}
+		if l < 0 {
+			return ErrMessage
+		}
+		b = b[l:]
+		ok := true
+		switch n {
+		case fieldKID:
+			m.KID, ok = uint16(v), v <= 1<<16-1 && typ == protowire.VarintType
+		case fieldCtr:
+			m.Ctr, ok = uint32(v), v <= 1<<32-1 && typ == protowire.VarintType
+		case fieldEpoch:
+			m.Epoch, ok = uint32(v), v <= 1<<32-1 && typ == protowire.VarintType
+		case fieldVersion:
+			m.Version, ok = uint8(v), v <= 255 && typ == protowire.VarintType
+		case fieldFlags:
+			m.Flags, ok = uint8(v), v <= 255 && typ == protowire.VarintType
+		case fieldCT:
+			m.CT, ok = bs, typ == protowire.BytesType
+		case fieldTag:
+			m.Tag, ok = bs, typ == protowire.BytesType
+		case fieldExt:
+			m.Ext, ok = bs, typ == protowire.BytesType
+		}
+		if !ok {
+			return ErrMessage
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package pbframe
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var key = xxtea.NewKey([]byte("0123456789ABCDEF"))
+
+// descriptor returns EncryptedPayload as declared in pbframe.proto.
+func descriptor(t *testing.T) protoreflect.MessageDescriptor {
+	field := func(name string, n int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(n),
+			Type: typ.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()}
+	}
+	u32, by := descriptorpb.FieldDescriptorProto_TYPE_UINT32, descriptorpb.FieldDescriptorProto_TYPE_BYTES
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name: proto.String("pbframe.proto"), Package: proto.String("xxtea.pbframe"), Syntax: proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("EncryptedPayload"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("kid", 1, u32), field("ctr", 2, u32), field("ct", 3, by), field("tag", 4, by),
+				field("epoch", 5, u32), field("version", 6, u32), field("flags", 7, u32), field("ext", 8, by),
+			},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd.Messages().Get(0)
+}
+
+func Test_RoundTrip(t *testing.T) {
+	md := descriptor(t)
+	for _, h := range []envelope.Header{
+		{KeyID: 7, Epoch: 3, Counter: 300},
+		{Version: envelope.Version1, Flags: envelope.FlagTime | envelope.FlagLength, Time: 1700000000, KeyID: 1},
+	} {
+		f, _ := envelope.SealPadded(key, h, []byte("hello"))
+		m, err := FromFrame(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := m.Marshal()
+		dm := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(b, dm); err != nil {
+			t.Fatal("Not a valid message", err)
+		}
+		if ob, _ := (proto.MarshalOptions{Deterministic: true}).Marshal(dm); !bytes.Equal(ob, b) {
+			t.Error("Encoding differs from protobuf runtime")
+		}
+		var g EncryptedPayload
+		if err := g.Unmarshal(append(b, protowire.AppendVarint(protowire.AppendTag(nil, 99, protowire.VarintType), 1)...)); err != nil {
+			t.Fatal("Unmarshal failed", err)
+		}
+		gf, err := g.Frame()
+		if err != nil || !bytes.Equal(gf, f) {
+			t.Error("Frame not restored", err)
+		}
+		if _, p, err := envelope.Open(key, gf); err != nil || string(p[:5]) != "hello" {
+			t.Error("Restored frame not opened", err)
+		}
+	}
+	var m EncryptedPayload
+	for _, b := range [][]byte{
+		{0x08},                   // truncated varint
+		{0x08, 0x80, 0x80, 0x04}, // kid over 16 bits
+		{0x1a, 0x05, 1},          // truncated bytes
+		{0x0a, 0x00},             // kid as bytes
+	} {
+		if err := m.Unmarshal(b); err != ErrMessage {
+			t.Error("Bad message taken", b, err)
+		}
+	}
+	m = EncryptedPayload{Version: 2, Ext: []byte{1}, CT: make([]byte, 12), Tag: make([]byte, 16)}
+	if _, err := m.Frame(); err == nil {
+		t.Error("Frame of bad extension built")
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrPermutation is returned by NewPermuter for malformed specifications.
+var ErrPermutation = errors.New("xxtea: bad byte permutation")
+
+// Permuter reorders bytes within every chunk of a buffer, for the oddball
+// byte orders not covered by As... functions.  Chunk size is the length of
+// the permutation.
+type Permuter struct {
+	p []int // out[i] = in[p[i]]
+}
+
+// NewPermuter compiles a comma separated permutation of chunk byte
+// indexes: output byte i of every chunk is the input byte at the i-th
+// index.  Eg. "3,2,1,0" is AsLEBE, and "1,0,3,2" is the legacy AsLB16.
+// Chunks can be 2 to 16 bytes long.
+func NewPermuter(spec string) (*Permuter, error) {
+	f := strings.Split(spec, ",")
+	if len(f) < 2 || len(f) > 16 {
+		return nil, ErrPermutation
+	}
+	p := make([]int, len(f))
+	var seen uint32
+	for i, s := range f {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 0 || n >= len(f) || seen&(1<<n) != 0 {
+			return nil, ErrPermutation
+		}
+		seen |= 1 << n
+		p[i] = n
+	}
+	return &Permuter{p}, nil
+}
+
+// Size returns the chunk size.
+func (pm *Permuter) Size() int {
+	return len(pm.p)
+}
+
+// Inverse returns the Permuter undoing this one.
+func (pm *Permuter) Inverse() *Permuter {
+	q := make([]int, len(pm.p))
+	for i, n := range pm.p {
+		q[n] = i
+	}
+	return &Permuter{q}
+}
+
+// Apply does its juggling in-place then returns the same 'd' slice.
+// It expects len(d) to be at least the chunk size and divisible by it.
+func (pm *Permuter) Apply(d []byte) []byte {
+	n := len(pm.p)
+	if len(d) < n {
+		panic(ErrMsgShort)
+	}
+	if len(d)%n != 0 {
+		panic(ErrMsgAlign)
+	}
+	var c [16]byte
+	for i := 0; i < len(d); i += n {
+		copy(c[:], d[i:i+n])
+		for j, k := range pm.p {
+			d[i+j] = c[k]
+		}
+	}
+	return d
+}
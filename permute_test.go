@@ -0,0 +1,37 @@
+package xxtea
+
+import "testing"
+
+func Test_Permuter(t *testing.T) {
+	pm, err := NewPermuter("3,2,1,0")
+	if err != nil || string(pm.Apply([]byte(keyLEBE))) != keyBEBE {
+		t.Error("Permuter 3,2,1,0 is not AsLEBE", err)
+	}
+	pm, err = NewPermuter(" 1, 0 ")
+	if err != nil || pm.Size() != 2 || string(pm.Apply([]byte("1032547698BADCFE"))) != keyBEBE {
+		t.Error("Permuter 1,0 failed", err)
+	}
+	pm, _ = NewPermuter("2,3,0,1,7,4,6,5")
+	d := pm.Apply([]byte(keyBEBE))
+	if string(d) != "23017465AB89FCED" || string(pm.Inverse().Apply(d)) != keyBEBE {
+		t.Error("Inverse does not undo permutation")
+	}
+	if string(pm.Apply([]byte("01234567"))) != "23017465" {
+		t.Error("Permuter 2,3,0,1,7,4,6,5 failed")
+	}
+	for _, s := range []string{"", "0", "0,0", "0,2", "1,-0,", "a,b", "0,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16"} {
+		if _, err := NewPermuter(s); err != ErrPermutation {
+			t.Error("Bad permutation accepted:", s)
+		}
+	}
+}
+
+func Test_Permuter_Panics(t *testing.T) {
+	pm, _ := NewPermuter("1,2,0")
+	defer func() {
+		if recover() == nil {
+			t.Error("bad length should panic")
+		}
+	}()
+	pm.Apply(make([]byte, 4))
+}
@@ -0,0 +1,286 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package profile bundles word order, padding, MAC length and counter
+// policy into named presets, so integrators pick one vetted combination
+// instead of assembling options of several packages by hand:
+//
+//	c, err := profile.New(profile.ProfileIoTDefault, key)
+//	frame, err := c.Seal(reading)
+//	...
+//	reading, err := peer.Open(frame)
+//
+// Presets:
+//
+//	ProfileLegacyRaw   bare XXTEA blocks, zero padded; no MAC, no counter
+//	ProfileIoTDefault  envelope frames, 16B MAC, counters strictly increasing
+//	ProfileInterPHP    the compat format of xxtea-js, PHP and alike
+//	ProfileBroadcast   envelope frames, 8B MAC, time ordered, 5 minutes skew
+//
+// Profiles other than the presets can be put together, and New rejects
+// combinations that do not hold together with ErrProfile.  Profiles
+// without a MAC neither authenticate nor detect replays; they are for
+// talking to devices that can not do better.
+package profile
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/compat"
+	"github.com/ohir/xxtea/envelope"
+)
+
+// Format is the wire format of a Profile.
+type Format uint8
+
+const (
+	FormatRaw      Format = iota // bare XXTEA blocks, padded as Pad says
+	FormatCompat                 // compat package format, any length
+	FormatEnvelope               // envelope frames
+)
+
+// CounterPolicy tells how frames of an envelope Profile are ordered and
+// what Open takes as a replay.
+type CounterPolicy uint8
+
+const (
+	// CounterNone numbers frames, and Open takes them in any order.
+	CounterNone CounterPolicy = iota
+	// CounterStrict numbers frames within an epoch set from the clock at
+	// New, and Open takes only frames newer than the last one.
+	CounterStrict
+	// CounterTime timestamps frames, and Open takes only frames newer than
+	// the last one by time, then counter, and within MaxSkew of its clock.
+	// Senders need no durable counter.
+	CounterTime
+)
+
+// Profile is a combination of options of a link.
+type Profile struct {
+	Name    string
+	Format  Format
+	Order   xxtea.WordOrder // word order of FormatRaw, WordsLE for FormatCompat
+	Pad     xxtea.PadPolicy // padding of FormatRaw
+	MAC     int             // tag size of FormatEnvelope: envelope.TagSizeV1 or TagSizeV2
+	Counter CounterPolicy   // FormatEnvelope only
+	MaxSkew time.Duration   // accepted clock skew of CounterTime, zero for any
+}
+
+// Presets.
+var (
+	ProfileLegacyRaw = Profile{
+		Name:  "legacy-raw",
+		Order: xxtea.WordsBE,
+		Pad:   xxtea.PadZero,
+	}
+	ProfileIoTDefault = Profile{
+		Name:    "iot-default",
+		Format:  FormatEnvelope,
+		MAC:     envelope.TagSizeV2,
+		Counter: CounterStrict,
+	}
+	ProfileInterPHP = Profile{
+		Name:   "inter-php",
+		Format: FormatCompat,
+		Order:  xxtea.WordsLE,
+	}
+	ProfileBroadcast = Profile{
+		Name:    "broadcast",
+		Format:  FormatEnvelope,
+		MAC:     envelope.TagSizeV1,
+		Counter: CounterTime,
+		MaxSkew: 5 * time.Minute,
+	}
+)
+
+var ErrProfile = errors.New("profile: options do not hold together")
+
+// check returns ErrProfile for combinations New does not take.
+func (p *Profile) check() error {
+	ok := false
+	switch p.Format {
+	case FormatRaw:
+		ok = p.MAC == 0 && p.Counter == CounterNone && p.MaxSkew == 0 &&
+			(p.Order == xxtea.WordsBE && p.Pad <= xxtea.PadZero ||
+				p.Order == xxtea.WordsLE && (p.Pad == xxtea.PadNone || p.Pad == xxtea.PadZero))
+	case FormatCompat:
+		ok = p.Order == xxtea.WordsLE && p.Pad == xxtea.PadNone && p.MAC == 0 &&
+			p.Counter == CounterNone && p.MaxSkew == 0
+	case FormatEnvelope:
+		ok = p.Order == xxtea.WordsBE && p.Pad == xxtea.PadNone &&
+			(p.MAC == envelope.TagSizeV1 || p.MAC == envelope.TagSizeV2) &&
+			p.Counter <= CounterTime && (p.MaxSkew == 0 || p.Counter == CounterTime)
+	}
+	if !ok {
+		return ErrProfile
+	}
+	return nil
+}
+
+// Codec seals and opens payloads of a link as its Profile says.  A Codec
+// keeps the sending counter and the state of the last frame opened, so
+// each side of a link needs one.
+//
+// Codec is not safe for concurrent use.
+type Codec struct {
+	KeyID uint16 // key-id of sealed envelope frames
+
+	p    Profile
+	key  xxtea.TeaKey
+	h    envelope.Header
+	last envelope.State
+	seen bool
+}
+
+// New returns a Codec of the profile under the key.  It returns ErrProfile
+// if the profile options do not hold together.
+func New(p Profile, key xxtea.TeaKey) (*Codec, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	c := &Codec{p: p, key: key}
+	if p.Format == FormatEnvelope {
+		c.h.Version = envelope.Version2
+		if p.MAC == envelope.TagSizeV1 {
+			c.h.Version = envelope.Version1
+		}
+		c.h.Flags = envelope.FlagLength
+		switch p.Counter {
+		case CounterStrict:
+			c.h.Epoch = uint32(xxtea.Now().Unix())
+		case CounterTime:
+			c.h.Flags |= envelope.FlagTime
+		}
+	}
+	return c, nil
+}
+
+// Profile returns the profile of the Codec.
+func (c *Codec) Profile() Profile {
+	return c.p
+}
+
+// Seal returns the payload sealed as the profile says.  Lengths the
+// profile can not take give xxtea.ErrMsgShort or ErrMsgLong, or the error
+// of envelope.Seal.
+func (c *Codec) Seal(payload []byte) ([]byte, error) {
+	switch c.p.Format {
+	case FormatRaw:
+		if err := c.rawLen(len(payload)); err != nil {
+			return nil, err
+		}
+		if c.p.Order == xxtea.WordsBE {
+			return c.key.EncryptAny(payload, c.p.Pad), nil
+		}
+		n := len(payload)
+		if c.p.Pad == xxtea.PadZero {
+			n = (n + 3) &^ 3
+			if n < 12 {
+				n = 12
+			}
+		}
+		b := make([]byte, n)
+		copy(b, payload)
+		xxtea.AsLEBE(b)
+		return xxtea.AsLEBE(c.key.Encrypt(b, b)), nil
+	case FormatCompat:
+		if len(payload) == 0 {
+			return nil, xxtea.ErrMsgShort
+		}
+		return compat.EncryptKey(payload, c.key), nil
+	}
+	h := c.h
+	h.KeyID = c.KeyID
+	f, err := envelope.Seal(c.key, h, payload)
+	if err != nil {
+		return nil, err
+	}
+	if c.h.Counter++; c.h.Counter == 0 {
+		c.h.Epoch++
+	}
+	return f, nil
+}
+
+// rawLen returns the misuse code of a FormatRaw payload length, or nil.
+func (c *Codec) rawLen(n int) error {
+	max := 208
+	switch c.p.Pad {
+	case xxtea.PadNone:
+		if n < 12 {
+			return xxtea.ErrMsgShort
+		}
+		if n&3 != 0 {
+			return xxtea.ErrMsgAlign
+		}
+	case xxtea.PadISO, xxtea.PadPKCS7:
+		max = 207
+	case xxtea.PadClearTail:
+		if n < 12 {
+			return xxtea.ErrMsgShort
+		}
+		max = 211
+	}
+	if n > max {
+		return xxtea.ErrMsgLong
+	}
+	return nil
+}
+
+// Open returns the payload of a message sealed by a Codec of the same
+// profile and key.  Envelope frames are checked against the counter
+// policy: frames not newer than the last one opened give
+// envelope.ErrReplay, frames out of MaxSkew envelope.ErrSkew.  Frames of
+// other MAC length, and every malformed or forged frame, give
+// envelope.ErrOpenFailed.
+func (c *Codec) Open(msg []byte) ([]byte, error) {
+	switch c.p.Format {
+	case FormatRaw:
+		if n := len(msg); (c.p.Pad == xxtea.PadNone || c.p.Order == xxtea.WordsLE) && (n < 12 || n > 208 || n&3 != 0) {
+			return nil, xxtea.ErrPadding
+		}
+		if c.p.Order == xxtea.WordsBE {
+			return c.key.DecryptAny(msg, c.p.Pad)
+		}
+		b := xxtea.AsLEBE(append([]byte(nil), msg...))
+		xxtea.AsLEBE(c.key.Decrypt(b, b))
+		if c.p.Pad == xxtea.PadZero {
+			for len(b) > 0 && b[len(b)-1] == 0 {
+				b = b[:len(b)-1]
+			}
+		}
+		return b, nil
+	case FormatCompat:
+		return compat.DecryptKey(msg, c.key)
+	}
+	h, p, err := envelope.Open(c.key, msg)
+	if err != nil || h.Version != c.h.Version || h.Flags != c.h.Flags {
+		return nil, envelope.ErrOpenFailed
+	}
+	if c.p.Counter == CounterTime && c.p.MaxSkew > 0 {
+		d := xxtea.Now().Sub(time.Unix(int64(h.Time), 0))
+		if d > c.p.MaxSkew || d < -c.p.MaxSkew {
+			return nil, envelope.ErrSkew
+		}
+	}
+	if c.seen && !c.after(&h) {
+		return nil, envelope.ErrReplay
+	}
+	c.last = envelope.State{Epoch: h.Epoch, Counter: h.Counter, Time: h.Time, Version: h.Version}
+	c.seen = true
+	return p, nil
+}
+
+// after reports whether the frame comes after the last one opened, as the
+// counter policy orders them.
+func (c *Codec) after(h *envelope.Header) bool {
+	switch c.p.Counter {
+	case CounterStrict:
+		return h.Epoch > c.last.Epoch || h.Epoch == c.last.Epoch && h.Counter > c.last.Counter
+	case CounterTime:
+		return h.Time > c.last.Time || h.Time == c.last.Time && h.Counter > c.last.Counter
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package profile
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/compat"
+	"github.com/ohir/xxtea/envelope"
+)
+
+const keyBEBE = "0123456789ABCDEF"
+
+func Test_Presets(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	for _, p := range []Profile{ProfileLegacyRaw, ProfileIoTDefault, ProfileInterPHP, ProfileBroadcast,
+		{Name: "raw-le", Order: xxtea.WordsLE, Pad: xxtea.PadZero}} {
+		tx, err := New(p, key)
+		if err != nil {
+			t.Fatal("Preset rejected", p.Name, err)
+		}
+		rx, _ := New(p, key)
+		for _, n := range []int{1, 12, 13, 100, 207} {
+			pt := bytes.Repeat([]byte{'x'}, n)
+			m, err := tx.Seal(pt)
+			if err != nil {
+				t.Fatal("Seal failed", p.Name, n, err)
+			}
+			if got, err := rx.Open(m); err != nil || !bytes.Equal(got, pt) {
+				t.Error("Open failed", p.Name, n, err)
+			}
+		}
+	}
+}
+
+func Test_Wire(t *testing.T) {
+	key := xxtea.NewKeyCompat("1234567890")
+	c, _ := New(ProfileInterPHP, key)
+	m, _ := c.Seal([]byte("Hello"))
+	if p, err := compat.Decrypt(m, []byte("1234567890")); err != nil || string(p) != "Hello" {
+		t.Error("InterPHP is not the compat format", err)
+	}
+	c, _ = New(ProfileBroadcast, key)
+	f, _ := c.Seal([]byte("hi"))
+	if h, _ := envelope.ParseHeader(f); h.Version != envelope.Version1 || h.Flags&envelope.FlagTime == 0 {
+		t.Error("Broadcast frame options wrong", h)
+	}
+	c, _ = New(Profile{Order: xxtea.WordsLE, Pad: xxtea.PadNone}, key)
+	m, _ = c.Seal([]byte(keyBEBE))
+	if !bytes.Equal(m, xxtea.AsLEBE(key.Encrypt(xxtea.AsLEBE([]byte(keyBEBE)), make([]byte, 16)))) {
+		t.Error("LE words not juggled")
+	}
+}
+
+func Test_Replay(t *testing.T) {
+	defer xxtea.SetClock(nil)
+	at := time.Unix(1700000000, 0)
+	xxtea.SetClock(xxtea.ClockFunc(func() time.Time { return at }))
+	key := xxtea.NewKey([]byte(keyBEBE))
+	for _, p := range []Profile{ProfileIoTDefault, ProfileBroadcast} {
+		tx, _ := New(p, key)
+		rx, _ := New(p, key)
+		f1, _ := tx.Seal([]byte("one"))
+		f2, _ := tx.Seal([]byte("two"))
+		if _, err := rx.Open(f2); err != nil {
+			t.Error("Open failed", p.Name, err)
+		}
+		if _, err := rx.Open(f1); err != envelope.ErrReplay {
+			t.Error("Older frame accepted", p.Name, err)
+		}
+		if _, err := rx.Open(f2); err != envelope.ErrReplay {
+			t.Error("Replay accepted", p.Name, err)
+		}
+	}
+	tx, _ := New(ProfileBroadcast, key)
+	rx, _ := New(ProfileIoTDefault, key)
+	f, _ := tx.Seal([]byte("hi"))
+	if _, err := rx.Open(f); err != envelope.ErrOpenFailed {
+		t.Error("Frame of other profile accepted", err)
+	}
+	rx, _ = New(ProfileBroadcast, key)
+	at = at.Add(time.Hour)
+	if _, err := rx.Open(f); err != envelope.ErrSkew {
+		t.Error("Stale broadcast accepted", err)
+	}
+}
+
+func Test_Check(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	for _, p := range []Profile{
+		{Format: FormatRaw, MAC: envelope.TagSizeV2},
+		{Format: FormatRaw, Order: xxtea.WordsLE, Pad: xxtea.PadClearTail},
+		{Format: FormatCompat},
+		{Format: FormatEnvelope, MAC: 4},
+		{Format: FormatEnvelope, MAC: envelope.TagSizeV2, Pad: xxtea.PadISO},
+		{Format: FormatEnvelope, MAC: envelope.TagSizeV2, Counter: CounterStrict, MaxSkew: time.Second},
+		{Format: FormatEnvelope + 1},
+	} {
+		if _, err := New(p, key); err != ErrProfile {
+			t.Error("Bad profile accepted", p, err)
+		}
+	}
+	c, _ := New(ProfileLegacyRaw, key)
+	if _, err := c.Seal(make([]byte, 209)); err != xxtea.ErrMsgLong {
+		t.Error("Long payload sealed", err)
+	}
+	c, _ = New(Profile{Pad: xxtea.PadNone}, key)
+	if _, err := c.Open(make([]byte, 13)); err != xxtea.ErrPadding {
+		t.Error("Bad raw length opened", err)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package promstats exports session.Stats as Prometheus metrics.
+//
+// It is a separate module, so that the xxtea module does not depend on
+// the Prometheus client.
+package promstats
+
+import (
+	"github.com/ohir/xxtea/session"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metric struct {
+	desc *prometheus.Desc
+	val  func(c *session.Counts) uint64
+}
+
+// Collector is a prometheus.Collector of a session.Stats.
+type Collector struct {
+	stats   *session.Stats
+	metrics []metric
+}
+
+// NewCollector returns a Collector of the stats, with the constant labels
+// (eg. link or device) on every metric.
+func NewCollector(stats *session.Stats, labels prometheus.Labels) *Collector {
+	d := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("xxtea_session_"+name, help, nil, labels)
+	}
+	return &Collector{stats, []metric{
+		{d("frames_sealed_total", "Frames sealed."), func(c *session.Counts) uint64 { return c.Sealed }},
+		{d("frames_opened_total", "Frames opened."), func(c *session.Counts) uint64 { return c.Opened }},
+		{d("mac_failures_total", "Frames that failed to open: forged, malformed or of other key-id."), func(c *session.Counts) uint64 { return c.MACFailures }},
+		{d("replay_drops_total", "Frames dropped as replayed or too old."), func(c *session.Counts) uint64 { return c.ReplayDrops }},
+		{d("sealed_bytes_total", "Payload bytes sealed."), func(c *session.Counts) uint64 { return c.BytesSealed }},
+		{d("opened_bytes_total", "Payload bytes opened."), func(c *session.Counts) uint64 { return c.BytesOpened }},
+	}}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics {
+		ch <- m.desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	counts := c.stats.Counts()
+	for _, m := range c.metrics {
+		ch <- prometheus.MustNewConstMetric(m.desc, prometheus.CounterValue, float64(m.val(&counts)))
+	}
+}
@@ -0,0 +1,42 @@
+package promstats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/session"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_Collector(t *testing.T) {
+	key := xxtea.NewKey([]byte("0123456789ABCDEF"))
+	st := &session.Stats{}
+	s, r := session.NewSender(key), session.NewReceiver(key)
+	s.Stats, r.Stats = st, st
+	f, _ := s.Send([]byte("hello"))
+	r.Receive(f)
+	r.Receive(f)
+
+	c := NewCollector(st, prometheus.Labels{"link": "uplink"})
+	exp := `
+# HELP xxtea_session_frames_sealed_total Frames sealed.
+# TYPE xxtea_session_frames_sealed_total counter
+xxtea_session_frames_sealed_total{link="uplink"} 1
+# HELP xxtea_session_replay_drops_total Frames dropped as replayed or too old.
+# TYPE xxtea_session_replay_drops_total counter
+xxtea_session_replay_drops_total{link="uplink"} 1
+# HELP xxtea_session_opened_bytes_total Payload bytes opened.
+# TYPE xxtea_session_opened_bytes_total counter
+xxtea_session_opened_bytes_total{link="uplink"} 5
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(exp),
+		"xxtea_session_frames_sealed_total", "xxtea_session_replay_drops_total", "xxtea_session_opened_bytes_total")
+	if err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(c); n != 6 {
+		t.Error("Collector gives", n, "metrics")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ratchet gives every packet of a one-way link its own key by
+// advancing a hash chain, for sensor uplinks that want per-packet keys
+// (and forward secrecy) without handshakes.
+//
+//	chain[0]   = root.Derive("chain")
+//	message[i] = chain[i].Derive("msg")
+//	chain[i+1] = chain[i].Derive("chain")
+//
+// Packets are envelope frames sealed under message[i] with counter i.
+// Receiver catches up over lost packets, at most MaxGap at once, and
+// forgets passed chain keys, so late or replayed packets are rejected.
+package ratchet
+
+import (
+	"errors"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+// DefaultMaxGap is the MaxGap of a new Receiver.
+const DefaultMaxGap = 64
+
+var (
+	ErrOld = errors.New("ratchet: packet is older than the chain")
+	ErrGap = errors.New("ratchet: too many packets lost")
+	ErrEnd = errors.New("ratchet: chain exhausted")
+)
+
+var (
+	lblChain = []byte("chain")
+	lblMsg   = []byte("msg")
+)
+
+// ChainState is the chain key for the packet Index.  It is all the state
+// a side needs to persist.
+type ChainState struct {
+	Key   xxtea.TeaKey
+	Index uint32
+}
+
+// NewChain returns the chain state of the first packet under the root key.
+func NewChain(root xxtea.TeaKey) ChainState {
+	return ChainState{Key: root.Derive(lblChain)}
+}
+
+// Next returns the key and index of the current packet and advances the
+// chain.
+func (c *ChainState) Next() (key xxtea.TeaKey, idx uint32) {
+	key, idx = c.Key.Derive(lblMsg), c.Index
+	c.Key = c.Key.Derive(lblChain)
+	c.Index++
+	return
+}
+
+// Sender seals packets, each under the next key of the chain.
+type Sender struct {
+	Chain ChainState
+	KeyID uint16
+}
+
+// NewSender returns a Sender starting a new chain under the root key.
+func NewSender(root xxtea.TeaKey, kid uint16) *Sender {
+	return &Sender{Chain: NewChain(root), KeyID: kid}
+}
+
+// Seal returns payload sealed into the next packet.
+func (s *Sender) Seal(payload []byte) ([]byte, error) {
+	if s.Chain.Index == ^uint32(0) {
+		return nil, ErrEnd
+	}
+	c := s.Chain
+	k, i := c.Next()
+	frame, err := envelope.Seal(k, envelope.Header{KeyID: s.KeyID, Counter: i}, payload)
+	if err != nil {
+		return nil, err
+	}
+	s.Chain = c
+	return frame, nil
+}
+
+// Receiver opens packets of a chain.
+type Receiver struct {
+	Chain  ChainState
+	MaxGap uint32 // packets that may be skipped at once
+}
+
+// NewReceiver returns a Receiver of a new chain under the root key.
+func NewReceiver(root xxtea.TeaKey) *Receiver {
+	return &Receiver{Chain: NewChain(root), MaxGap: DefaultMaxGap}
+}
+
+// Open authenticates the packet and returns its header and payload.  The
+// chain is advanced past the packet only if it authenticates.
+func (r *Receiver) Open(frame []byte) (envelope.Header, []byte, error) {
+	h, err := envelope.ParseHeader(frame)
+	if err != nil {
+		return h, nil, err
+	}
+	if h.Counter < r.Chain.Index {
+		return h, nil, ErrOld
+	}
+	if h.Counter-r.Chain.Index > r.MaxGap {
+		return h, nil, ErrGap
+	}
+	c := r.Chain
+	for c.Index < h.Counter {
+		c.Next()
+	}
+	k, _ := c.Next()
+	h, p, err := envelope.Open(k, frame)
+	if err != nil {
+		return h, nil, err
+	}
+	r.Chain = c
+	return h, p, nil
+}
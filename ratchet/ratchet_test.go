@@ -0,0 +1,83 @@
+package ratchet
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+const msg16 = `Sixteen bytes!!!`
+
+var root = xxtea.NewKey([]byte("0123456789ABCDEF"))
+
+func Test_Chain(t *testing.T) {
+	c := NewChain(root)
+	k0, i0 := c.Next()
+	k1, i1 := c.Next()
+	if i0 != 0 || i1 != 1 || c.Index != 2 || k0 == k1 || k0 == root {
+		t.Error("Chain does not advance")
+	}
+	if d := NewChain(root); d.Key == c.Key {
+		t.Error("Chain key not advanced")
+	}
+}
+
+func Test_SendReceive(t *testing.T) {
+	s, r := NewSender(root, 7), NewReceiver(root)
+	var frames [][]byte
+	for i := 0; i < 5; i++ {
+		f, err := s.Seal([]byte(msg16))
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, f)
+	}
+	if h, p, err := r.Open(frames[0]); err != nil || h.Counter != 0 || h.KeyID != 7 || string(p) != msg16 {
+		t.Error("First packet not opened", err)
+	}
+	if _, _, err := r.Open(frames[0]); err != ErrOld {
+		t.Error("Replay accepted", err)
+	}
+	if _, _, err := r.Open(frames[3]); err != nil || r.Chain.Index != 4 {
+		t.Error("Lost packets not caught up", err)
+	}
+	if _, _, err := r.Open(frames[2]); err != ErrOld {
+		t.Error("Late packet accepted", err)
+	}
+	forged := append([]byte(nil), frames[4]...)
+	forged[len(forged)-1] ^= 1
+	if _, _, err := r.Open(forged); err != envelope.ErrOpenFailed || r.Chain.Index != 4 {
+		t.Error("Forged packet moved the chain", err)
+	}
+	if _, _, err := r.Open(frames[4]); err != nil {
+		t.Error("Packet after forgery not opened", err)
+	}
+}
+
+func Test_Gap(t *testing.T) {
+	s, r := NewSender(root, 0), NewReceiver(root)
+	r.MaxGap = 2
+	var f []byte
+	for i := 0; i < 4; i++ {
+		f, _ = s.Seal([]byte(msg16))
+	}
+	if _, _, err := r.Open(f); err != ErrGap {
+		t.Error("Gap over MaxGap accepted", err)
+	}
+	r.MaxGap = 3
+	if _, _, err := r.Open(f); err != nil {
+		t.Error("Gap of MaxGap not accepted", err)
+	}
+	s.Chain.Index = ^uint32(0)
+	if _, err := s.Seal([]byte(msg16)); err != ErrEnd {
+		t.Error("Exhausted chain used", err)
+	}
+	if _, err := s.Seal(nil); err != ErrEnd {
+		t.Error("Exhausted chain used", err)
+	}
+	s.Chain.Index = 0
+	if _, err := s.Seal(nil); err != envelope.ErrLength || s.Chain.Index != 0 {
+		t.Error("Failed seal moved the chain", err)
+	}
+}
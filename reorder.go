@@ -0,0 +1,108 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import (
+	"errors"
+	"io"
+)
+
+// WordOrder is the byte order of 4B words in a stream.  Chunk order of
+// a stream can not be reversed without reading all of it, so stream
+// reordering deals with byte order within words only: WordsLE to WordsBE
+// is AsLEBE applied to every word.
+type WordOrder uint8
+
+const (
+	WordsBE WordOrder = iota // big-endian words, as XXTEA serializes them
+	WordsLE                  // little-endian words, raw memory of LE devices
+)
+
+// ErrPartialWord is returned by reordering streams that end within a word.
+var ErrPartialWord = errors.New("xxtea: stream ends within a 4B word")
+
+// ReorderReader reads a stream converting its words from one order to
+// another.
+type ReorderReader struct {
+	r    io.Reader
+	swap bool
+	buf  [4]byte
+	n    int // bytes of a partial word in buf
+}
+
+// NewReorderReader returns a reader of r with words converted from the
+// 'from' order to the 'to' order.  It returns ErrPartialWord if r ends
+// within a word.
+func NewReorderReader(r io.Reader, from, to WordOrder) *ReorderReader {
+	return &ReorderReader{r: r, swap: from != to}
+}
+
+// Read implements io.Reader.  It returns whole words only.
+func (rr *ReorderReader) Read(p []byte) (int, error) {
+	if len(p) < 4 {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.ErrShortBuffer
+	}
+	p = p[:len(p)&^3]
+	n := copy(p, rr.buf[:rr.n])
+	m, err := io.ReadAtLeast(rr.r, p[n:], 4-n)
+	n += m
+	if err == io.ErrUnexpectedEOF || err == io.EOF && n > 0 {
+		err = ErrPartialWord
+	}
+	w := n &^ 3
+	rr.n = copy(rr.buf[:], p[w:n])
+	if rr.swap && w > 0 {
+		AsLEBE(p[:w])
+	}
+	return w, err
+}
+
+// ReorderWriter writes a stream converting its words from one order to
+// another.
+type ReorderWriter struct {
+	w    io.Writer
+	swap bool
+	buf  []byte
+	part [4]byte
+	n    int // bytes of a partial word in part
+}
+
+// NewReorderWriter returns a writer to w with words converted from the
+// 'from' order to the 'to' order.  Close must be called to check that the
+// stream ended on a word boundary.
+func NewReorderWriter(w io.Writer, from, to WordOrder) *ReorderWriter {
+	return &ReorderWriter{w: w, swap: from != to}
+}
+
+// Write implements io.Writer.  A trailing partial word is held until more
+// bytes are written.
+func (rw *ReorderWriter) Write(p []byte) (int, error) {
+	total := len(p)
+	rw.buf = append(append(rw.buf[:0], rw.part[:rw.n]...), p...)
+	w := len(rw.buf) &^ 3
+	rw.n = copy(rw.part[:], rw.buf[w:])
+	if w == 0 {
+		return total, nil
+	}
+	if rw.swap {
+		AsLEBE(rw.buf[:w])
+	}
+	if _, err := rw.w.Write(rw.buf[:w]); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Close returns ErrPartialWord if bytes of a partial word were written.
+// It does not close the underlying writer.
+func (rw *ReorderWriter) Close() error {
+	if rw.n != 0 {
+		return ErrPartialWord
+	}
+	return nil
+}
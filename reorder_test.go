@@ -0,0 +1,51 @@
+package xxtea
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func Test_ReorderReader(t *testing.T) {
+	src := []byte(keyLEBE + keyLEBE)
+	for _, r := range []io.Reader{
+		bytes.NewReader(src),
+		iotest.OneByteReader(bytes.NewReader(src)),
+		iotest.HalfReader(bytes.NewReader(src)),
+		iotest.DataErrReader(bytes.NewReader(src)),
+	} {
+		got, err := io.ReadAll(NewReorderReader(r, WordsLE, WordsBE))
+		if err != nil || string(got) != keyBEBE+keyBEBE {
+			t.Error("ReorderReader failed", string(got), err)
+		}
+	}
+	got, err := io.ReadAll(NewReorderReader(bytes.NewReader(src), WordsLE, WordsLE))
+	if err != nil || !bytes.Equal(got, src) {
+		t.Error("ReorderReader changed same order stream")
+	}
+	got, err = io.ReadAll(NewReorderReader(bytes.NewReader(src[:18]), WordsLE, WordsBE))
+	if err != ErrPartialWord || string(got) != keyBEBE {
+		t.Error("Partial word not reported", string(got), err)
+	}
+	if _, err = NewReorderReader(bytes.NewReader(src), WordsBE, WordsLE).Read(make([]byte, 3)); err != io.ErrShortBuffer {
+		t.Error("Short buffer accepted", err)
+	}
+}
+
+func Test_ReorderWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := NewReorderWriter(&b, WordsBE, WordsLE)
+	for _, c := range []string{"0", "12", "3456789", "ABCDEF"} {
+		if n, err := w.Write([]byte(c)); n != len(c) || err != nil {
+			t.Error("Write failed", n, err)
+		}
+	}
+	if err := w.Close(); err != nil || b.String() != keyLEBE {
+		t.Error("ReorderWriter failed", b.String(), err)
+	}
+	w.Write([]byte("01"))
+	if err := w.Close(); err != ErrPartialWord {
+		t.Error("Partial word not reported", err)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+// TeaKey.EncryptRounds is Encrypt doing the given number of rounds
+// instead of 6 + 52/n for n words, to emulate devices whose firmware
+// hardcodes a count, eg. 32.
+//
+// WARNING: output of any other count does not decrypt with Decrypt nor
+// with any conforming XXTEA, and fewer rounds than 6 + 52/n weaken the
+// cipher: the security margin of short messages is in those rounds.  Use
+// it only to talk to such devices.
+//
+// Rounds must be in 1..65536 range; lengths are those of Encrypt.
+func (k TeaKey) EncryptRounds(in, out []byte, rounds int) []byte {
+	chkRounds(in, out, rounds)
+	v, n := loadWords(in)
+	k.encryptN(v[:n], uint32(rounds))
+	return storeWords(v[:n], out)
+}
+
+// TeaKey.DecryptRounds is the inverse of EncryptRounds of the same count.
+func (k TeaKey) DecryptRounds(in, out []byte, rounds int) []byte {
+	chkRounds(in, out, rounds)
+	v, n := loadWords(in)
+	k.decryptN(v[:n], uint32(rounds))
+	return storeWords(v[:n], out)
+}
+
+// chkRounds panics on lengths or rounds EncryptRounds can not take.
+func chkRounds(in, out []byte, rounds int) {
+	if err := chkMsg(len(in), len(out)); err != nil {
+		panic(err)
+	}
+	if rounds < 1 || rounds > 1<<16 {
+		panic(ErrMisuse)
+	}
+}
@@ -0,0 +1,34 @@
+package xxtea
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_EncryptRounds(t *testing.T) {
+	k := NewKey([]byte(keyBEBE))
+	for n := 12; n <= 208; n += 4 {
+		pt := []byte(msgMax)[:n]
+		std := 6 + 52/(n/4)
+		if !bytes.Equal(k.EncryptRounds(pt, make(bs, n), std), k.Encrypt(pt, make(bs, n))) {
+			t.Error("EncryptRounds of standard count differs from Encrypt", n)
+		}
+		ct := k.EncryptRounds(pt, make(bs, n), 32)
+		if std != 32 && bytes.Equal(ct, k.Encrypt(pt, make(bs, n))) {
+			t.Error("EncryptRounds ignored the count", n)
+		}
+		if !bytes.Equal(k.DecryptRounds(ct, ct, 32), pt) {
+			t.Error("DecryptRounds failed", n)
+		}
+	}
+	for _, r := range []int{0, -1, 1<<16 + 1} {
+		func() {
+			defer func() {
+				if recover() != ErrMisuse {
+					t.Error("Bad round count accepted", r)
+				}
+			}()
+			k.EncryptRounds([]byte(msgMin), make(bs, 12), r)
+		}()
+	}
+}
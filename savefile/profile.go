@@ -0,0 +1,60 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package savefile
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+var ErrProfile = errors.New("savefile: unknown profile")
+
+var (
+	profMu   sync.RWMutex
+	profiles = map[string]Codec{
+		// cocos2d-x Lua and JS assets, sign of the project templates
+		"cocos2dx": {Sign: []byte("XXTEA")},
+		// Cocos Creator .jsc scripts, no sign
+		"cocos-creator": {},
+	}
+)
+
+// Register adds a profile: a Codec without the key, describing saves of an
+// engine variant.  Supporting a new variant takes a Register call only.  It
+// panics if the name is taken, as registries of the standard library do.
+func Register(name string, c Codec) {
+	profMu.Lock()
+	defer profMu.Unlock()
+	if _, dup := profiles[name]; dup {
+		panic("savefile: Register called twice for profile " + name)
+	}
+	c.Key = nil
+	profiles[name] = c
+}
+
+// Profile returns a Codec of the named profile under the key.
+func Profile(name string, key []byte) (*Codec, error) {
+	profMu.RLock()
+	c, ok := profiles[name]
+	profMu.RUnlock()
+	if !ok {
+		return nil, ErrProfile
+	}
+	c.Key = key
+	return &c, nil
+}
+
+// Profiles returns sorted names of the registered profiles.
+func Profiles() []string {
+	profMu.RLock()
+	defer profMu.RUnlock()
+	names := make([]string, 0, len(profiles))
+	for n := range profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package savefile reads and writes game saves and assets in the layout
+// used by several mobile game engines (cocos2d-x and derived ones):
+//
+//	sign | checksum (4B, optional) | body
+//
+// Sign is a cleartext marker chosen by the game, eg. "XXTEA".  Checksum is
+// CRC-32 (IEEE) of the plain data, little-endian, obscured by XOR with
+// CRC-32 of the key.  Body is the data encrypted as package compat does,
+// unless the Codec asks for a variant.  Known variants are registered as
+// named profiles.
+//
+// It is a compatibility codec: saves are neither authenticated nor
+// protected against a player who has extracted the key from the game.
+package savefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/compat"
+	"github.com/ohir/xxtea/internal/btea"
+)
+
+var (
+	ErrSign     = errors.New("savefile: sign does not match")
+	ErrChecksum = errors.New("savefile: checksum does not match")
+)
+
+// Codec encodes and decodes saves of a single game.  Zero values of the
+// variant fields give the body of package compat.
+type Codec struct {
+	Sign     []byte // cleartext marker, may be empty
+	Key      []byte
+	Checksum bool // saves carry the obscured checksum
+
+	BigEndian bool // body and key are read as big-endian words
+	ZeroPad   bool // data is zero padded to words (8B at least), no length word
+	KeyRepeat bool // short keys are expanded by repeating, not zero padding
+}
+
+// mask returns the checksum obscuring word.
+func (c *Codec) mask() uint32 {
+	return crc32.ChecksumIEEE(c.Key)
+}
+
+func (c *Codec) order() binary.ByteOrder {
+	if c.BigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// key returns the key prepared as the Codec asks.
+func (c *Codec) key() *[4]uint32 {
+	var b [16]byte
+	n := copy(b[:], c.Key)
+	if c.KeyRepeat && n > 0 {
+		for ; n < 16; n += len(c.Key) {
+			copy(b[n:], c.Key)
+		}
+	}
+	var w [4]uint32
+	for i := range w {
+		w[i] = c.order().Uint32(b[4*i:])
+	}
+	return &w
+}
+
+// encrypt returns the body of data.
+func (c *Codec) encrypt(data []byte) []byte {
+	if !c.BigEndian && !c.ZeroPad && !c.KeyRepeat {
+		return compat.Encrypt(data, c.Key)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	n := (len(data) + 3) / 4
+	v := make([]uint32, n, n+1)
+	var w [4]byte
+	for i := range v {
+		w = [4]byte{}
+		copy(w[:], data[4*i:])
+		v[i] = c.order().Uint32(w[:])
+	}
+	if c.ZeroPad {
+		if n < 2 {
+			v = append(v, 0)
+		}
+	} else {
+		v = append(v, uint32(len(data)))
+	}
+	btea.Encrypt(v, c.key())
+	out := make([]byte, 4*len(v))
+	for i, x := range v {
+		c.order().PutUint32(out[4*i:], x)
+	}
+	return out
+}
+
+// decrypt returns the data of a body.  Data of ZeroPad bodies keeps its
+// padding.
+func (c *Codec) decrypt(body []byte) ([]byte, error) {
+	if !c.BigEndian && !c.ZeroPad && !c.KeyRepeat {
+		return compat.Decrypt(body, c.Key)
+	}
+	if len(body) < 8 || len(body)&3 != 0 {
+		return nil, compat.ErrData
+	}
+	v := make([]uint32, len(body)/4)
+	for i := range v {
+		v[i] = c.order().Uint32(body[4*i:])
+	}
+	btea.Decrypt(v, c.key())
+	out := make([]byte, len(body))
+	for i, x := range v {
+		c.order().PutUint32(out[4*i:], x)
+	}
+	if c.ZeroPad {
+		return out, nil
+	}
+	n := len(out) - 4
+	m := int64(v[len(v)-1])
+	if m < int64(n-3) || m > int64(n) {
+		return nil, compat.ErrData
+	}
+	if xxtea.Strict() {
+		for _, b := range out[m:n] {
+			if b != 0 {
+				return nil, compat.ErrData
+			}
+		}
+	}
+	return out[:m], nil
+}
+
+// Encode returns data encoded as a save.
+func (c *Codec) Encode(data []byte) []byte {
+	out := append([]byte(nil), c.Sign...)
+	if c.Checksum {
+		var sum [4]byte
+		binary.LittleEndian.PutUint32(sum[:], crc32.ChecksumIEEE(c.unpadded(data))^c.mask())
+		out = append(out, sum[:]...)
+	}
+	return append(out, c.encrypt(data)...)
+}
+
+// Decode returns the data of a save.  It returns ErrSign for files
+// without the sign, ErrChecksum if decrypted data does not match the
+// checksum and compat.ErrData for bodies not encrypted under the key.
+//
+// ZeroPad bodies tell no length: their data is returned with the padding,
+// checksum is computed over the data without trailing zeros, and a wrong
+// key goes unnoticed without a checksum.
+func (c *Codec) Decode(save []byte) ([]byte, error) {
+	if !bytes.HasPrefix(save, c.Sign) {
+		return nil, ErrSign
+	}
+	save = save[len(c.Sign):]
+	var sum uint32
+	if c.Checksum {
+		if len(save) < 4 {
+			return nil, compat.ErrData
+		}
+		sum = binary.LittleEndian.Uint32(save) ^ c.mask()
+		save = save[4:]
+	}
+	if len(save) == 0 {
+		return nil, nil // empty data has an empty body
+	}
+	data, err := c.decrypt(save)
+	if err != nil {
+		return nil, err
+	}
+	if c.Checksum && crc32.ChecksumIEEE(c.unpadded(data)) != sum {
+		return nil, ErrChecksum
+	}
+	return data, nil
+}
+
+// unpadded returns data without the padding of a ZeroPad body.
+func (c *Codec) unpadded(data []byte) []byte {
+	if c.ZeroPad {
+		data = bytes.TrimRight(data, "\x00")
+	}
+	return data
+}
@@ -0,0 +1,106 @@
+package savefile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ohir/xxtea/compat"
+)
+
+func Test_RoundTrip(t *testing.T) {
+	data := []byte(`{"level":7,"coins":12345}`)
+	for _, c := range []*Codec{
+		{Key: []byte("2dxLua")},
+		{Sign: []byte("XXTEA"), Key: []byte("2dxLua")},
+		{Sign: []byte("SAV1"), Key: []byte("0123456789ABCDEF"), Checksum: true},
+	} {
+		save := c.Encode(data)
+		if !bytes.HasPrefix(save, c.Sign) || bytes.Contains(save, data[:8]) {
+			t.Error("Save layout wrong", c.Sign)
+		}
+		if d, err := c.Decode(save); err != nil || !bytes.Equal(d, data) {
+			t.Error("Round trip failed", c.Sign, err)
+		}
+		if d, err := c.Decode(c.Encode(nil)); err != nil || len(d) != 0 {
+			t.Error("Empty save failed", c.Sign, err)
+		}
+	}
+}
+
+func Test_Cocos(t *testing.T) {
+	// the body is plain compat ciphertext after the sign
+	c := &Codec{Sign: []byte("XXTEA"), Key: []byte("2dxLua")}
+	save := c.Encode([]byte("print('hi')"))
+	if d, err := compat.Decrypt(save[5:], c.Key); err != nil || string(d) != "print('hi')" {
+		t.Error("Body is not compat ciphertext", err)
+	}
+}
+
+func Test_Rejects(t *testing.T) {
+	c := &Codec{Sign: []byte("SAV1"), Key: []byte("key"), Checksum: true}
+	save := c.Encode([]byte("some save data"))
+	if _, err := c.Decode(save[1:]); err != ErrSign {
+		t.Error("Missing sign accepted", err)
+	}
+	bad := append([]byte(nil), save...)
+	bad[4] ^= 1
+	if _, err := c.Decode(bad); err != ErrChecksum {
+		t.Error("Bad checksum accepted", err)
+	}
+	other := &Codec{Sign: c.Sign, Key: []byte("other"), Checksum: true}
+	if _, err := other.Decode(save); err == nil {
+		t.Error("Decoded under other key")
+	}
+	if _, err := c.Decode(save[:6]); err != compat.ErrData {
+		t.Error("Truncated save accepted", err)
+	}
+}
+
+func Test_Variants(t *testing.T) {
+	data := []byte("level=7;coins=12345")
+	plain := &Codec{Key: []byte("key")}
+	for _, c := range []*Codec{
+		{Key: []byte("key"), BigEndian: true},
+		{Key: []byte("key"), KeyRepeat: true},
+		{Key: []byte("key"), ZeroPad: true, Checksum: true},
+		{Key: []byte("key"), BigEndian: true, ZeroPad: true, KeyRepeat: true},
+	} {
+		save := c.Encode(data)
+		if bytes.Equal(save, plain.Encode(data)) {
+			t.Error("Variant gave compat body", *c)
+		}
+		d, err := c.Decode(save)
+		if err != nil || !bytes.Equal(c.unpadded(d), data) {
+			t.Error("Variant round trip failed", *c, err)
+		}
+	}
+	c := &Codec{Key: []byte("key"), ZeroPad: true}
+	if save := c.Encode([]byte("ab")); len(save) != 8 {
+		t.Error("Short ZeroPad body not padded to 8 bytes", len(save))
+	}
+}
+
+func Test_Profiles(t *testing.T) {
+	c, err := Profile("cocos2dx", []byte("2dxLua"))
+	if err != nil || string(c.Sign) != "XXTEA" {
+		t.Fatal("cocos2dx profile failed", err)
+	}
+	if _, err := Profile("nonesuch", nil); err != ErrProfile {
+		t.Error("Unknown profile found")
+	}
+	Register("test-be", Codec{Sign: []byte("BE"), BigEndian: true, Key: []byte("dropped")})
+	c, _ = Profile("test-be", []byte("k"))
+	if string(c.Key) != "k" || !c.BigEndian {
+		t.Error("Registered profile wrong", *c)
+	}
+	names := Profiles()
+	if len(names) != 3 || names[2] != "test-be" {
+		t.Error("Profiles listed wrong", names)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Duplicate Register did not panic")
+		}
+	}()
+	Register("cocos2dx", Codec{})
+}
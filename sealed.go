@@ -0,0 +1,86 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import "runtime"
+
+// TeaKey.Wipe overwrites the key with zeros.  A wiped key panics with
+// ErrZeroKey if used with a SealedKey, and silently encrypts under the
+// zero key if used by itself, so drop it after Wipe.
+//
+// TeaKey is a value: every copy passed or assigned stays in memory until
+// overwritten.  Hold long-lived keys in a SealedKey, which is not copied.
+func (k *TeaKey) Wipe() {
+	*k = TeaKey{}
+	runtime.KeepAlive(k) // keep the stores
+}
+
+// SealedKey holds a key that can be used, but not read, changed nor
+// printed, and that can be erased for good with Wipe.  Copies of a
+// SealedKey share the key, so Wipe erases it for all of them.  The zero
+// SealedKey is a wiped one.
+type SealedKey struct {
+	k *TeaKey
+}
+
+// NewSealedKey moves the key into a SealedKey and wipes *k.
+func NewSealedKey(k *TeaKey) SealedKey {
+	s := SealedKey{new(TeaKey)}
+	*s.k = *k
+	k.Wipe()
+	return s
+}
+
+// SealedKey.Wipe overwrites the key with zeros.  Further use panics with
+// ErrZeroKey.
+func (s SealedKey) Wipe() {
+	if s.k != nil {
+		s.k.Wipe()
+	}
+}
+
+// SealedKey.Wiped reports whether the key was wiped.
+func (s SealedKey) Wiped() bool {
+	return s.k == nil || s.k[0]|s.k[1]|s.k[2]|s.k[3] == 0
+}
+
+// key returns the key, or panics with ErrZeroKey if it was wiped.
+func (s SealedKey) key() *TeaKey {
+	if s.Wiped() {
+		panic(ErrZeroKey)
+	}
+	return s.k
+}
+
+// SealedKey.Encrypt is TeaKey.Encrypt under the sealed key.
+func (s SealedKey) Encrypt(in, out []byte) []byte {
+	k := s.key()
+	if err := chkMsg(len(in), len(out)); err != nil {
+		panic(err)
+	}
+	return k.encryptBytes(in, out)
+}
+
+// SealedKey.Decrypt is TeaKey.Decrypt under the sealed key.
+func (s SealedKey) Decrypt(in, out []byte) []byte {
+	k := s.key()
+	if err := chkMsg(len(in), len(out)); err != nil {
+		panic(err)
+	}
+	return k.decryptBytes(in, out)
+}
+
+// SealedKey.String returns a placeholder, never the key.
+func (s SealedKey) String() string {
+	if s.Wiped() {
+		return "xxtea.SealedKey(wiped)"
+	}
+	return "xxtea.SealedKey(redacted)"
+}
+
+// SealedKey.GoString is String, so %#v does not print the key either.
+func (s SealedKey) GoString() string {
+	return s.String()
+}
@@ -0,0 +1,45 @@
+package xxtea
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_SealedKey(t *testing.T) {
+	k := NewKey([]byte(keyBEBE))
+	want := k.Encrypt([]byte(msgMax), make(bs, len(msgMax)))
+	s := NewSealedKey(&k)
+	if k != (TeaKey{}) {
+		t.Error("Key not wiped by NewSealedKey")
+	}
+	ct := s.Encrypt([]byte(msgMax), make(bs, len(msgMax)))
+	if !bytes.Equal(ct, want) || string(s.Decrypt(ct, ct)) != msgMax {
+		t.Error("SealedKey round trip failed")
+	}
+	hex := fmt.Sprintf("%08x", NewKey([]byte(keyBEBE))[0])
+	for _, f := range []string{"%v", "%+v", "%#v", "%s"} {
+		if p := fmt.Sprintf(f, s); strings.Contains(p, hex) || !strings.Contains(p, "redacted") {
+			t.Error("SealedKey printed", f, p)
+		}
+	}
+	c := s
+	s.Wipe()
+	if !c.Wiped() || c.String() != "xxtea.SealedKey(wiped)" {
+		t.Error("Wipe of a copy failed")
+	}
+	for _, f := range []func(){
+		func() { c.Encrypt([]byte(msgMin), make(bs, 12)) },
+		func() { SealedKey{}.Decrypt([]byte(msgMin), make(bs, 12)) },
+	} {
+		func() {
+			defer func() {
+				if recover() != ErrZeroKey {
+					t.Error("Wiped key use should panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrSelfTest is wrapped by errors returned from SelfTest.
+var ErrSelfTest = errors.New("xxtea: self-test failed")
+
+// known answers: key, plaintext, ciphertext
+var kats = []struct{ key, pt, ct string }{
+	{"SomeKeyBytesHere", "Some message to encrypt here",
+		"\x22\x5c\xe2\x1c\x75\x3c\x6c\xec\xea\xae\x78\x59\xda\xe5\xbd\xa3\x2c\xe6\xf1\xe5\xc2\xdd\xb0\x98\xa3\x41\x9b\xf5"},
+}
+
+// SelfTest runs the embedded known answer vectors, then cross-checks
+// Encrypt and Decrypt against a plain transcription of the reference C code
+// for every legal message length.  It returns nil if all checks pass.
+//
+// SelfTest is meant for factory and power-on tests of deployed devices.
+func SelfTest() error {
+	for i, v := range kats {
+		ct := NewKey([]byte(v.key)).Encrypt([]byte(v.pt), make([]byte, len(v.pt)))
+		if string(ct) != v.ct {
+			return fmt.Errorf("%w: known answer %d: encrypt mismatch", ErrSelfTest, i)
+		}
+		if pt := NewKey([]byte(v.key)).Decrypt(ct, ct); string(pt) != v.pt {
+			return fmt.Errorf("%w: known answer %d: decrypt mismatch", ErrSelfTest, i)
+		}
+	}
+	var key [16]byte
+	var pt, ct, ref [208]byte
+	for i := range key {
+		key[i] = byte(i*7 + 1)
+	}
+	for i := range pt {
+		pt[i] = byte(i * 13)
+	}
+	k := NewKey(key[:])
+	for n := 12; n <= 208; n += 4 {
+		k.Encrypt(pt[:n], ct[:n])
+		copy(ref[:n], pt[:n])
+		refBtea(ref[:n], int32(n/4), k)
+		if !bytes.Equal(ct[:n], ref[:n]) {
+			return fmt.Errorf("%w: %d bytes: encrypt differs from reference", ErrSelfTest, n)
+		}
+		refBtea(ref[:n], -int32(n/4), k)
+		if !bytes.Equal(ref[:n], pt[:n]) {
+			return fmt.Errorf("%w: %d bytes: reference does not round-trip", ErrSelfTest, n)
+		}
+		if k.Decrypt(ct[:n], ct[:n]); !bytes.Equal(ct[:n], pt[:n]) {
+			return fmt.Errorf("%w: %d bytes: decrypt differs from reference", ErrSelfTest, n)
+		}
+	}
+	return nil
+}
+
+// VerifyAgainstReference round-trips random messages of each size through
+// Encrypt and Decrypt and through the reference transcription under the key,
+// iterations times per size, and reports the first mismatch.  Nil sizes
+// mean every legal size.  It is meant for acceptance tests of builds for
+// unusual targets.  Sizes out of XXTEA limits panic.
+func VerifyAgainstReference(key TeaKey, sizes []int, iterations int) error {
+	if sizes == nil {
+		for n := 12; n <= 208; n += 4 {
+			sizes = append(sizes, n)
+		}
+	}
+	var pt, ct, ref [208]byte
+	for _, n := range sizes {
+		if err := chkMsg(n, n); err != nil {
+			panic(err)
+		}
+		for i := 0; i < iterations; i++ {
+			if err := ReadEntropy(pt[:n]); err != nil {
+				return err
+			}
+			key.Encrypt(pt[:n], ct[:n])
+			copy(ref[:n], pt[:n])
+			if refBtea(ref[:n], int32(n/4), key); !bytes.Equal(ct[:n], ref[:n]) {
+				return fmt.Errorf("%w: %d bytes: encrypt differs from reference for % X", ErrSelfTest, n, pt[:n])
+			}
+			if refBtea(ref[:n], -int32(n/4), key); !bytes.Equal(ref[:n], pt[:n]) {
+				return fmt.Errorf("%w: %d bytes: reference does not round-trip % X", ErrSelfTest, n, pt[:n])
+			}
+			if key.Decrypt(ct[:n], ct[:n]); !bytes.Equal(ct[:n], pt[:n]) {
+				return fmt.Errorf("%w: %d bytes: decrypt differs from reference for % X", ErrSelfTest, n, pt[:n])
+			}
+		}
+	}
+	return nil
+}
+
+// refBtea is the corrected block tea reference code, transcribed line by
+// line from the paper for cross-checking only.  n > 0 encrypts, n < 0
+// decrypts |n| big-endian words of b in place.
+func refBtea(b []byte, n int32, key TeaKey) {
+	v := make([]uint32, len(b)/4)
+	for i := range v {
+		v[i] = uint32(b[4*i])<<24 | uint32(b[4*i+1])<<16 | uint32(b[4*i+2])<<8 | uint32(b[4*i+3])
+	}
+	var y, z, sum, p, e uint32
+	mx := func() uint32 {
+		return ((z>>5 ^ y<<2) + (y>>3 ^ z<<4)) ^ ((sum ^ y) + (key[(p&3)^e] ^ z))
+	}
+	if n > 1 {
+		un := uint32(n)
+		rounds := 6 + 52/un
+		sum = 0
+		z = v[un-1]
+		for ; rounds > 0; rounds-- {
+			sum += delta
+			e = (sum >> 2) & 3
+			for p = 0; p < un-1; p++ {
+				y = v[p+1]
+				v[p] += mx()
+				z = v[p]
+			}
+			y = v[0]
+			v[un-1] += mx()
+			z = v[un-1]
+		}
+	} else if n < -1 {
+		un := uint32(-n)
+		rounds := 6 + 52/un
+		sum = rounds * delta
+		y = v[0]
+		for ; rounds > 0; rounds-- {
+			e = (sum >> 2) & 3
+			for p = un - 1; p > 0; p-- {
+				z = v[p-1]
+				v[p] -= mx()
+				y = v[p]
+			}
+			z = v[un-1]
+			v[0] -= mx()
+			y = v[0]
+			sum -= delta
+		}
+	}
+	for i, w := range v {
+		b[4*i], b[4*i+1], b[4*i+2], b[4*i+3] = byte(w>>24), byte(w>>16), byte(w>>8), byte(w)
+	}
+}
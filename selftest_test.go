@@ -0,0 +1,45 @@
+package xxtea
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_SelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Error("SelfTest failed:", err)
+	}
+	saved := kats[0].ct
+	defer func() { kats[0].ct = saved }()
+	kats[0].ct = "x" + saved[1:]
+	if err := SelfTest(); !errors.Is(err, ErrSelfTest) {
+		t.Error("Bad known answer not detected")
+	}
+}
+
+func Test_RefBtea(t *testing.T) {
+	b := []byte(msgMax)
+	k := NewKey([]byte(keyBEBE))
+	refBtea(b, 52, k)
+	if string(b) == msgMax || string(k.Decrypt(b, b)) != msgMax {
+		t.Error("Reference btea does not match Decrypt")
+	}
+}
+
+func Test_VerifyAgainstReference(t *testing.T) {
+	if err := VerifyAgainstReference(NewKey([]byte(keyBEBE)), nil, 3); err != nil {
+		t.Error("VerifyAgainstReference failed:", err)
+	}
+	if err := VerifyAgainstReference(NewKey([]byte(keyLELE)), []int{12, 208}, 50); err != nil {
+		t.Error("VerifyAgainstReference failed:", err)
+	}
+}
+
+func Test_VerifyAgainstReference_Panics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Bad size did not panic")
+		}
+	}()
+	VerifyAgainstReference(NewKey([]byte(keyBEBE)), []int{14}, 1)
+}
@@ -0,0 +1,53 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_Chain(t *testing.T) {
+	var cps []Checkpoint
+	s := NewSender(key)
+	s.Chain = &Chain{Every: 3, Export: func(c Checkpoint) { cps = append(cps, c) }}
+	var fs [][]byte
+	for i := 0; i < 7; i++ {
+		f, err := s.Send([]byte("reading"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fs = append(fs, f)
+	}
+	if len(cps) != 2 || cps[0].Frames != 3 || cps[1].Frames != 6 {
+		t.Fatal("Checkpoints not exported", cps)
+	}
+	last := s.Checkpoint()
+	if last.Frames != 7 {
+		t.Error("Bad current checkpoint", last.Frames)
+	}
+	if err := VerifyChain(key, Checkpoint{}, fs[:3], cps[0]); err != nil {
+		t.Error("Chain from start not verified", err)
+	}
+	if err := VerifyChain(key, cps[0], fs[3:], last); err != nil {
+		t.Error("Chain between checkpoints not verified", err)
+	}
+	if err := VerifyChain(key, cps[0], append(fs[3:5:5], fs[6]), last); err != ErrChain {
+		t.Error("Missing frame not found", err)
+	}
+	if err := VerifyChain(key, cps[0], [][]byte{fs[4], fs[3], fs[5], fs[6]}, last); err != ErrChain {
+		t.Error("Reordered frames not found", err)
+	}
+	fs[4] = append([]byte(nil), fs[4]...)
+	fs[4][len(fs[4])-1] ^= 1
+	if err := VerifyChain(key, cps[0], fs[3:], last); err != ErrChain {
+		t.Error("Modified frame not found", err)
+	}
+	forged := cps[1]
+	forged.Frames = 5
+	if err := VerifyChain(key, cps[0], fs[3:5], forged); err != ErrChain {
+		t.Error("Forged checkpoint taken", err)
+	}
+	if err := VerifyChain(xxtea.NewKey([]byte("FEDCBA9876543210")), Checkpoint{}, fs[:3], cps[0]); err != ErrChain {
+		t.Error("Checkpoint verified under other key", err)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package session
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/ohir/xxtea/envelope"
+)
+
+var ErrUplink = errors.New("session: malformed uplink")
+
+// Queue collects payloads, eg. telemetry readings, and seals them all in a
+// single Flush, so a battery device wakes its CPU for crypto once per
+// uplink instead of once per reading.
+//
+// Queue is not safe for concurrent use.
+type Queue struct {
+	Sender *Sender
+
+	buf  []byte // queued payloads back to back
+	ends []int  // end offsets of payloads in buf
+}
+
+// NewQueue returns an empty Queue sealing with the Sender.
+func NewQueue(s *Sender) *Queue {
+	return &Queue{Sender: s}
+}
+
+// Add queues a copy of the payload.  Payloads longer than
+// envelope.MaxPayload are refused with envelope.ErrLength.
+func (q *Queue) Add(payload []byte) error {
+	if len(payload) > envelope.MaxPayload {
+		return envelope.ErrLength
+	}
+	q.buf = append(q.buf, payload...)
+	q.ends = append(q.ends, len(q.buf))
+	return nil
+}
+
+// Len returns the number of queued payloads.
+func (q *Queue) Len() int {
+	return len(q.ends)
+}
+
+// Flush seals the queued payloads into frames, in order, and empties the
+// queue.  On error the payloads not sealed yet stay queued.
+func (q *Queue) Flush() ([][]byte, error) {
+	frames := make([][]byte, 0, len(q.ends))
+	err := q.flush(func(f []byte) { frames = append(frames, f) })
+	return frames, err
+}
+
+// FlushUplink is Flush with the frames concatenated into a single uplink
+// message, each prefixed with its 2-byte big-endian length.  SplitUplink
+// takes it apart.
+func (q *Queue) FlushUplink() ([]byte, error) {
+	var up []byte
+	err := q.flush(func(f []byte) {
+		up = append(up, byte(len(f)>>8), byte(len(f)))
+		up = append(up, f...)
+	})
+	return up, err
+}
+
+func (q *Queue) flush(emit func([]byte)) error {
+	start := 0
+	for i, end := range q.ends {
+		f, err := q.Sender.Send(q.buf[start:end])
+		if err != nil {
+			q.buf = q.buf[:copy(q.buf, q.buf[start:])]
+			n := copy(q.ends, q.ends[i:])
+			q.ends = q.ends[:n]
+			for j := range q.ends {
+				q.ends[j] -= start
+			}
+			return err
+		}
+		emit(f)
+		start = end
+	}
+	q.buf, q.ends = q.buf[:0], q.ends[:0]
+	return nil
+}
+
+// SplitUplink returns the frames of an uplink message made by FlushUplink.
+func SplitUplink(up []byte) ([][]byte, error) {
+	var frames [][]byte
+	for len(up) > 0 {
+		if len(up) < 2 {
+			return nil, ErrUplink
+		}
+		n := int(binary.BigEndian.Uint16(up))
+		if len(up) < 2+n {
+			return nil, ErrUplink
+		}
+		frames = append(frames, up[2:2+n])
+		up = up[2+n:]
+	}
+	return frames, nil
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ohir/xxtea/envelope"
+)
+
+func Test_Queue(t *testing.T) {
+	q, r := NewQueue(NewSender(key)), NewReceiver(key)
+	msgs := []string{"t=21.5", "", "h=40", "battery=3.61V"}
+	for _, m := range msgs {
+		if err := q.Add([]byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Add(make([]byte, envelope.MaxPayload+1)); err != envelope.ErrLength {
+		t.Error("Long payload queued", err)
+	}
+	frames, err := q.Flush()
+	if err != nil || len(frames) != len(msgs) || q.Len() != 0 {
+		t.Fatal("Flush failed", err)
+	}
+	for i, f := range frames {
+		if p, err := r.Receive(f); err != nil || string(p) != msgs[i] {
+			t.Error("Queued frame not received", i, err)
+		}
+	}
+	q.Add([]byte("a"))
+	q.Add([]byte("bc"))
+	up, err := q.FlushUplink()
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames, err = SplitUplink(up)
+	if err != nil || len(frames) != 2 {
+		t.Fatal("SplitUplink failed", err)
+	}
+	for i, m := range []string{"a", "bc"} {
+		if p, err := r.Receive(frames[i]); err != nil || string(p) != m {
+			t.Error("Uplink frame not received", i, err)
+		}
+	}
+	if _, err := SplitUplink(up[:len(up)-1]); err != ErrUplink {
+		t.Error("Truncated uplink split", err)
+	}
+}
+
+type failStore struct{}
+
+var errStore = errors.New("store down")
+
+func (failStore) Reserve(uint16, uint32, uint32) (uint32, error) { return 0, errStore }
+
+func Test_QueueError(t *testing.T) {
+	s := NewSender(key)
+	s.Store = failStore{}
+	q := NewQueue(s)
+	q.Add([]byte("one"))
+	q.Add([]byte("two"))
+	if _, err := q.Flush(); err != errStore || q.Len() != 2 {
+		t.Fatal("Failed Flush lost payloads", err, q.Len())
+	}
+	s.Store = &envelope.MemStore{}
+	frames, err := q.Flush()
+	if err != nil || len(frames) != 2 {
+		t.Fatal("Retried Flush failed", err)
+	}
+	if p, _ := NewReceiver(key).Receive(frames[1]); string(p) != "two" {
+		t.Error("Payload order lost")
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package session wraps envelope frames into a Sender and a Receiver that
+// manage counters, replay windows and rekeying themselves, leaving the
+// application only Send(payload) and Receive(frame).
+//
+// Frames carry their plaintext length (envelope.FlagLength), so payloads
+// of any size up to 208 bytes can be sent.  Every epoch has its own key
+// derived from the session key; the Sender moves to a new epoch after
+// RekeyAfter frames and the Receiver follows on the first authentic frame
+// of it.  Counters start from zero in every epoch, and an epoch is never
+// used twice as long as the Sender's Store survives restarts.
+//
+// Epoch keys are derived, so the session key itself protects everything
+// a Sender sends.  XXTEA leaves little margin for large volumes under one
+// key: the Sender counts what it sealed and RekeyRecommended reports when
+// a new session key, from a new handshake, is due.
+//
+// Use a separate key per direction (see handshake.SessionKeys).
+package session
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+// DefaultRekeyAfter is the RekeyAfter of a new Sender.
+const DefaultRekeyAfter = 1 << 20
+
+// Usage limits of a new Sender, past which a new session key is advised.
+const (
+	DefaultMaxFrames = 1 << 26
+	DefaultMaxBytes  = 1 << 33
+)
+
+// WindowSize is the number of out of order counters a Receiver accepts.
+const WindowSize = 64
+
+// epochs are reserved from the store as counters of this epoch
+const epochTrack = ^uint32(0)
+
+var (
+	ErrReplay = errors.New("session: frame replayed or too old")
+	ErrStale  = errors.New("session: frame of a past epoch")
+	ErrKeyID  = errors.New("session: frame of other key-id")
+)
+
+// epochKey returns the key of the epoch.
+func epochKey(k xxtea.TeaKey, epoch uint32) xxtea.TeaKey {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], epoch)
+	return k.DeriveLabel("session", b[:])
+}
+
+// Sender seals payloads into frames.
+//
+// Sender is not safe for concurrent use.
+type Sender struct {
+	KeyID      uint16
+	RekeyAfter uint32                // frames per epoch
+	Store      envelope.CounterStore // reserves epochs, must be durable to survive restarts
+	Stats      *Stats                // optional operation counts
+	Chain      *Chain                // optional hash chain over sealed frames
+	MaxFrames  uint64                // frames under the session key, zero for no limit
+	MaxBytes   uint64                // payload bytes under the session key, zero for no limit
+
+	key     xxtea.TeaKey
+	epoch   xxtea.TeaKey
+	h       envelope.Header
+	started bool
+	usage   Usage
+}
+
+// Usage is what a Sender sealed under its session key.
+type Usage struct {
+	Frames uint64
+	Bytes  uint64
+}
+
+// NewSender returns a Sender under the session key, with an in-memory
+// Store.
+func NewSender(key xxtea.TeaKey) *Sender {
+	return &Sender{
+		RekeyAfter: DefaultRekeyAfter,
+		Store:      &envelope.MemStore{},
+		MaxFrames:  DefaultMaxFrames,
+		MaxBytes:   DefaultMaxBytes,
+		key:        key,
+	}
+}
+
+// rekey moves the Sender to a fresh epoch.
+func (s *Sender) rekey() error {
+	e, err := s.Store.Reserve(s.KeyID, epochTrack, 1)
+	if err != nil {
+		return err
+	}
+	s.h = envelope.Header{Flags: envelope.FlagLength, KeyID: s.KeyID, Epoch: e}
+	s.epoch, s.started = epochKey(s.key, e), true
+	return nil
+}
+
+// Send returns the payload sealed into the next frame.
+func (s *Sender) Send(payload []byte) ([]byte, error) {
+	if !s.started || s.h.Counter >= s.RekeyAfter || s.h.Counter == ^uint32(0) {
+		if err := s.rekey(); err != nil {
+			return nil, err
+		}
+	}
+	f, err := envelope.Seal(s.epoch, s.h, payload)
+	if err != nil {
+		return nil, err
+	}
+	s.h.Counter++
+	s.usage.Frames++
+	s.usage.Bytes += uint64(len(payload))
+	s.Stats.seal(len(payload))
+	s.Chain.add(s.key, f)
+	return f, nil
+}
+
+// Usage returns what the Sender sealed so far.
+func (s *Sender) Usage() Usage {
+	return s.usage
+}
+
+// RekeyRecommended reports whether the Sender went over MaxFrames or
+// MaxBytes, and the session key should be replaced.  Send keeps working
+// past the limits; it is up to the application to start a new session.
+func (s *Sender) RekeyRecommended() bool {
+	return s.MaxFrames > 0 && s.usage.Frames >= s.MaxFrames ||
+		s.MaxBytes > 0 && s.usage.Bytes >= s.MaxBytes
+}
+
+// Receiver opens frames of a Sender.  It accepts frames reordered by up to
+// WindowSize counters, and each of them only once.
+//
+// Receiver is not safe for concurrent use.
+type Receiver struct {
+	KeyID uint16
+	Stats *Stats // optional operation counts
+
+	key     xxtea.TeaKey
+	epoch   uint32
+	top     uint32 // highest counter seen
+	seen    uint64 // bit i: counter top-i seen
+	started bool
+}
+
+// NewReceiver returns a Receiver under the session key.
+func NewReceiver(key xxtea.TeaKey) *Receiver {
+	return &Receiver{key: key}
+}
+
+// Receive authenticates the frame and returns its payload.
+func (r *Receiver) Receive(frame []byte) ([]byte, error) {
+	p, err := r.receive(frame)
+	r.Stats.open(len(p), err)
+	return p, err
+}
+
+func (r *Receiver) receive(frame []byte) ([]byte, error) {
+	h, err := envelope.ParseHeader(frame)
+	if err != nil {
+		return nil, err
+	}
+	if h.KeyID != r.KeyID {
+		return nil, ErrKeyID
+	}
+	newer := !r.started || h.Epoch > r.epoch
+	if !newer {
+		if h.Epoch < r.epoch {
+			return nil, ErrStale
+		}
+		if h.Counter <= r.top && (r.top-h.Counter >= WindowSize || r.seen&(1<<(r.top-h.Counter)) != 0) {
+			return nil, ErrReplay
+		}
+	}
+	_, p, err := envelope.Open(epochKey(r.key, h.Epoch), frame)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case newer:
+		r.epoch, r.top, r.seen, r.started = h.Epoch, h.Counter, 1, true
+	case h.Counter > r.top:
+		if d := h.Counter - r.top; d < WindowSize {
+			r.seen = r.seen<<d | 1
+		} else {
+			r.seen = 1
+		}
+		r.top = h.Counter
+	default:
+		r.seen |= 1 << (r.top - h.Counter)
+	}
+	return p, nil
+}
@@ -0,0 +1,133 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+var key = xxtea.NewKey([]byte("0123456789ABCDEF"))
+
+func Test_SendReceive(t *testing.T) {
+	s, r := NewSender(key), NewReceiver(key)
+	for _, m := range []string{"", "on", "set temp 21.5 and report back"} {
+		f, err := s.Send([]byte(m))
+		if err != nil {
+			t.Fatal("Send failed:", err)
+		}
+		if p, err := r.Receive(f); err != nil || string(p) != m {
+			t.Error("Receive failed:", m, err)
+		}
+		if _, err := r.Receive(f); err != ErrReplay {
+			t.Error("Replay accepted:", m, err)
+		}
+	}
+	f, _ := s.Send([]byte("x"))
+	f[len(f)-1] ^= 1
+	if _, err := r.Receive(f); err != envelope.ErrOpenFailed {
+		t.Error("Forgery accepted", err)
+	}
+	if _, err := NewReceiver(xxtea.NewKey([]byte("FEDCBA9876543210"))).Receive(f); err != envelope.ErrOpenFailed {
+		t.Error("Other key accepted", err)
+	}
+	r.KeyID = 1
+	if _, err := r.Receive(f); err != ErrKeyID {
+		t.Error("Other key-id accepted", err)
+	}
+}
+
+func Test_Window(t *testing.T) {
+	s, r := NewSender(key), NewReceiver(key)
+	var fs [][]byte
+	for i := 0; i < WindowSize+2; i++ {
+		f, _ := s.Send([]byte("hi"))
+		fs = append(fs, f)
+	}
+	for _, i := range []int{3, 1, 2, 0, WindowSize + 1} {
+		if _, err := r.Receive(fs[i]); err != nil {
+			t.Error("Reordered frame rejected:", i, err)
+		}
+	}
+	for _, i := range []int{1, 0, 3, WindowSize + 1} {
+		if _, err := r.Receive(fs[i]); err != ErrReplay {
+			t.Error("Frame accepted twice or too old:", i, err)
+		}
+	}
+	if _, err := r.Receive(fs[WindowSize]); err != nil {
+		t.Error("Frame within window rejected", err)
+	}
+}
+
+func Test_Rekey(t *testing.T) {
+	s, r := NewSender(key), NewReceiver(key)
+	s.RekeyAfter = 2
+	var fs [][]byte
+	for i := 0; i < 5; i++ {
+		f, err := s.Send([]byte("hi"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fs = append(fs, f)
+	}
+	h0, _ := envelope.ParseHeader(fs[0])
+	h4, _ := envelope.ParseHeader(fs[4])
+	if h4.Epoch != h0.Epoch+2 || h4.Counter != 0 {
+		t.Error("Sender did not rekey", h0, h4)
+	}
+	for _, i := range []int{0, 2, 4} {
+		if _, err := r.Receive(fs[i]); err != nil {
+			t.Error("Frame of new epoch rejected:", i, err)
+		}
+	}
+	if _, err := r.Receive(fs[3]); err != ErrStale {
+		t.Error("Frame of past epoch accepted", err)
+	}
+	// a restarted Sender with the same store never reuses an epoch
+	s2 := NewSender(key)
+	s2.Store = s.Store
+	f, _ := s2.Send([]byte("hi"))
+	if _, err := r.Receive(f); err != nil {
+		t.Error("Restarted Sender rejected", err)
+	}
+}
+
+func Test_Usage(t *testing.T) {
+	s := NewSender(key)
+	s.MaxFrames, s.MaxBytes = 4, 10
+	for i := 0; i < 3; i++ {
+		if s.RekeyRecommended() {
+			t.Error("Rekey advised early", i)
+		}
+		s.Send([]byte("hi"))
+	}
+	if u := s.Usage(); u.Frames != 3 || u.Bytes != 6 {
+		t.Error("Bad usage", u)
+	}
+	s.Send([]byte("hi"))
+	if !s.RekeyRecommended() {
+		t.Error("Rekey not advised over MaxFrames")
+	}
+	s = NewSender(key)
+	s.MaxBytes = 10
+	s.Send(make([]byte, 10))
+	if !s.RekeyRecommended() {
+		t.Error("Rekey not advised over MaxBytes")
+	}
+}
+
+func Test_ErrorAllocs(t *testing.T) {
+	s, r := NewSender(key), NewReceiver(key)
+	f, _ := s.Send([]byte("x"))
+	r.Receive(f)
+	other := append([]byte(nil), f...)
+	other[3] ^= 1 // key-id
+	for name, fn := range map[string]func(){
+		"replay": func() { r.Receive(f) },
+		"key-id": func() { r.Receive(other) },
+	} {
+		if n := testing.AllocsPerRun(100, fn); n != 0 {
+			t.Error("Error path allocates:", name, n)
+		}
+	}
+}
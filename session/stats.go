@@ -0,0 +1,65 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package session
+
+import (
+	"sync/atomic"
+
+	"github.com/ohir/xxtea/envelope"
+)
+
+// Stats counts operations of Senders and Receivers it is set on.  One Stats
+// can be shared by many of them.
+//
+// Stats is safe for concurrent use.  On 32-bit platforms it must be
+// 64-bit aligned: allocate it, or make it the first field of a struct.
+type Stats struct {
+	sealed, opened    uint64 // atomic
+	macFail, replays  uint64
+	bytesIn, bytesOut uint64
+}
+
+// Counts is a snapshot of Stats.
+type Counts struct {
+	Sealed      uint64 // frames sealed
+	Opened      uint64 // frames opened
+	MACFailures uint64 // frames that failed to open (forged, malformed or of other key-id)
+	ReplayDrops uint64 // frames replayed, too old, or of a past epoch
+	BytesSealed uint64 // payload bytes sealed
+	BytesOpened uint64 // payload bytes opened
+}
+
+// Counts returns current counts.
+func (s *Stats) Counts() Counts {
+	return Counts{
+		Sealed:      atomic.LoadUint64(&s.sealed),
+		Opened:      atomic.LoadUint64(&s.opened),
+		MACFailures: atomic.LoadUint64(&s.macFail),
+		ReplayDrops: atomic.LoadUint64(&s.replays),
+		BytesSealed: atomic.LoadUint64(&s.bytesIn),
+		BytesOpened: atomic.LoadUint64(&s.bytesOut),
+	}
+}
+
+func (s *Stats) seal(n int) {
+	if s != nil {
+		atomic.AddUint64(&s.sealed, 1)
+		atomic.AddUint64(&s.bytesIn, uint64(n))
+	}
+}
+
+func (s *Stats) open(n int, err error) {
+	switch {
+	case s == nil:
+	case err == nil:
+		atomic.AddUint64(&s.opened, 1)
+		atomic.AddUint64(&s.bytesOut, uint64(n))
+	case err == envelope.ErrOpenFailed, err == envelope.ErrFrame, err == envelope.ErrVersion,
+		err == envelope.ErrFlags, err == ErrKeyID:
+		atomic.AddUint64(&s.macFail, 1)
+	case err == ErrReplay || err == ErrStale:
+		atomic.AddUint64(&s.replays, 1)
+	}
+}
@@ -0,0 +1,26 @@
+package session
+
+import "testing"
+
+func Test_Stats(t *testing.T) {
+	st := &Stats{}
+	s, r := NewSender(key), NewReceiver(key)
+	s.Stats, r.Stats = st, st
+	f, _ := s.Send([]byte("hello"))
+	r.Receive(f)
+	r.Receive(f)
+	g, _ := s.Send([]byte("abc"))
+	g[len(g)-1] ^= 1
+	r.Receive(g)
+	r.Receive(g[:5])                    // malformed
+	r.Receive(append([]byte{99}, f...)) // unknown version
+	o := NewSender(key)
+	o.KeyID, o.Stats = 9, st
+	h, _ := o.Send([]byte("other"))
+	r.Receive(h)
+	exp := Counts{Sealed: 3, Opened: 1, MACFailures: 4, ReplayDrops: 1, BytesSealed: 13, BytesOpened: 5}
+	if c := st.Counts(); c != exp {
+		t.Errorf("Counts are %+v", c)
+	}
+	NewReceiver(key).Receive(f) // no Stats set
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package simdevice
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/handshake"
+	"github.com/ohir/xxtea/identity"
+	"github.com/ohir/xxtea/session"
+)
+
+// Backend is the backend side of the conversation, a reference for tests
+// of backends and a driver for tests of devices.
+//
+// Backend is not safe for concurrent use.
+type Backend struct {
+	Claims identity.Claims // of the connected device
+
+	rw io.ReadWriter
+	tx *session.Sender
+	rx *session.Receiver
+}
+
+// Accept runs the handshake with the device on rw under its pre-shared key
+// and verifies the device identity token.
+func Accept(rw io.ReadWriter, key xxtea.TeaKey) (*Backend, error) {
+	hello, err := expect(rw, MsgHello)
+	if err != nil {
+		return nil, err
+	}
+	hs := handshake.NewResponder(key)
+	reply, err := hs.Reply(hello)
+	if err != nil {
+		return nil, err
+	}
+	if err = WriteMsg(rw, MsgReply, reply); err != nil {
+		return nil, err
+	}
+	confirm, err := expect(rw, MsgConfirm)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = hs.Finish(confirm); err != nil {
+		return nil, err
+	}
+	c2s, s2c, _ := hs.Keys()
+	token, err := expect(rw, MsgIdentity)
+	if err != nil {
+		return nil, err
+	}
+	b := &Backend{rw: rw, tx: session.NewSender(s2c), rx: session.NewReceiver(c2s)}
+	if b.Claims, err = identity.Verify(key, token); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(b.Claims.Nonce[:], hello[:len(b.Claims.Nonce)]) {
+		return nil, ErrMessage
+	}
+	return b, nil
+}
+
+// Command sends the command to the device and returns its reply.
+func (b *Backend) Command(cmd []byte) ([]byte, error) {
+	f, err := b.tx.Send(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if err = WriteMsg(b.rw, MsgFrame, f); err != nil {
+		return nil, err
+	}
+	return b.recv()
+}
+
+// Update sends the ota bundle to the device and returns its answer, "ota
+// ok" or the error it ran into.
+func (b *Backend) Update(bundle []byte) (string, error) {
+	if err := WriteMsg(b.rw, MsgOTA, bundle); err != nil {
+		return "", err
+	}
+	p, err := b.recv()
+	return string(p), err
+}
+
+func (b *Backend) recv() ([]byte, error) {
+	f, err := expect(b.rw, MsgFrame)
+	if err != nil {
+		return nil, err
+	}
+	return b.rx.Receive(f)
+}
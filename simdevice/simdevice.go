@@ -0,0 +1,225 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package simdevice is a fake device speaking the formats of this module,
+// for end-to-end tests of backends with no hardware on the bench.
+//
+// Device and backend exchange messages over any byte stream, typically
+// one end of net.Pipe:
+//
+//	type (1B) | body length (4B BE) | body
+//
+// A Device runs this conversation:
+//
+//	MsgHello    -->                 handshake, device initiates
+//	            <--  MsgReply
+//	MsgConfirm  -->
+//	MsgIdentity -->                 identity token under the device key
+//	            <--  MsgFrame       command, sealed by a session.Sender
+//	MsgFrame    -->                 reply of Device.Handle
+//	            <--  MsgOTA         ota bundle
+//	MsgFrame    -->                 "ota ok", or "ota: " and the error
+//
+// Frames go over session Senders and Receivers with the directional keys
+// of the handshake, so counters, replay windows and rekeying are those of
+// a real device.  Backend is the other side, for tests that need one.
+package simdevice
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/handshake"
+	"github.com/ohir/xxtea/identity"
+	"github.com/ohir/xxtea/ota"
+	"github.com/ohir/xxtea/session"
+)
+
+// Message types.
+const (
+	MsgHello = 1 + iota
+	MsgReply
+	MsgConfirm
+	MsgIdentity
+	MsgFrame
+	MsgOTA
+)
+
+// MaxMessage is the maximum size of a message body.
+const MaxMessage = 1 << 20
+
+var (
+	ErrMessage = errors.New("simdevice: malformed or unexpected message")
+	ErrSize    = errors.New("simdevice: message over MaxMessage")
+)
+
+// WriteMsg writes a message of the type and body.
+func WriteMsg(w io.Writer, typ byte, body []byte) error {
+	if len(body) > MaxMessage {
+		return ErrSize
+	}
+	b := make([]byte, 5, 5+len(body))
+	b[0] = typ
+	binary.BigEndian.PutUint32(b[1:], uint32(len(body)))
+	_, err := w.Write(append(b, body...))
+	return err
+}
+
+// ReadMsg reads the next message.  It returns io.EOF if the stream ended
+// before one.
+func ReadMsg(r io.Reader) (typ byte, body []byte, err error) {
+	var h [5]byte
+	if _, err = io.ReadFull(r, h[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(h[1:])
+	if n > MaxMessage {
+		return 0, nil, ErrSize
+	}
+	body = make([]byte, n)
+	if _, err = io.ReadFull(r, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return h[0], body, nil
+}
+
+// expect reads the next message and returns its body, or ErrMessage if it
+// is not of the type.
+func expect(r io.Reader, typ byte) ([]byte, error) {
+	t, b, err := ReadMsg(r)
+	if err == nil && t != typ {
+		err = ErrMessage
+	}
+	return b, err
+}
+
+// Device is a simulated device.  Fields are set before Run.
+type Device struct {
+	ID       uint64
+	KeyID    uint16
+	Key      xxtea.TeaKey      // pre-shared device key
+	Firmware uint32            // version, incremented by every update
+	Caps     uint32            // capabilities
+	OTAKey   ed25519.PublicKey // vendor key of update bundles
+	// Handle returns the reply to a command; nil sends no reply.  Nil
+	// Handle echoes commands back.
+	Handle func(cmd []byte) []byte
+
+	mu    sync.Mutex
+	image []byte
+}
+
+// New returns a Device of the id and pre-shared key.
+func New(id uint64, key xxtea.TeaKey) *Device {
+	return &Device{ID: id, Key: key}
+}
+
+// Image returns the firmware image of the last update, nil if none.
+func (d *Device) Image() []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.image
+}
+
+// Run runs the device side of the conversation over rw until it ends.  It
+// returns nil when the backend closes the stream.
+func (d *Device) Run(rw io.ReadWriter) error {
+	hs := handshake.NewInitiator(d.Key)
+	hello, err := hs.Hello()
+	if err != nil {
+		return err
+	}
+	if err = WriteMsg(rw, MsgHello, hello); err != nil {
+		return err
+	}
+	reply, err := expect(rw, MsgReply)
+	if err != nil {
+		return err
+	}
+	confirm, _, err := hs.Finish(reply)
+	if err != nil {
+		return err
+	}
+	if err = WriteMsg(rw, MsgConfirm, confirm); err != nil {
+		return err
+	}
+	c2s, s2c, _ := hs.Keys()
+	d.mu.Lock()
+	cl := identity.Claims{DeviceID: d.ID, Firmware: d.Firmware, Caps: d.Caps}
+	d.mu.Unlock()
+	copy(cl.Nonce[:], hello)
+	token, err := identity.Seal(d.Key, d.KeyID, cl)
+	if err != nil {
+		return err
+	}
+	if err = WriteMsg(rw, MsgIdentity, token); err != nil {
+		return err
+	}
+	tx, rx := session.NewSender(c2s), session.NewReceiver(s2c)
+	send := func(p []byte) error {
+		f, err := tx.Send(p)
+		if err != nil {
+			return err
+		}
+		return WriteMsg(rw, MsgFrame, f)
+	}
+	for {
+		typ, b, err := ReadMsg(rw)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case MsgFrame:
+			var r []byte
+			if r, err = rx.Receive(b); err != nil {
+				return err
+			}
+			if d.Handle != nil {
+				r = d.Handle(r)
+			}
+			if r != nil {
+				err = send(r)
+			}
+		case MsgOTA:
+			img, oerr := ota.Open(d.OTAKey, d.Key, b)
+			if oerr != nil {
+				err = send([]byte("ota: " + oerr.Error()))
+				break
+			}
+			d.mu.Lock()
+			d.image = img
+			d.Firmware++
+			d.mu.Unlock()
+			err = send([]byte("ota ok"))
+		default:
+			err = ErrMessage
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Pipe runs the device on one end of a net.Pipe and returns the other end,
+// and a channel receiving the result of Run once it returns.
+func Pipe(d *Device) (net.Conn, <-chan error) {
+	a, b := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Run(b)
+		b.Close()
+	}()
+	return a, done
+}
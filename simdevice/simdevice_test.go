@@ -0,0 +1,58 @@
+package simdevice
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/ota"
+)
+
+var key = xxtea.NewKey([]byte("0123456789ABCDEF"))
+
+func Test_Device(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	d := New(0xd00d, key)
+	d.Firmware, d.Caps, d.OTAKey = 7, 3, pub
+	conn, done := Pipe(d)
+	b, err := Accept(conn, key)
+	if err != nil {
+		t.Fatal("Accept failed", err)
+	}
+	if b.Claims.DeviceID != 0xd00d || b.Claims.Firmware != 7 || !b.Claims.Has(3) {
+		t.Error("Bad claims", b.Claims)
+	}
+	for _, cmd := range []string{"ping", "set interval 60"} {
+		if r, err := b.Command([]byte(cmd)); err != nil || string(r) != cmd {
+			t.Error("Command not echoed", cmd, err)
+		}
+	}
+	img := bytes.Repeat([]byte("firmware"), 100)
+	bundle, _ := ota.Seal(priv, key, img)
+	if r, err := b.Update(bundle); err != nil || r != "ota ok" || !bytes.Equal(d.Image(), img) {
+		t.Error("Update failed", r, err)
+	}
+	bundle[len(bundle)-1] ^= 1
+	if r, err := b.Update(bundle); err != nil || r != "ota: "+ota.ErrSignature.Error() {
+		t.Error("Bad bundle not refused", r, err)
+	}
+	conn.Close()
+	if err := <-done; err != nil {
+		t.Error("Device failed", err)
+	}
+	if d.Firmware != 8 {
+		t.Error("Firmware not updated", d.Firmware)
+	}
+}
+
+func Test_WrongKey(t *testing.T) {
+	conn, done := Pipe(New(1, key))
+	if _, err := Accept(conn, xxtea.NewKey([]byte("FEDCBA9876543210"))); err == nil {
+		t.Error("Device accepted under other key")
+	}
+	conn.Close()
+	if err := <-done; err == nil {
+		t.Error("Device ran under other key")
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stream
+
+import (
+	"context"
+
+	"github.com/ohir/xxtea/internal/ctxio"
+)
+
+// WriteContext is Write bounded by ctx.  It checks ctx before every record
+// and, if the underlying writer takes deadlines as net.Conn does, bounds
+// writes of records by it too.  On cancellation it returns the bytes of
+// the records written so far with the error of ctx.  A record cut by a
+// deadline leaves the stream broken.
+func (w *Writer) WriteContext(ctx context.Context, p []byte) (n int, err error) {
+	if w.end {
+		return 0, ErrClosed
+	}
+	defer ctxio.Bound(ctx, ctxio.Write(w.w))()
+	for len(p) > 0 {
+		if err = ctx.Err(); err != nil {
+			return n, err
+		}
+		c := len(p)
+		if c > MaxData {
+			c = MaxData
+		}
+		if err = w.record(p[:c], false); err != nil {
+			return n, ctxio.Err(ctx, err)
+		}
+		n += c
+		p = p[c:]
+	}
+	return n, nil
+}
+
+// ReadContext reads data into p until p is full, the stream ends or ctx is
+// done.  It checks ctx before every record and, if the underlying reader
+// takes deadlines as net.Conn does, bounds reads by it too, so a peer
+// trickling bytes can not hold the caller longer.  It returns the bytes
+// read so far with the error: io.EOF at the end of the stream, the error
+// of ctx on cancellation.  A record cut by a deadline leaves the Reader
+// broken.
+func (r *Reader) ReadContext(ctx context.Context, p []byte) (n int, err error) {
+	defer ctxio.Bound(ctx, ctxio.Read(r.r))()
+	for n < len(p) {
+		if len(r.data) == 0 {
+			if err = ctx.Err(); err != nil {
+				return n, err
+			}
+		}
+		m, err := r.Read(p[n:])
+		n += m
+		if err != nil {
+			return n, ctxio.Err(ctx, err)
+		}
+	}
+	return n, nil
+}
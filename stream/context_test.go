@@ -0,0 +1,52 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ohir/xxtea"
+)
+
+func Test_Context(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	a, b := net.Pipe()
+	defer a.Close()
+	go func() {
+		w := NewWriterID(b, key, 1)
+		w.WriteContext(context.Background(), data[:3*MaxData]) // three records, then stall
+	}()
+	r := NewReader(a, key)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	p := make([]byte, len(data))
+	n, err := r.ReadContext(ctx, p)
+	if err != context.DeadlineExceeded || n != 3*MaxData || !bytes.Equal(p[:n], data[:n]) {
+		t.Error("Partial read not returned", n, err)
+	}
+	b.Close()
+
+	var buf bytes.Buffer
+	w := NewWriterID(&buf, key, 2)
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if n, err = w.WriteContext(ctx, data); n != 0 || err != context.Canceled {
+		t.Error("Cancelled write ran", n, err)
+	}
+	if n, err = w.WriteContext(context.Background(), data); n != len(data) || err != nil {
+		t.Error("WriteContext failed", n, err)
+	}
+	w.Close()
+	sealed := buf.Bytes()
+	n, err = NewReader(bytes.NewReader(sealed), key).ReadContext(context.Background(), p)
+	if err != nil || n != len(data) || !bytes.Equal(p, data) {
+		t.Error("ReadContext failed", n, err)
+	}
+	if n, err = NewReader(bytes.NewReader(sealed), key).ReadContext(context.Background(), make([]byte, 2*len(data))); err != io.EOF || n != len(data) {
+		t.Error("End of stream not reported", n, err)
+	}
+}
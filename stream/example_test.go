@@ -0,0 +1,40 @@
+package stream_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/stream"
+)
+
+// Writer and Reader sit on any byte stream, eg. a serial port or a
+// socket: records are length prefixed, sealed and opened in order.  A
+// bufio.Writer of MaxData bytes in front of the Writer packs small writes
+// into full records, so each of them does not cost a record.
+func Example_pipe() {
+	key := xxtea.NewKey([]byte("0123456789ABCDEF"))
+	a, b := net.Pipe()
+	go func() {
+		sw, err := stream.NewWriter(a, key)
+		if err != nil {
+			panic(err)
+		}
+		bw := bufio.NewWriterSize(sw, stream.MaxData)
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(bw, "reading %d\n", i)
+		}
+		bw.Flush()
+		sw.Close()
+		a.Close()
+	}()
+	data, err := io.ReadAll(stream.NewReader(b, key))
+	fmt.Printf("%s%v\n", data, err)
+	// Output:
+	// reading 0
+	// reading 1
+	// reading 2
+	// <nil>
+}
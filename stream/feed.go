@@ -0,0 +1,88 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stream
+
+import (
+	"encoding/binary"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+// Decoder is the push counterpart of Reader, for receivers driven by
+// interrupt, DMA or UART callbacks instead of a goroutine per connection.
+// Bytes of a stream are fed to it in chunks of any size as they arrive;
+// it keeps a partial record in a fixed buffer until the rest comes.
+//
+// Errors are sticky: once Feed fails the stream is broken and every
+// further Feed returns the same error.
+type Decoder struct {
+	key xxtea.TeaKey
+	seq
+	have  int // bytes of the current record in frame
+	err   error
+	frame [2 + envelope.HeaderSize + envelope.MaxPayload + envelope.TagSizeV2]byte
+}
+
+// NewDecoder returns a Decoder opening records under the key.
+func NewDecoder(key xxtea.TeaKey) *Decoder {
+	return &Decoder{key: key}
+}
+
+// Feed takes the next bytes of the stream and returns data of the records
+// they completed, in order, in slices of their own.  Records without data
+// are not returned.  Bytes after the final record, and record lengths no
+// frame can have, are an ErrRecord.
+func (d *Decoder) Feed(b []byte) (frames [][]byte, err error) {
+	for len(b) > 0 && d.err == nil {
+		if d.end {
+			d.err = ErrRecord
+			break
+		}
+		if d.have < 2 {
+			c := copy(d.frame[d.have:2], b)
+			d.have += c
+			b = b[c:]
+			if d.have < 2 {
+				break
+			}
+			if n := int(binary.BigEndian.Uint16(d.frame[:2])); n < minFrame || 2+n > len(d.frame) {
+				d.err = ErrRecord
+				break
+			}
+		}
+		want := 2 + int(binary.BigEndian.Uint16(d.frame[:2]))
+		c := copy(d.frame[d.have:want], b)
+		d.have += c
+		b = b[c:]
+		if d.have < want {
+			break
+		}
+		d.have = 0
+		var p []byte
+		if p, d.err = d.open(d.key, d.frame[2:want]); len(p) > 0 {
+			frames = append(frames, append([]byte(nil), p...))
+		}
+	}
+	return frames, d.err
+}
+
+// Close reports whether the stream ended properly: it returns the error
+// of Feed if any, ErrTruncated if the final record was not fed yet, and
+// nil otherwise.
+func (d *Decoder) Close() error {
+	if d.err != nil {
+		return d.err
+	}
+	if !d.end {
+		return ErrTruncated
+	}
+	return nil
+}
+
+// Done reports whether the final record was fed.
+func (d *Decoder) Done() bool {
+	return d.end && d.err == nil
+}
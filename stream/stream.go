@@ -0,0 +1,262 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package stream frames a byte stream into sealed records, so data of any
+// length can be carried over pipes, files, serial lines and sockets.
+//
+// Every record is an envelope frame prefixed with its 2-byte big-endian
+// length.  Frames of a stream share a random stream id (put in the epoch
+// field) and are counted from zero, so records can be neither reordered
+// nor spliced in from another stream.  Record payload is:
+//
+//	data (0..207B) | zero pad | trailer (1B)
+//
+// Trailer holds the pad length, trailer included, in its low bits and the
+// final-record mark in its high bit.  A stream that ends without the final
+// record is reported as truncated.  In xxtea.Strict mode padding must be zeros,
+// and no longer than Writer makes it.
+package stream
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+	"github.com/ohir/xxtea/faults"
+)
+
+// MaxData is the maximum number of data bytes in a single record.
+const MaxData = envelope.MaxPayload - 1
+
+const finalMark = 0x80
+
+// minFrame is the size of the shortest frame of a record.
+const minFrame = envelope.HeaderSize + envelope.MinPayload + envelope.TagSizeV1
+
+var (
+	ErrTruncated = errors.New("stream: truncated stream")
+	ErrRecord    = errors.New("stream: malformed record")
+	ErrOrder     = errors.New("stream: record out of order")
+	ErrClosed    = errors.New("stream: write to closed stream")
+)
+
+// Writer seals data written to it into records.  Each Write emits its data
+// at once, as one or more records.  Close must be called to mark the end
+// of the stream.
+type Writer struct {
+	w   io.Writer
+	key xxtea.TeaKey
+	h   envelope.Header
+	buf [2 + envelope.HeaderSize + envelope.MaxPayload + envelope.TagSizeV2]byte
+	end bool
+}
+
+// NewWriter returns a Writer sealing records to w under the key.
+func NewWriter(w io.Writer, key xxtea.TeaKey) (*Writer, error) {
+	sw := &Writer{w: w, key: key}
+	var id [4]byte
+	if err := xxtea.ReadEntropy(id[:]); err != nil {
+		return nil, err
+	}
+	sw.h.Epoch = binary.BigEndian.Uint32(id[:])
+	return sw, nil
+}
+
+// NewWriterID returns a Writer sealing records to w under the key, with the
+// given stream id instead of a random one.  It is for keys used for a single
+// stream only, where a fixed id makes output reproducible.
+func NewWriterID(w io.Writer, key xxtea.TeaKey, id uint32) *Writer {
+	sw := &Writer{w: w, key: key}
+	sw.h.Epoch = id
+	return sw
+}
+
+// record seals p (up to MaxData bytes) and writes it out.
+func (w *Writer) record(p []byte, final bool) error {
+	n := (len(p) + 1 + 3) &^ 3
+	if n < envelope.MinPayload {
+		n = envelope.MinPayload
+	}
+	pl := w.buf[2+envelope.HeaderSize : 2+envelope.HeaderSize+n]
+	copy(pl, p)
+	for i := len(p); i < n; i++ {
+		pl[i] = 0
+	}
+	pl[n-1] = byte(n - len(p))
+	if final {
+		pl[n-1] |= finalMark
+	}
+	f, err := envelope.Seal(w.key, w.h, pl)
+	if err != nil {
+		return err
+	}
+	w.h.Counter++
+	binary.BigEndian.PutUint16(w.buf[:2], uint16(len(f)))
+	_, err = w.w.Write(append(w.buf[:2], f...))
+	return err
+}
+
+// Write seals p into records and writes them to the underlying writer.
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if w.end {
+		return 0, ErrClosed
+	}
+	for len(p) > 0 {
+		c := len(p)
+		if c > MaxData {
+			c = MaxData
+		}
+		if err = w.record(p[:c], false); err != nil {
+			return n, err
+		}
+		n += c
+		p = p[c:]
+	}
+	return n, nil
+}
+
+// Close writes the final record.  It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.end {
+		return nil
+	}
+	w.end = true
+	return w.record(nil, true)
+}
+
+// LimitError is returned by Reader when the stream goes over one of its
+// limits.
+type LimitError struct {
+	What  string // "frame size", "records" or "bytes"
+	Limit int64
+}
+
+func (e *LimitError) Error() string {
+	return "stream: " + e.What + " over limit of " + strconv.FormatInt(e.Limit, 10)
+}
+
+// Reader opens records read from the underlying reader and returns their
+// data.  Read returns io.EOF only after the final record was read.
+//
+// Reader holds a single record at a time, in a fixed buffer, whatever the
+// peer sends.  Limits, zero for none, also cap what a peer can make it
+// read: frames longer than MaxFrame are refused before being read, and a
+// stream of more than MaxRecords records or MaxBytes data bytes ends with
+// a *LimitError.
+type Reader struct {
+	MaxFrame   int   // frame size, at most that of a 208 bytes record
+	MaxRecords int64 // records per stream
+	MaxBytes   int64 // data bytes per stream
+
+	r   io.Reader
+	key xxtea.TeaKey
+	seq
+	data  []byte
+	total int64 // data bytes read
+	frame [2 + envelope.HeaderSize + envelope.MaxPayload + envelope.TagSizeV2]byte
+}
+
+// NewReader returns a Reader opening records from r under the key.
+func NewReader(r io.Reader, key xxtea.TeaKey) *Reader {
+	return &Reader{r: r, key: key}
+}
+
+// Read reads data of the stream into p.
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.data) == 0 {
+		if r.end {
+			return 0, io.EOF
+		}
+		if err := r.record(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// record reads and opens the next record.
+func (r *Reader) record() error {
+	if _, err := io.ReadFull(r.r, r.frame[:2]); err != nil {
+		if err == io.EOF {
+			err = ErrTruncated
+		}
+		return err
+	}
+	n := faults.Cut(int(binary.BigEndian.Uint16(r.frame[:2])))
+	if n > len(r.frame)-2 {
+		return ErrRecord
+	}
+	if r.MaxFrame > 0 && n > r.MaxFrame {
+		return &LimitError{"frame size", int64(r.MaxFrame)}
+	}
+	if r.MaxRecords > 0 && int64(r.next) >= r.MaxRecords {
+		return &LimitError{"records", r.MaxRecords}
+	}
+	f := r.frame[2 : 2+n]
+	_, err := io.ReadFull(r.r, f)
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrTruncated
+		}
+		return err
+	}
+	if r.data, err = r.open(r.key, f); err != nil {
+		return err
+	}
+	r.total += int64(len(r.data))
+	if r.MaxBytes > 0 && r.total > r.MaxBytes {
+		r.data, r.end = nil, false
+		return &LimitError{"bytes", r.MaxBytes}
+	}
+	return nil
+}
+
+// seq opens records of a stream in order.
+type seq struct {
+	next uint32
+	id   uint32
+	end  bool
+}
+
+// open opens the record frame f, checks it is the next one of the stream
+// and returns its data.
+func (s *seq) open(key xxtea.TeaKey, f []byte) ([]byte, error) {
+	h, pl, err := envelope.Open(key, f)
+	if err != nil {
+		return nil, err
+	}
+	if s.next == 0 {
+		s.id = h.Epoch
+	}
+	if h.Epoch != s.id || h.Counter != s.next {
+		return nil, ErrOrder
+	}
+	s.next++
+	t := pl[len(pl)-1]
+	pad := int(t &^ finalMark)
+	if pad == 0 || pad > len(pl) {
+		return nil, ErrRecord
+	}
+	if xxtea.Strict() {
+		n := (len(pl) - pad + 1 + 3) &^ 3
+		if n < envelope.MinPayload {
+			n = envelope.MinPayload
+		}
+		if len(pl) != n {
+			return nil, ErrRecord
+		}
+		for _, c := range pl[len(pl)-pad : len(pl)-1] {
+			if c != 0 {
+				return nil, ErrRecord
+			}
+		}
+	}
+	s.end = t&finalMark != 0
+	return pl[:len(pl)-pad], nil
+}
@@ -0,0 +1,196 @@
+package stream
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ohir/xxtea"
+	"github.com/ohir/xxtea/envelope"
+)
+
+const (
+	keyBEBE = "0123456789ABCDEF"
+	keyLELE = "FEDCBA9876543210"
+)
+
+func seal(t *testing.T, chunks ...[]byte) []byte {
+	var b bytes.Buffer
+	w, err := NewWriter(&b, xxtea.NewKey([]byte(keyBEBE)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range chunks {
+		if _, err = w.Write(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func open(b []byte, key string) ([]byte, error) {
+	return io.ReadAll(NewReader(bytes.NewReader(b), xxtea.NewKey([]byte(key))))
+}
+
+func Test_RoundTrip(t *testing.T) {
+	msg := bytes.Repeat([]byte("0123456789"), 100)
+	for _, chunks := range [][][]byte{
+		nil,
+		{{}},
+		{msg[:1]},
+		{msg[:MaxData]},
+		{msg[:MaxData+1]},
+		{msg[:10], msg[10:500], msg[500:]},
+	} {
+		want := bytes.Join(chunks, nil)
+		b := seal(t, chunks...)
+		if bytes.Contains(b, []byte("0123456789")) {
+			t.Error("Data leaked in clear")
+		}
+		got, err := open(b, keyBEBE)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Error("Round trip failed", len(want), err)
+		}
+	}
+}
+
+func Test_Closed(t *testing.T) {
+	w, _ := NewWriter(io.Discard, xxtea.NewKey([]byte(keyBEBE)))
+	w.Close()
+	if _, err := w.Write([]byte("x")); err != ErrClosed {
+		t.Error("Write after Close accepted")
+	}
+	if err := w.Close(); err != nil {
+		t.Error("Second Close failed")
+	}
+}
+
+func Test_Tamper(t *testing.T) {
+	b := seal(t, []byte("first record"), []byte("second record"))
+	rec := 2 + int(b[1]) // records are of the same size here
+	if _, err := open(b, keyLELE); err != envelope.ErrOpenFailed {
+		t.Error("Stream opened with other key")
+	}
+	if _, err := open(b[:len(b)-rec], keyBEBE); err != ErrTruncated {
+		t.Error("Missing final record not reported", err)
+	}
+	if _, err := open(b[:len(b)-3], keyBEBE); err != ErrTruncated {
+		t.Error("Cut record not reported", err)
+	}
+	swapped := append(append(append([]byte(nil), b[rec:2*rec]...), b[:rec]...), b[2*rec:]...)
+	if _, err := open(swapped, keyBEBE); err != ErrOrder {
+		t.Error("Reordered records accepted", err)
+	}
+	other := seal(t, []byte("first record"), []byte("second record"))
+	spliced := append(append([]byte(nil), b[:rec]...), other[rec:]...)
+	if _, err := open(spliced, keyBEBE); err != ErrOrder {
+		t.Error("Spliced records accepted", err)
+	}
+	f, _ := envelope.Seal(xxtea.NewKey([]byte(keyBEBE)), envelope.Header{}, make([]byte, 12))
+	if _, err := open(append([]byte{0, byte(len(f))}, f...), keyBEBE); err != ErrRecord {
+		t.Error("Record with bad trailer accepted", err)
+	}
+	long := []byte{0xff, 0xff}
+	if _, err := open(long, keyBEBE); err != ErrRecord {
+		t.Error("Oversized record accepted", err)
+	}
+}
+
+func Test_WriterID(t *testing.T) {
+	key := xxtea.NewKey([]byte(keyBEBE))
+	var a, b bytes.Buffer
+	for _, buf := range []*bytes.Buffer{&a, &b} {
+		w := NewWriterID(buf, key, 7)
+		w.Write([]byte("reproducible"))
+		w.Close()
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("Streams of fixed id differ")
+	}
+	if h, _ := envelope.ParseHeader(a.Bytes()[2:]); h.Epoch != 7 {
+		t.Error("Stream id not used", h.Epoch)
+	}
+	if d, err := io.ReadAll(NewReader(&a, key)); err != nil || string(d) != "reproducible" {
+		t.Error("Stream of fixed id not read", err)
+	}
+}
+
+func Test_Limits(t *testing.T) {
+	b := seal(t, bytes.Repeat([]byte("x"), 3*MaxData))
+	read := func(f func(r *Reader)) error {
+		r := NewReader(bytes.NewReader(b), xxtea.NewKey([]byte(keyBEBE)))
+		f(r)
+		_, err := io.ReadAll(r)
+		return err
+	}
+	if err := read(func(r *Reader) { r.MaxFrame, r.MaxRecords, r.MaxBytes = 300, 4, 3*MaxData }); err != nil {
+		t.Error("Stream within limits not read", err)
+	}
+	for _, c := range []struct {
+		what string
+		set  func(r *Reader)
+	}{
+		{"frame size", func(r *Reader) { r.MaxFrame = 100 }},
+		{"records", func(r *Reader) { r.MaxRecords = 3 }},
+		{"bytes", func(r *Reader) { r.MaxBytes = 3*MaxData - 1 }},
+	} {
+		err := read(c.set)
+		if le, ok := err.(*LimitError); !ok || le.What != c.what {
+			t.Error("Limit not enforced:", c.what, err)
+		}
+	}
+}
+
+func Test_Feed(t *testing.T) {
+	msg := bytes.Repeat([]byte("0123456789"), 50)
+	b := seal(t, msg[:7], msg[7:300], msg[300:])
+	for _, chunk := range []int{1, 3, 64, len(b)} {
+		d := NewDecoder(xxtea.NewKey([]byte(keyBEBE)))
+		var got []byte
+		for i := 0; i < len(b); i += chunk {
+			j := i + chunk
+			if j > len(b) {
+				j = len(b)
+			}
+			frames, err := d.Feed(b[i:j])
+			if err != nil {
+				t.Fatal("Feed failed", chunk, err)
+			}
+			for _, f := range frames {
+				got = append(got, f...)
+			}
+		}
+		if !bytes.Equal(got, msg) || !d.Done() || d.Close() != nil {
+			t.Error("Fed stream not decoded", chunk)
+		}
+	}
+	d := NewDecoder(xxtea.NewKey([]byte(keyBEBE)))
+	if _, err := d.Feed(b[:len(b)-1]); err != nil || d.Close() != ErrTruncated {
+		t.Error("Partial stream not reported truncated", err)
+	}
+	if _, err := d.Feed(b[len(b)-1:]); err != nil {
+		t.Error("Last byte not taken", err)
+	}
+	if _, err := d.Feed([]byte{0}); err != ErrRecord {
+		t.Error("Bytes after final record taken", err)
+	}
+	for _, bad := range [][]byte{{0, 0, 'x'}, {0, 0}, {0, minFrame - 1}, {0xff, 0xff}} {
+		d = NewDecoder(xxtea.NewKey([]byte(keyBEBE)))
+		if _, err := d.Feed(bad); err != ErrRecord {
+			t.Error("Bad record length taken", bad, err)
+		}
+		if _, err := d.Feed(b); err != ErrRecord {
+			t.Error("Bad record length error not sticky", bad, err)
+		}
+	}
+	d = NewDecoder(xxtea.NewKey([]byte(keyLELE)))
+	if _, err := d.Feed(b); err != envelope.ErrOpenFailed {
+		t.Error("Wrong key not refused", err)
+	}
+	if _, err := d.Feed(b); err != envelope.ErrOpenFailed {
+		t.Error("Error not sticky", err)
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import "sync/atomic"
+
+var strict int32
+
+// SetStrict turns strict parsing on or off for decoders of this package
+// and its subpackages.  In strict mode they take canonical encodings only,
+// the very bytes their encoders give, and reject encodings that decode to
+// the same value other ways: non-zero padding, overlong varints, trailing
+// bytes, non-canonical base64 or JSON.  Layers that cache verified frames,
+// signatures or MACs by their bytes need it, so one value can not pass
+// under two byte strings.  Key parsing is always strict.
+func SetStrict(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&strict, v)
+}
+
+// Strict reports whether strict parsing is on.
+func Strict() bool {
+	return atomic.LoadInt32(&strict) != 0
+}
@@ -0,0 +1,18 @@
+package xxtea
+
+import "testing"
+
+func Test_Strict(t *testing.T) {
+	defer SetStrict(false)
+	if Strict() {
+		t.Error("Strict on by default")
+	}
+	SetStrict(true)
+	if !Strict() {
+		t.Error("SetStrict(true) failed")
+	}
+	SetStrict(false)
+	if Strict() {
+		t.Error("SetStrict(false) failed")
+	}
+}
@@ -0,0 +1,66 @@
+package tea
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+// Vectors of golang.org/x/crypto/tea.
+var kats = []struct {
+	key    xxtea.TeaKey
+	pt, ct string
+}{
+	{xxtea.TeaKey{}, "0000000000000000", "41ea3a0a94baa940"},
+	{xxtea.TeaKey{^uint32(0), ^uint32(0), ^uint32(0), ^uint32(0)}, "ffffffffffffffff", "319bbefb016abdb2"},
+}
+
+func Test_KnownAnswers(t *testing.T) {
+	for i, v := range kats {
+		pt, _ := hex.DecodeString(v.pt)
+		b := Encrypt(v.key, pt, make([]byte, BlockSize))
+		if hex.EncodeToString(b) != v.ct {
+			t.Error("Encrypt failed", i)
+		}
+		if hex.EncodeToString(Decrypt(v.key, b, b)) != v.pt {
+			t.Error("Decrypt failed", i)
+		}
+	}
+}
+
+func Test_Words(t *testing.T) {
+	k := xxtea.NewKey([]byte("0123456789abcdef"))
+	v := [2]uint32{0x01020304, 0x05060708}
+	EncryptWords(k, &v)
+	b := Encrypt(k, []byte{1, 2, 3, 4, 5, 6, 7, 8}, make([]byte, 8))
+	if binary.BigEndian.Uint32(b) != v[0] || binary.BigEndian.Uint32(b[4:]) != v[1] {
+		t.Error("EncryptWords differs from Encrypt")
+	}
+	DecryptWords(k, &v)
+	if v != [2]uint32{0x01020304, 0x05060708} {
+		t.Error("DecryptWords failed")
+	}
+}
+
+func Test_Misuse(t *testing.T) {
+	var k xxtea.TeaKey
+	for _, tc := range []struct {
+		in, out int
+		err     error
+	}{
+		{7, 7, xxtea.ErrMsgShort},
+		{16, 16, xxtea.ErrMsgLong},
+		{8, 12, xxtea.ErrLenMismatch},
+	} {
+		func() {
+			defer func() {
+				if recover() != tc.err {
+					t.Error("Misuse not caught", tc.in, tc.out)
+				}
+			}()
+			Encrypt(k, make([]byte, tc.in), make([]byte, tc.out))
+		}()
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import "math/bits"
+
+// TeaKey.EncryptView encrypts the caller-owned words of v in place, with no
+// copy, eg. a DMA buffer or an mmap'd register window.  Order tells how
+// the message is laid out in v: with WordsBE the result is that of Encrypt
+// over the words of v serialized big-endian, with WordsLE over them
+// serialized little-endian, which is the raw memory of a LE host.  WordsLE
+// words are swapped in place for the rounds and back after them.
+//
+// Slice v must be 3..52 words long.  It returns the same 'v' slice it has got.
+func (k TeaKey) EncryptView(v []uint32, order WordOrder) []uint32 {
+	chkView(v, order)
+	swapView(v, order)
+	k.encrypt(v)
+	swapView(v, order)
+	return v
+}
+
+// TeaKey.DecryptView is the inverse of EncryptView.
+func (k TeaKey) DecryptView(v []uint32, order WordOrder) []uint32 {
+	chkView(v, order)
+	swapView(v, order)
+	k.decrypt(v)
+	swapView(v, order)
+	return v
+}
+
+// TeaKey.EncryptWords encrypts the words of v in place, as XXTEA words,
+// with no byte conversion at all.  It is EncryptView with WordsBE, for
+// firmware holding payloads as uint32 arrays.
+//
+// Slice v must be 3..52 words long, as Encrypt takes 12..208 bytes.  It
+// returns the same 'v' slice it has got.
+func (k TeaKey) EncryptWords(v []uint32) []uint32 {
+	chkView(v, WordsBE)
+	k.encrypt(v)
+	return v
+}
+
+// TeaKey.DecryptWords is the inverse of EncryptWords.
+func (k TeaKey) DecryptWords(v []uint32) []uint32 {
+	chkView(v, WordsBE)
+	k.decrypt(v)
+	return v
+}
+
+// chkView panics on a view XXTEA can not take.
+func chkView(v []uint32, order WordOrder) {
+	if err := chkMsg(4*len(v), 4*len(v)); err != nil {
+		panic(err)
+	}
+	if order > WordsLE {
+		panic(ErrMisuse)
+	}
+}
+
+// swapView converts WordsLE words from and to XXTEA word values.
+func swapView(v []uint32, order WordOrder) {
+	if order == WordsLE {
+		for i, w := range v {
+			v[i] = bits.ReverseBytes32(w)
+		}
+	}
+}
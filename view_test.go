@@ -0,0 +1,62 @@
+package xxtea
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func Test_View(t *testing.T) {
+	key := NewKey(bs(keyBEBE))
+	for _, n := range []int{3, 4, 13, 52} {
+		msg := make([]byte, 4*n)
+		for i := range msg {
+			msg[i] = byte(i * 7)
+		}
+		for _, o := range []struct {
+			order WordOrder
+			bo    binary.ByteOrder
+		}{{WordsBE, binary.BigEndian}, {WordsLE, binary.LittleEndian}} {
+			v := make([]uint32, n)
+			for i := range v {
+				v[i] = o.bo.Uint32(msg[4*i:])
+			}
+			key.EncryptView(v, o.order)
+			ct := make([]byte, 4*n)
+			for i, w := range v {
+				o.bo.PutUint32(ct[4*i:], w)
+			}
+			if !bytes.Equal(ct, key.Encrypt(msg, make([]byte, 4*n))) {
+				t.Error("EncryptView failed", n, o.order)
+			}
+			key.DecryptView(v, o.order)
+			for i, w := range v {
+				if w != o.bo.Uint32(msg[4*i:]) {
+					t.Error("DecryptView failed", n, o.order)
+					break
+				}
+			}
+		}
+	}
+	v := []uint32{0x30313233, 0x34353637, 0x38394142, 0x43444546}
+	key.EncryptWords(v)
+	ct := key.Encrypt(bs(keyBEBE), make([]byte, 16))
+	for i, w := range v {
+		if w != binary.BigEndian.Uint32(ct[4*i:]) {
+			t.Error("EncryptWords failed")
+		}
+	}
+	if key.DecryptWords(v)[3] != 0x43444546 {
+		t.Error("DecryptWords failed")
+	}
+	for _, v := range [][]uint32{make([]uint32, 2), make([]uint32, 53)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("Bad view length accepted", len(v))
+				}
+			}()
+			key.EncryptView(v, WordsBE)
+		}()
+	}
+}
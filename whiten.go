@@ -0,0 +1,21 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+// WithWhitening returns the key with a 64-bit salt mixed into its words
+// before any rounds: the high half of the salt is XORed into words 0 and 2,
+// the low half into words 1 and 3.  It matches vendor libraries whitening
+// keys this way, and varies keys per deployment.
+//
+// WARNING: a known salt adds no strength; keys differing by a salt are
+// related keys.  A salt that zeroes the key panics, as a zero key does.
+func (k TeaKey) WithWhitening(salt uint64) TeaKey {
+	hi, lo := uint32(salt>>32), uint32(salt)
+	w := TeaKey{k[0] ^ hi, k[1] ^ lo, k[2] ^ hi, k[3] ^ lo}
+	if w == (TeaKey{}) {
+		panic(ErrZeroKey)
+	}
+	return w
+}
@@ -0,0 +1,23 @@
+package xxtea
+
+import "testing"
+
+func Test_WithWhitening(t *testing.T) {
+	k := NewKey([]byte(keyBEBE))
+	if k.WithWhitening(0) != k {
+		t.Error("Zero salt changed the key")
+	}
+	w := k.WithWhitening(0x0102030405060708)
+	if w != (TeaKey{k[0] ^ 0x01020304, k[1] ^ 0x05060708, k[2] ^ 0x01020304, k[3] ^ 0x05060708}) {
+		t.Error("Whitening failed", w)
+	}
+	if w.WithWhitening(0x0102030405060708) != k {
+		t.Error("Whitening is not an involution")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Zeroing salt should panic")
+		}
+	}()
+	TeaKey{1, 2, 1, 2}.WithWhitening(1<<32 | 2)
+}
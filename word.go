@@ -0,0 +1,38 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xxtea
+
+import "encoding/binary"
+
+// WordBlockSize is the size of a block holding a single 4B value.
+const WordBlockSize = 8
+
+// EncryptWord expands a single 4B value to a block of two big-endian
+// words, value | ^value, and returns it encrypted by XXTEA over the two
+// words (32 rounds), as RFID tokens carrying a 4B id do.  The fill word is
+// derived from the value, so DecryptWord can tell a block that was not
+// made by EncryptWord under the same key.
+//
+// Two words are below the 12 bytes Encrypt takes: EncryptWord is for such
+// tokens only.
+func (k TeaKey) EncryptWord(v uint32) (b [WordBlockSize]byte) {
+	w := [2]uint32{v, ^v}
+	k.encrypt(w[:])
+	binary.BigEndian.PutUint32(b[0:], w[0])
+	binary.BigEndian.PutUint32(b[4:], w[1])
+	return b
+}
+
+// DecryptWord returns the value of a block made by EncryptWord.  It
+// returns false if the fill word does not match the value.  Block must be
+// WordBlockSize bytes long.
+func (k TeaKey) DecryptWord(b []byte) (uint32, bool) {
+	if len(b) != WordBlockSize {
+		panic(ErrLenMismatch)
+	}
+	w := [2]uint32{binary.BigEndian.Uint32(b[0:]), binary.BigEndian.Uint32(b[4:])}
+	k.decrypt(w[:])
+	return w[0], w[1] == ^w[0]
+}
@@ -0,0 +1,40 @@
+package xxtea
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func Test_EncryptWord(t *testing.T) {
+	k := NewKey([]byte(keyBEBE))
+	for _, v := range []uint32{0, 1, 0xdeadbeef, ^uint32(0)} {
+		b := k.EncryptWord(v)
+		if b != k.EncryptWord(v) {
+			t.Error("EncryptWord not deterministic")
+		}
+		if got, ok := k.DecryptWord(b[:]); !ok || got != v {
+			t.Error("DecryptWord failed", v, got)
+		}
+		ref := make([]byte, 8)
+		binary.BigEndian.PutUint32(ref, v)
+		binary.BigEndian.PutUint32(ref[4:], ^v)
+		if refBtea(ref, 2, k); !bytes.Equal(ref, b[:]) {
+			t.Error("EncryptWord differs from reference", v)
+		}
+		b[7] ^= 1
+		if _, ok := k.DecryptWord(b[:]); ok {
+			t.Error("Tampered block accepted", v)
+		}
+	}
+	b := k.EncryptWord(42)
+	if _, ok := NewKey([]byte(keyLELE)).DecryptWord(b[:]); ok {
+		t.Error("Block accepted under other key")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("short block should panic")
+		}
+	}()
+	k.DecryptWord(b[:4])
+}
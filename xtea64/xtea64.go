@@ -0,0 +1,93 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package xtea64 implements the XTEA 64-bit block cipher of Needham and
+// Wheeler's "Tea extensions", 32 cycles, as many vendor devices run it.
+// Keys are xxtea.TeaKey, made with NewKey of the same 16 big-endian
+// bytes, and blocks are two big-endian words; devices dumping words in
+// their memory order need the xxtea juggling helpers, eg. AsLEBE, on key
+// and data alike.
+//
+// XTEA takes one 8-byte block.  Chaining blocks is left to the caller,
+// so is everything else: there is no mode, padding nor authentication.
+// Use it to talk to XTEA devices, not for anything new.
+//
+// Misuse panics with xxtea misuse codes, as in package xxtea.
+package xtea64
+
+import "github.com/ohir/xxtea"
+
+// BlockSize is the XTEA block size in bytes.
+const BlockSize = 8
+
+const (
+	delta  uint32 = 0x9e3779b9
+	cycles        = 32
+)
+
+// Encrypt encrypts the 8-byte block 'in' writing result to 'out', and
+// returns the same 'out' slice it has got.  Both can be the same slice.
+func Encrypt(k xxtea.TeaKey, in, out []byte) []byte {
+	chk(in, out)
+	v := [2]uint32{be(in), be(in[4:])}
+	EncryptWords(k, &v)
+	put(out, v[0])
+	put(out[4:], v[1])
+	return out
+}
+
+// Decrypt is the inverse of Encrypt.
+func Decrypt(k xxtea.TeaKey, in, out []byte) []byte {
+	chk(in, out)
+	v := [2]uint32{be(in), be(in[4:])}
+	DecryptWords(k, &v)
+	put(out, v[0])
+	put(out[4:], v[1])
+	return out
+}
+
+// EncryptWords encrypts the block of two words v in place, as the
+// reference C code does.
+func EncryptWords(k xxtea.TeaKey, v *[2]uint32) {
+	v0, v1 := v[0], v[1]
+	var sum uint32
+	for i := 0; i < cycles; i++ {
+		v0 += ((v1<<4 ^ v1>>5) + v1) ^ (sum + k[sum&3])
+		sum += delta
+		v1 += ((v0<<4 ^ v0>>5) + v0) ^ (sum + k[sum>>11&3])
+	}
+	v[0], v[1] = v0, v1
+}
+
+// DecryptWords is the inverse of EncryptWords.
+func DecryptWords(k xxtea.TeaKey, v *[2]uint32) {
+	v0, v1 := v[0], v[1]
+	sum := uint32(0xc6ef3720) // delta * cycles
+	for i := 0; i < cycles; i++ {
+		v1 -= ((v0<<4 ^ v0>>5) + v0) ^ (sum + k[sum>>11&3])
+		sum -= delta
+		v0 -= ((v1<<4 ^ v1>>5) + v1) ^ (sum + k[sum&3])
+	}
+	v[0], v[1] = v0, v1
+}
+
+// chk panics on blocks XTEA can not take.
+func chk(in, out []byte) {
+	switch {
+	case len(in) != len(out):
+		panic(xxtea.ErrLenMismatch)
+	case len(in) < BlockSize:
+		panic(xxtea.ErrMsgShort)
+	case len(in) > BlockSize:
+		panic(xxtea.ErrMsgLong)
+	}
+}
+
+func be(b []byte) uint32 {
+	return uint32(b[3]) | uint32(b[2])<<8 | uint32(b[1])<<16 | uint32(b[0])<<24
+}
+
+func put(b []byte, w uint32) {
+	b[3], b[2], b[1], b[0] = byte(w), byte(w>>8), byte(w>>16), byte(w>>24)
+}
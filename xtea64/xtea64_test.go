@@ -0,0 +1,67 @@
+package xtea64
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ohir/xxtea"
+)
+
+// Vectors of golang.org/x/crypto/xtea and Bouncy Castle.
+var kats = []struct{ key, pt, ct string }{
+	{"000102030405060708090a0b0c0d0e0f", "4141414141414141", "e78f2d13744341d8"},
+	{"000102030405060708090a0b0c0d0e0f", "5a5b6e278948d77f", "4141414141414141"},
+	{"0123456712345678234567893456789a", "0000000000000000", "1ff9a0261ac64264"},
+	{"0123456712345678234567893456789a", "0102030405060708", "8c67155b2ef91ead"},
+}
+
+func Test_KnownAnswers(t *testing.T) {
+	for i, v := range kats {
+		k, err := xxtea.ParseKeyHex(v.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pt, _ := hex.DecodeString(v.pt)
+		b := Encrypt(k, pt, make([]byte, BlockSize))
+		if hex.EncodeToString(b) != v.ct {
+			t.Error("Encrypt failed", i)
+		}
+		if hex.EncodeToString(Decrypt(k, b, b)) != v.pt {
+			t.Error("Decrypt failed", i)
+		}
+	}
+}
+
+func Test_Words(t *testing.T) {
+	k, _ := xxtea.ParseKeyHex(kats[3].key)
+	v := [2]uint32{0x01020304, 0x05060708}
+	EncryptWords(k, &v)
+	if v != [2]uint32{0x8c67155b, 0x2ef91ead} {
+		t.Error("EncryptWords failed")
+	}
+	DecryptWords(k, &v)
+	if v != [2]uint32{0x01020304, 0x05060708} {
+		t.Error("DecryptWords failed")
+	}
+}
+
+func Test_Misuse(t *testing.T) {
+	var k xxtea.TeaKey
+	for _, tc := range []struct {
+		in, out int
+		err     error
+	}{
+		{7, 7, xxtea.ErrMsgShort},
+		{16, 16, xxtea.ErrMsgLong},
+		{8, 12, xxtea.ErrLenMismatch},
+	} {
+		func() {
+			defer func() {
+				if recover() != tc.err {
+					t.Error("Misuse not caught", tc.in, tc.out)
+				}
+			}()
+			Encrypt(k, make([]byte, tc.in), make([]byte, tc.out))
+		}()
+	}
+}
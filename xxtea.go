@@ -35,11 +35,13 @@
 
 package xxtea
 
-const (
-	em    string = "xxtea: XXTEA cipher misuse! Read teh Docs, Luke!"
-	delta uint32 = 0x9e3779b9
+import (
+	"encoding/binary"
+	"math/bits"
 )
 
+const delta uint32 = 0x9e3779b9
+
 // TeaKey contains secret key ints
 type TeaKey [4]uint32
 
@@ -57,8 +59,9 @@ type TeaKey [4]uint32
 //
 // AsLELE FEDCBA9876543210 <=> 0123456789ABCDEF
 func NewKey(key []byte) (k TeaKey) {
+	legacy(depNewKey)
 	if len(key) != 16 {
-		panic(em)
+		panic(ErrKeyLen)
 	}
 	var c uint32
 	for n := 0; n < 16; n += 4 {
@@ -67,11 +70,22 @@ func NewKey(key []byte) (k TeaKey) {
 		c |= k[n>>2]
 	}
 	if c == 0 {
-		panic(em) // all-zeros key
+		panic(ErrZeroKey)
 	}
 	return
 }
 
+// TeaKey.Bytes returns the key serialized to 16 big-endian bytes, the form
+// NewKey expects.
+func (k TeaKey) Bytes() []byte {
+	b := make([]byte, 16)
+	for n := 0; n < 16; n += 4 {
+		w := k[n>>2] // to bytes
+		b[n+3], b[n+2], b[n+1], b[n] = byte(w), byte(w>>8), byte(w>>16), byte(w>>24)
+	}
+	return b
+}
+
 // AsBELE reverses chunks order, preserves byte order in a 4B chunk.
 //
 // (BELE) CDEF89AB45670123 <=> 0123456789ABCDEF (BEBE)
@@ -79,12 +93,11 @@ func NewKey(key []byte) (k TeaKey) {
 // Function does its juggling in-place then returns the same 'd' slice.
 // It expects len(d) to be at least 4 and divisible by 4.
 func AsBELE(d []byte) []byte {
-	var i, l int
-	l = chk4len(len(d))
+	i, l := 0, chk4len(len(d))-3 // l: last chunk
 	for i < l {
-		d[i+3], d[i+2], d[i+1], d[i],
-			d[l-3], d[l-2], d[l-1], d[l] = d[l], d[l-1], d[l-2], d[l-3],
-			d[i+0], d[i+1], d[i+2], d[i+3]
+		a, b := binary.BigEndian.Uint32(d[i:]), binary.BigEndian.Uint32(d[l:])
+		binary.BigEndian.PutUint32(d[i:], b)
+		binary.BigEndian.PutUint32(d[l:], a)
 		l -= 4
 		i += 4
 	}
@@ -99,9 +112,13 @@ func AsBELE(d []byte) []byte {
 // It expects len(d) to be at least 4 and divisible by 4.
 func AsLEBE(d []byte) []byte {
 	var i int
-	for i < chk4len(len(d)) {
-		d[i+0], d[i+1], d[i+2], d[i+3] = d[i+3], d[i+2], d[i+1], d[i+0]
-		i += 4
+	l := chk4len(len(d)) + 1
+	for ; i+8 <= l; i += 8 { // two chunks at once
+		v := bits.ReverseBytes64(binary.BigEndian.Uint64(d[i:]))
+		binary.BigEndian.PutUint64(d[i:], bits.RotateLeft64(v, 32))
+	}
+	if i < l {
+		binary.BigEndian.PutUint32(d[i:], bits.ReverseBytes32(binary.BigEndian.Uint32(d[i:])))
 	}
 	return d
 }
@@ -124,80 +141,200 @@ func AsLELE(d []byte) []byte {
 	return d
 }
 
-/* mid-endian jugglings are now obsolete
-// AsLB16 reverses byte order in a 2B chunk, preserves chunks order
+// AsBELE64 reverses 8B chunks order, preserves byte order in a 8B chunk.
+// It is for devices serializing data as uint64 words.
 //
-// (LB16) 1032547698BADCFE <=> 0123456789ABCDEF (BEBE)
+// (BELE64) 89ABCDEF01234567 <=> 0123456789ABCDEF (BEBE)
 //
 // Function does its juggling in-place then returns the same 'd' slice.
-// It expects len(d) to be at least 4 and divisible by 4.
-// Limit is imposed for consistency with other As... functions.
-func AsLB16(d []byte) []byte {
-	var i int
-	for i < chk4len(len(d)) {
-		d[i], d[i+1] = d[i+1], d[i]
-		i += 2
+// It expects len(d) to be at least 8 and divisible by 8.
+func AsBELE64(d []byte) []byte {
+	i, l := 0, chk8len(len(d))-7 // l: last chunk
+	for i < l {
+		a, b := binary.BigEndian.Uint64(d[i:]), binary.BigEndian.Uint64(d[l:])
+		binary.BigEndian.PutUint64(d[i:], b)
+		binary.BigEndian.PutUint64(d[l:], a)
+		l -= 8
+		i += 8
 	}
 	return d
 }
 
-// AsME16 reverses byte order in each even 2B chunk
+// AsLEBE64 reverses byte order in a 8B chunk, preserves chunks order.
+// It is for devices serializing data as uint64 words.
 //
-// (ME16) 1023546798ABDCEF <=> 0123456789ABCDEF (BEBE)
+// (LEBE64) 76543210FEDCBA98 <=> 0123456789ABCDEF (BEBE)
 //
 // Function does its juggling in-place then returns the same 'd' slice.
-// It expects len(d) to be at least 4 and divisible by 4.
-func AsME16(d []byte) []byte {
-	var i int // uh, now we have slices.Reverse
-	for i < chk4len(len(d)) {
-		d[i], d[i+1] = d[i+1], d[i]
-		i += 4
+// It expects len(d) to be at least 8 and divisible by 8.
+func AsLEBE64(d []byte) []byte {
+	for i := 0; i < chk8len(len(d)); i += 8 {
+		binary.BigEndian.PutUint64(d[i:], bits.ReverseBytes64(binary.BigEndian.Uint64(d[i:])))
 	}
 	return d
 }
 
-// AsMX16 reverses byte order in each odd 2B chunk
-//
-// (MX16) 0132457689BACDFE <=> 0123456789ABCDEF (BEBE)
-//
-// Function does its juggling in-place then returns the same 'd' slice.
-// It expects len(d) to be at least 4 and divisible by 4.
-func AsMX16(d []byte) []byte {
-	var i int
-	for i < chk4len(len(d)) {
-		d[i+2], d[i+3] = d[i+3], d[i+2]
-		i += 4
-	}
-	return d
-}
-*/
 // check4len tests if length is >= 4 and divisible by 4, otherwise it panics.
 // It returns index of the last element in a slice if l is slice length.
 func chk4len(l int) int {
-	if l < 4 || l&3 != 0 {
-		panic(em)
+	if l < 4 {
+		panic(ErrMsgShort)
+	}
+	if l&3 != 0 {
+		panic(ErrMsgAlign)
 	}
 	return l - 1
 }
 
+// chk8len tests if length is >= 8 and divisible by 8, otherwise it panics.
+// It returns index of the last element in a slice if l is slice length.
+func chk8len(l int) int {
+	if l < 8 {
+		panic(ErrMsgShort)
+	}
+	if l&7 != 0 {
+		panic(ErrMsgAlign)
+	}
+	return l - 1
+}
+
+// TeaKey.Derive returns a subkey of k bound to the 'info' bytes.
+//
+// Info, prefixed with its length byte and zero-padded to at least 16 bytes
+// in multiples of four, is encrypted under k.  First 16 bytes of the result
+// make the subkey.  Info can not be empty nor longer than 207 bytes.
+// DeriveLabel is the preferred way to build info.
+func (k TeaKey) Derive(info []byte) (d TeaKey) {
+	var b [208]byte
+	n := len(info) + 1
+	if n > 208 || n == 1 {
+		panic(ErrMisuse)
+	}
+	b[0] = byte(len(info))
+	copy(b[1:], info)
+	return k.derive(b[:], n)
+}
+
+// TeaKey.DeriveLabel returns a subkey of k for the domain label, eg. "mac",
+// "enc" or "ota", bound to optional context bytes (nonces, indexes).  It is
+// Derive of the label, a space, then the context; labels have no spaces,
+// so no label and context give the info of another label.
+//
+// Label must be 1..64 printable ASCII characters, no space, and the whole
+// info at most 207 bytes, or DeriveLabel panics.  Keep one label per
+// purpose: reusing a label with other context layout defeats separation.
+func (k TeaKey) DeriveLabel(label string, context ...[]byte) TeaKey {
+	if len(label) == 0 || len(label) > 64 {
+		panic(ErrMisuse)
+	}
+	var b [208]byte
+	n := 1 + copy(b[1:], label)
+	b[n] = ' '
+	n++
+	for i := 0; i < len(label); i++ {
+		if label[i] < 0x21 || label[i] > 0x7e {
+			panic(ErrMisuse)
+		}
+	}
+	for _, c := range context {
+		if n+len(c) > 208 {
+			panic(ErrMisuse)
+		}
+		n += copy(b[n:], c)
+	}
+	b[0] = byte(n - 1)
+	return k.derive(b[:], n)
+}
+
+// derive encrypts length prefixed info of n bytes, in b, into a subkey.
+func (k TeaKey) derive(b []byte, n int) (d TeaKey) {
+	n = (n + 3) &^ 3
+	if n < 16 {
+		n = 16
+	}
+	k.Encrypt(b[:n], b[:n])
+	for n = 0; n < 16; n += 4 {
+		d[n>>2] = uint32(b[n+3]) | uint32(b[n+2])<<8 | // from bytes
+			uint32(b[n+1])<<16 | uint32(b[n])<<24
+	}
+	return
+}
+
 // TeaKey.Encrypt does xxtea block rounds over 'in' bytes writing result to the
 // 'out' bytes.  It returns the same 'out' slice it has got.
 //
 // Slices must be the same length in 12..208 range, in multiples of four.
 // Both arguments can be the same slice.
 func (k TeaKey) Encrypt(in, out []byte) []byte {
-	var n, y, z, p, sum, rounds uint32
+	legacy(depEncrypt)
+	if err := chkMsg(len(in), len(out)); err != nil {
+		panic(err)
+	}
+	return k.encryptBytes(in, out)
+}
+
+// encryptBytes is Encrypt of checked lengths.
+func (k TeaKey) encryptBytes(in, out []byte) []byte {
+	var n, z uint32
 	var v [52]uint32
 	z = uint32(len(in)) // z bytes (temp)
-	if z < 12 || z > 208 || z&3 != 0 || z != uint32(len(out)) {
-		panic(em)
-	}
 	for n = 0; n < z; n += 4 {
 		v[n>>2] = uint32(in[n+3]) | uint32(in[n+2])<<8 | // from bytes
 			uint32(in[n+1])<<16 | uint32(in[n])<<24
 	}
-	n = z >> 2        // n uint32s
-	rounds = 6 + 52/n // rounds = 6 + 52/n;
+	k.encrypt(v[:z>>2])
+	for n = 0; n < uint32(len(out)); n += 4 {
+		k := v[n>>2] // to bytes
+		out[n+3], out[n+2], out[n+1], out[n] = byte(k), byte(k>>8), byte(k>>16), byte(k>>24)
+	}
+	return out
+}
+
+// TeaKey.Decrypt does xxtea block rounds over 'in' bytes writing result to the
+// 'out' bytes.  It returns the same 'out' slice it has got.
+//
+// Slices must be the same length in 12..208 range, in multiples of four.
+// Both arguments can be the same slice.
+func (k TeaKey) Decrypt(in, out []byte) []byte {
+	legacy(depDecrypt)
+	if err := chkMsg(len(in), len(out)); err != nil {
+		panic(err)
+	}
+	return k.decryptBytes(in, out)
+}
+
+// decryptBytes is Decrypt of checked lengths.
+func (k TeaKey) decryptBytes(in, out []byte) []byte {
+	var n, y uint32
+	var v [52]uint32
+	y = uint32(len(in)) // y bytes (temp)
+	for n = 0; n < y; n += 4 {
+		v[n>>2] = uint32(in[n+3]) | uint32(in[n+2])<<8 | // from bytes
+			uint32(in[n+1])<<16 | uint32(in[n])<<24
+	}
+	k.decrypt(v[:y>>2])
+	for n = 0; n < uint32(len(out)); n += 4 {
+		k := v[n>>2] // to bytes
+		out[n+3], out[n+2], out[n+1], out[n] = byte(k), byte(k>>8), byte(k>>16), byte(k>>24)
+	}
+	return out
+}
+
+// encrypt does xxtea block rounds over the words of v, in place.
+//
+// Every step takes the word the previous step made, through a chain of
+// five dependent operations (shift, xor, add, xor, add), and compiled Go
+// already runs at that latency: hand written amd64 assembly, BMI2 shifts
+// and unrolled steps included, measured no faster.  Throughput can only
+// come from interleaving independent messages, as EncryptBatch does.
+func (k TeaKey) encrypt(v []uint32) {
+	k.encryptN(v, 6+52/uint32(len(v))) // rounds = 6 + 52/n;
+}
+
+// encryptN does the given number of xxtea block rounds over v, in place.
+func (k TeaKey) encryptN(v []uint32, rounds uint32) {
+	var y, z, p, sum uint32
+	n := uint32(len(v)) // n uint32s
 	/* // reference C ENCRYPT
 	    z = v[n-1];
 	    sum = 0;
@@ -228,31 +365,17 @@ func (k TeaKey) Encrypt(in, out []byte) []byte {
 		v[n-1] += ((z>>5 ^ y<<2) + (y>>3 ^ z<<4)) ^ ((sum ^ y) + (k[p&3^e] ^ z))
 		z = v[n-1]
 	}
-	for n = 0; n < uint32(len(out)); n += 4 {
-		k := v[n>>2] // to bytes
-		out[n+3], out[n+2], out[n+1], out[n] = byte(k), byte(k>>8), byte(k>>16), byte(k>>24)
-	}
-	return out
 }
 
-// TeaKey.Decrypt does xxtea block rounds over 'in' bytes writing result to the
-// 'out' bytes.  It returns the same 'out' slice it has got.
-//
-// Slices must be the same length in 12..208 range, in multiples of four.
-// Both arguments can be the same slice.
-func (k TeaKey) Decrypt(in, out []byte) []byte {
-	var n, y, z, p, rounds uint32
-	var v [52]uint32
-	y = uint32(len(in)) // y bytes (temp)
-	if y < 12 || y > 208 || y&3 != 0 || y != uint32(len(out)) {
-		panic(em)
-	}
-	for n = 0; n < y; n += 4 {
-		v[n>>2] = uint32(in[n+3]) | uint32(in[n+2])<<8 | // from bytes
-			uint32(in[n+1])<<16 | uint32(in[n])<<24
-	}
-	n = y >> 2        // n ints
-	rounds = 6 + 52/n // rounds = 6 + 52/n;
+// decrypt does xxtea block rounds over the words of v, in place.
+func (k TeaKey) decrypt(v []uint32) {
+	k.decryptN(v, 6+52/uint32(len(v))) // rounds = 6 + 52/n;
+}
+
+// decryptN undoes encryptN of the given number of rounds.
+func (k TeaKey) decryptN(v []uint32, rounds uint32) {
+	var y, z, p uint32
+	n := uint32(len(v)) // n ints
 	/* // reference C DECRYPT
 	   y = v[0];
 	   sum = rounds*DELTA;
@@ -284,11 +407,6 @@ func (k TeaKey) Decrypt(in, out []byte) []byte {
 		y = v[0]
 		sum -= delta // sum -= DELTA;
 	}
-	for n = 0; n < uint32(len(out)); n += 4 {
-		k := v[n>>2] // to bytes
-		out[n+3], out[n+2], out[n+1], out[n] = byte(k), byte(k>>8), byte(k>>16), byte(k>>24)
-	}
-	return out
 }
 
 /*
@@ -15,9 +15,6 @@ const (
 	keyBELE = "CDEF89AB45670123"
 	keyLEBE = "32107654BA98FEDC"
 	keyLELE = "FEDCBA9876543210"
-	datLB16 = "1032547698BADCFE"
-	datME16 = "1023546798ABDCEF"
-	datMX16 = "0132457689BACDFE"
 
 	msgMin = `AbCdEFgHiJkL`
 	msgMax = `gygedyrtestycsedfdsfsdfdfsfslkdfsdflkjdfjljsdffsdfsdfsdfsjljdfl
@@ -104,6 +101,12 @@ func Test_EncDec(t *testing.T) {
 	}
 }
 
+func Test_KeyBytes(t *testing.T) {
+	if string(NewKey([]byte(keyBEBE)).Bytes()) != keyBEBE {
+		t.Error("Key serialization is broken")
+	}
+}
+
 func Test_ZeroKeyPanics(t *testing.T) {
 	defer func() {
 		if r := recover(); r == nil {
@@ -141,17 +144,48 @@ func Test_Juggles(t *testing.T) {
 	if string(AsLELE([]byte(keyLELE))) != keyBEBE {
 		t.Error("AsLELE logic is broken")
 	}
-	/* mid-endian jugglings are now obsolete
-	if string(AsLB16([]byte(datLB16))) != keyBEBE {
-		t.Error("AsLB16 logic is broken")
+}
+
+func Test_Juggles_Lengths(t *testing.T) {
+	for n := 4; n <= 36; n += 4 {
+		d := []byte(msgMax[:n])
+		lebe, bele := make([]byte, n), make([]byte, n)
+		for i := 0; i < n; i += 4 { // per byte reference
+			lebe[i], lebe[i+1], lebe[i+2], lebe[i+3] = d[i+3], d[i+2], d[i+1], d[i]
+			copy(bele[n-i-4:], d[i:i+4])
+		}
+		if string(AsLEBE([]byte(msgMax[:n]))) != string(lebe) {
+			t.Error("AsLEBE failed for length", n)
+		}
+		if string(AsBELE([]byte(msgMax[:n]))) != string(bele) {
+			t.Error("AsBELE failed for length", n)
+		}
 	}
-	if string(AsME16([]byte(datME16))) != keyBEBE {
-		t.Error("AsME16 logic is broken")
+}
+
+func Test_Juggles64(t *testing.T) {
+	if string(AsBELE64([]byte("89ABCDEF01234567"))) != keyBEBE {
+		t.Error("AsBELE64 logic is broken")
+	}
+	if string(AsLEBE64([]byte("76543210FEDCBA98"))) != keyBEBE {
+		t.Error("AsLEBE64 logic is broken")
+	}
+	if string(AsBELE64([]byte("01234567"))) != "01234567" || string(AsLEBE64([]byte("01234567"))) != "76543210" {
+		t.Error("8B juggling is broken")
+	}
+	if string(AsBELE64([]byte("ghijklmn89ABCDEF01234567"))) != keyBEBE+"ghijklmn" {
+		t.Error("AsBELE64 odd chunk count is broken")
 	}
-	if string(AsMX16([]byte(datMX16))) != keyBEBE {
-		t.Error("AsMX16 logic is broken")
+	for _, n := range []int{0, 4, 12} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("64-bit juggling should panic for length", n)
+				}
+			}()
+			AsLEBE64(make([]byte, n))
+		}()
 	}
-	*/
 }
 
 func Test_Juggles_min(t *testing.T) {
@@ -272,6 +306,65 @@ func Test_Decrypt_Panics_Unequal(t *testing.T) {
 	key.Decrypt(msg, out)
 }
 
+func Test_Derive(t *testing.T) {
+	key := NewKey([]byte(keyBEBE))
+	a := key.Derive([]byte("a"))
+	if a != key.Derive([]byte("a")) {
+		t.Error("Derive is not deterministic")
+	}
+	if a == key || a == key.Derive([]byte("b")) {
+		t.Error("Derive did not separate subkeys")
+	}
+	if a == key.Derive([]byte("a\x00")) {
+		t.Error("Derive did not bind info length")
+	}
+	if a == NewKey([]byte(keyLELE)).Derive([]byte("a")) {
+		t.Error("Derive did not bind the key")
+	}
+	_ = key.Derive(make([]byte, 207))
+}
+
+func Test_Derive_Panics_Long(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("info longer than 207 should panic")
+		}
+	}()
+	key := NewKey([]byte(keyBEBE))
+	key.Derive(make([]byte, 208))
+}
+
+func Test_DeriveLabel(t *testing.T) {
+	key := NewKey([]byte(keyBEBE))
+	if key.DeriveLabel("ab", bs("c")) == key.DeriveLabel("abc") {
+		t.Error("DeriveLabel of a label prefix and context collides")
+	}
+	if key.DeriveLabel("env", bs("-mac")) == key.Derive(bs("env-mac")) {
+		t.Error("DeriveLabel collides with Derive of a label")
+	}
+	if key.DeriveLabel("ota", bs{1, 2}, nil, bs{3}) != key.DeriveLabel("ota", bs{1, 2, 3}) {
+		t.Error("DeriveLabel context not joined")
+	}
+	_ = key.DeriveLabel("x", make([]byte, 205))
+	for _, f := range []func(){
+		func() { key.Derive(nil) },
+		func() { key.DeriveLabel("") },
+		func() { key.DeriveLabel("two words") },
+		func() { key.DeriveLabel("caf\u00e9") },
+		func() { key.DeriveLabel(string(make([]byte, 65))) },
+		func() { key.DeriveLabel("x", make([]byte, 206)) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("bad label should panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
+
 // /
 var note int
 